@@ -0,0 +1,165 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/dionvu/spogo/config"
+	"github.com/dionvu/spogo/err"
+	"github.com/dionvu/spogo/spotify/auth"
+)
+
+// A spogo subcommand, run from the shell instead of the tui,
+// for example "spogo smart --dry-run".
+type Command struct {
+	Name  string
+	Usage string
+	Run   func(args []string, env *Env) error
+
+	// The config is loaded before the command runs,
+	// unless the command reads it itself.
+	ReadsConfig bool
+}
+
+// The state shared by every command. The session is only
+// authenticated once a command asks for it, so commands that
+// work offline never open the browser.
+type Env struct {
+	Config  *config.Config
+	session *auth.Session
+}
+
+// Returns an authenticated session, creating it on first use.
+func (e *Env) Session() (*auth.Session, error) {
+	if e.session != nil {
+		return e.session, nil
+	}
+
+	s, err := auth.New(e.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	e.session = s
+
+	return s, nil
+}
+
+func commands() []Command {
+	return []Command{
+		smartCommand,
+		historyCommand,
+		statsCommand,
+		configCommand,
+		daemonCommand,
+		scrobbleCommand,
+	}
+}
+
+// Runs the subcommand named by the first argument.
+func Run(args []string, c *config.Config) error {
+	env := &Env{Config: c}
+
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		fmt.Println(Usage())
+		return nil
+	}
+
+	for _, cmd := range commands() {
+		if cmd.Name == args[0] {
+			if !cmd.ReadsConfig {
+				if err := c.Load(); err != nil {
+					return err
+				}
+			}
+
+			return cmd.Run(args[1:], env)
+		}
+	}
+
+	return errors.Input.New("unknown command %q, see \"spogo help\"", args[0])
+}
+
+// Lists every flag and subcommand and how to use them.
+func Usage() string {
+	lines := []string{"Usage: spogo [flags] [command]", "", "Flags:"}
+
+	for _, f := range flagUsage {
+		lines = append(lines, fmt.Sprintf("  %-22s %s", f[0], f[1]))
+	}
+
+	lines = append(lines, "", "Commands:")
+
+	for _, cmd := range commands() {
+		lines = append(lines, fmt.Sprintf("  %-10s %s", cmd.Name, cmd.Usage))
+	}
+
+	lines = append(lines, "",
+		"Options are read from, in increasing precedence: the defaults, config.yaml,",
+		"SPOGO_* environment variables named after the option, such as",
+		"SPOGO_ASCII_THRESHOLD for ascii.threshold, then --set flags.",
+	)
+
+	return strings.Join(lines, "\n")
+}
+
+// The flags given before the command, and what they do.
+var flagUsage = [][2]string{
+	{"--config file", "read the config from file, or $" + config.ENV_CONFIG},
+	{"--cache-dir dir", "keep tokens, images and logs in dir, or $" + config.ENV_CACHE_DIR},
+	{"--set option=value", "set an option, such as ascii.threshold=30, repeatable"},
+}
+
+// Repeated "--set" flags.
+type setFlags []string
+
+func (s *setFlags) String() string {
+	return strings.Join(*s, ", ")
+}
+
+func (s *setFlags) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// Parses the flags given before the command, returning
+// them and the command with its arguments.
+func ParseFlags(args []string) (config.Overrides, []string, error) {
+	o := config.Overrides{}
+
+	fs := flag.NewFlagSet("spogo", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.StringVar(&o.ConfigFile, "config", "", "")
+	fs.StringVar(&o.CacheDir, "cache-dir", "", "")
+	fs.Var((*setFlags)(&o.Set), "set", "")
+
+	if err := fs.Parse(args); err == flag.ErrHelp {
+		return o, []string{"help"}, nil
+	} else if err != nil {
+		return o, nil, errors.Input.New("%v, see \"spogo help\"", err)
+	}
+
+	return o, fs.Args(), nil
+}
+
+// Parses the flags wherever they are among the arguments, as flag
+// stops at the first argument that isn't one, returning the other
+// arguments in order.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	rest := []string{}
+
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+
+		if fs.NArg() == 0 {
+			return rest, nil
+		}
+
+		rest = append(rest, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/dionvu/spogo/config"
+	"github.com/dionvu/spogo/err"
+)
+
+var configCommand = Command{
+	Name:        "config",
+	Usage:       "[check|print-defaults]  check config.yaml and theme files, or print the default options",
+	Run:         runConfig,
+	ReadsConfig: true,
+}
+
+// "check" reports every problem in "config.yaml" and the theme files
+// with the line and column it is at, "print-defaults" prints the
+// options used where "config.yaml" doesn't set them.
+func runConfig(args []string, env *Env) error {
+	if len(args) == 0 {
+		return errors.Input.New("expected a config subcommand: check or print-defaults")
+	}
+
+	switch args[0] {
+	case "check":
+		return checkConfig(env.Config)
+	case "print-defaults":
+		defaults, err := config.DefaultsYAML()
+		if err != nil {
+			return err
+		}
+
+		fmt.Print(defaults)
+
+		return nil
+	default:
+		return errors.Input.New("unknown config subcommand %q", args[0])
+	}
+}
+
+// Prints the problems of every file, as opposed to stopping at the
+// first invalid one. The configured theme is checked with the config.
+func checkConfig(c *config.Config) error {
+	invalid := 0
+
+	if err := c.Load(); err != nil {
+		errors.Print(err)
+		invalid++
+	}
+
+	for _, name := range c.Themes() {
+		if name == c.ThemeName {
+			continue
+		}
+
+		if _, err := c.LoadTheme(name); err != nil {
+			errors.Print(err)
+			invalid++
+		}
+	}
+
+	if invalid > 0 {
+		return errors.Config.New("%d invalid file(s)", invalid)
+	}
+
+	fmt.Printf("%v and the themes in %v are valid\n", c.FilePath(), c.ThemesPath())
+
+	return nil
+}
@@ -0,0 +1,33 @@
+package cli
+
+import (
+	"github.com/dionvu/spogo/daemon"
+	"github.com/dionvu/spogo/err"
+	"github.com/dionvu/spogo/player"
+)
+
+var daemonCommand = Command{
+	Name:  "daemon",
+	Usage: "run hooks, notifications, record and scrobble plays in the background, without the tui",
+	Run:   runDaemon,
+}
+
+// Follows the player until interrupted. Meant to be started with the
+// session, for example by a systemd user service or "spogo daemon &".
+func runDaemon(args []string, env *Env) error {
+	if len(args) > 0 {
+		return errors.Input.New("daemon takes no arguments, got %q", args[0])
+	}
+
+	s, err := env.Session()
+	if err != nil {
+		return err
+	}
+
+	p, err := player.New(env.Config)
+	if err != nil {
+		return err
+	}
+
+	return daemon.Run(env.Config, s, p)
+}
@@ -0,0 +1,208 @@
+package cli
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dionvu/spogo/err"
+	"github.com/dionvu/spogo/history"
+)
+
+var historyCommand = Command{
+	Name:  "history",
+	Usage: "[searches|recent|plays [--since date] [--until date] [--csv]|purge [searches|recent|all]] [--json]  print or purge search history, or print tracks played",
+	Run:   runHistory,
+}
+
+// Prints the search history and recently opened items, one entry per
+// line separated by tabs, or as json with "--json". "purge" deletes
+// the history, optionally only the searches or the recent items.
+// "plays" prints the tracks played instead, see runPlays.
+func runHistory(args []string, env *Env) error {
+	fs := flag.NewFlagSet("history", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	asJson := fs.Bool("json", false, "print entries as json")
+
+	if err := fs.Parse(args); err != nil {
+		return errors.Input.Wrap(err, "invalid history arguments")
+	}
+
+	if fs.Arg(0) == "plays" {
+		return runPlays(fs.Args()[1:], env, *asJson)
+	}
+
+	// Flags given after the subcommand, such as "recent --json".
+	sub := flag.NewFlagSet("history "+fs.Arg(0), flag.ContinueOnError)
+	sub.SetOutput(io.Discard)
+	sub.BoolVar(asJson, "json", *asJson, "print entries as json")
+
+	var rest []string
+	if fs.NArg() > 0 {
+		var err error
+		if rest, err = parseInterspersed(sub, fs.Args()[1:]); err != nil {
+			return errors.Input.Wrap(err, "invalid history %s arguments", fs.Arg(0))
+		}
+	}
+
+	if fs.Arg(0) != "purge" && len(rest) > 0 {
+		return errors.Input.New("history %s takes no arguments, got %q", fs.Arg(0), rest[0])
+	}
+
+	h, err := history.LoadSearch(env.Config)
+	if err != nil {
+		return err
+	}
+
+	var entries []history.Entry
+
+	switch fs.Arg(0) {
+	case "":
+		entries = h.All()
+	case "searches":
+		entries = h.Queries()
+	case "recent":
+		entries = h.Recent()
+	case "purge":
+		if len(rest) > 1 {
+			return errors.Input.New("history purge takes one history to purge, got %q", rest)
+		}
+
+		switch strings.Join(rest, "") {
+		case "", "all":
+			return h.Purge(true, true)
+		case "searches":
+			return h.Purge(true, false)
+		case "recent":
+			return h.Purge(false, true)
+		default:
+			return errors.Input.New("unknown history to purge %q", rest[0])
+		}
+	default:
+		return errors.Input.New("unknown history subcommand %q", fs.Arg(0))
+	}
+
+	if *asJson {
+		b, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return errors.JSONMarshal.Wrap(err, "failed to marshal history")
+		}
+
+		fmt.Println(string(b))
+		return nil
+	}
+
+	for _, e := range entries {
+		fmt.Printf("%s\t%s\t%s\t%s\n", e.Time.Format(time.RFC3339), e.Kind, e.Name, e.Uri)
+	}
+
+	return nil
+}
+
+// Prints the tracks played, oldest first, one per line separated by
+// tabs, or as json or csv. "--since" and "--until" take a date such
+// as "2024-06-01", a time such as "2024-06-01T18:00:00Z", or a time
+// ago such as "7d" or "12h".
+func runPlays(args []string, env *Env, asJson bool) error {
+	fs := flag.NewFlagSet("plays", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.BoolVar(&asJson, "json", asJson, "print plays as json")
+	asCsv := fs.Bool("csv", false, "print plays as csv")
+	sinceFlag := fs.String("since", "", "only plays started since")
+	untilFlag := fs.String("until", "", "only plays started before")
+
+	if err := fs.Parse(args); err != nil {
+		return errors.Input.Wrap(err, "invalid history plays arguments")
+	}
+
+	if fs.NArg() > 0 {
+		return errors.Input.New("history plays takes no arguments, got %q", fs.Arg(0))
+	}
+
+	now := time.Now()
+
+	since, err := parseDate(*sinceFlag, now, false)
+	if err != nil {
+		return err
+	}
+
+	until, err := parseDate(*untilFlag, now, true)
+	if err != nil {
+		return err
+	}
+
+	plays, err := history.NewPlays(env.Config).Between(since, until)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case asJson:
+		b, err := json.MarshalIndent(plays, "", "  ")
+		if err != nil {
+			return errors.JSONMarshal.Wrap(err, "failed to marshal plays")
+		}
+
+		fmt.Println(string(b))
+
+	case *asCsv:
+		w := csv.NewWriter(os.Stdout)
+		w.Write([]string{"started_at", "track_id", "name", "artists", "album", "context_uri", "device", "listened_ms", "duration_ms"})
+
+		for _, p := range plays {
+			w.Write([]string{
+				p.StartedAt.Format(time.RFC3339), p.TrackID, p.Name, strings.Join(p.Artists, ", "), p.Album,
+				p.ContextUri, p.Device, strconv.Itoa(p.ListenedMs), strconv.Itoa(p.DurationMs),
+			})
+		}
+
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return errors.FileWrite.Wrap(err, "failed to write plays as csv")
+		}
+
+	default:
+		for _, p := range plays {
+			fmt.Printf("%s\t%s\t%s\t%s\n", p.StartedAt.Format(time.RFC3339), p.Name, strings.Join(p.Artists, ", "), p.Album)
+		}
+	}
+
+	return nil
+}
+
+// Reads a date, time or time ago, zero if empty. A date given for
+// the end of a range includes the whole day.
+func parseDate(s string, now time.Time, end bool) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.ParseInLocation(time.DateOnly, s, time.Local); err == nil {
+		if end {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+
+	if d, err := time.ParseDuration(s); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+
+	return time.Time{}, errors.Input.New("invalid date %q, expected a date such as 2024-06-01, a time, or a time ago such as 7d", s)
+}
@@ -0,0 +1,141 @@
+package cli
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dionvu/spogo/err"
+	"github.com/dionvu/spogo/scrobble"
+	"github.com/fatih/color"
+)
+
+var scrobbleCommand = Command{
+	Name:  "scrobble",
+	Usage: "[queue [--json]|send|auth lastfm]  print or send the listens waiting to be scrobbled, or sign in to lastfm",
+	Run:   runScrobble,
+}
+
+// Prints the listens queued for each service, one per line separated
+// by tabs, or as json with "--json". "send" submits the queues of the
+// enabled services straight away, and "auth lastfm" gets the session
+// key scrobbles to Last.fm are sent with.
+func runScrobble(args []string, env *Env) error {
+	fs := flag.NewFlagSet("scrobble", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	asJson := fs.Bool("json", false, "print the queue as json")
+
+	// Flags may follow the subcommand, as in "queue --json".
+	args, err := parseInterspersed(fs, args)
+	if err != nil {
+		return errors.Input.Wrap(err, "invalid scrobble arguments")
+	}
+
+	arg := func(i int) string {
+		if i < len(args) {
+			return args[i]
+		}
+		return ""
+	}
+
+	queue := scrobble.NewQueue(env.Config)
+
+	switch arg(0) {
+	case "", "queue":
+		return printQueue(queue, *asJson)
+
+	case "send":
+		services := scrobble.Services(env.Config.Scrobbling)
+		if len(services) == 0 {
+			return errors.Input.New("no scrobbling service is enabled in %v", env.Config.FilePath())
+		}
+
+		for _, service := range services {
+			if err := scrobble.Send(queue, service, nil); err != nil {
+				return err
+			}
+
+			fmt.Println(color.HiGreenString("sent the %s queue", service.Name()))
+		}
+
+		return nil
+
+	case "auth":
+		if arg(1) != scrobble.SERVICE_LASTFM {
+			return errors.Input.New("only lastfm needs signing in, got %q", arg(1))
+		}
+
+		return authLastFM(env)
+
+	default:
+		return errors.Input.New("unknown scrobble subcommand %q", arg(0))
+	}
+}
+
+func printQueue(queue *scrobble.Queue, asJson bool) error {
+	pending, err := queue.Pending()
+	if err != nil {
+		return err
+	}
+
+	if asJson {
+		b, err := json.MarshalIndent(pending, "", "  ")
+		if err != nil {
+			return errors.JSONMarshal.Wrap(err, "failed to marshal scrobble queue")
+		}
+
+		fmt.Println(string(b))
+		return nil
+	}
+
+	services := []string{}
+	for service := range pending {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+
+	for _, service := range services {
+		for _, q := range pending[service] {
+			l := q.Listen
+			fmt.Printf("%s\t%s\t%s\t%s\n", service, l.StartedAt.Format(time.RFC3339), l.Track, strings.Join(l.Artists, ", "))
+		}
+	}
+
+	return nil
+}
+
+// Has the user allow spogo's api account to scrobble for them,
+// printing the session key to set in "config.yaml".
+func authLastFM(env *Env) error {
+	o := env.Config.Scrobbling.LastFM
+	if o.ApiKey == "" || o.Secret == "" {
+		return errors.Input.New("set scrobbling.lastfm.api_key and secret in %v first", env.Config.FilePath())
+	}
+
+	fm := scrobble.NewLastFM(o)
+
+	token, err := fm.Token()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Allow spogo to scrobble at %s\nthen press enter.\n", fm.AuthUrl(token))
+
+	bufio.NewReader(os.Stdin).ReadString('\n')
+
+	name, key, err := fm.Session(token)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Signed in as %s, set this in %v:\n\nscrobbling:\n  lastfm:\n    enabled: true\n    session_key: %q\n",
+		name, env.Config.FilePath(), key)
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"time"
+
+	"github.com/dionvu/spogo/config"
+	"github.com/dionvu/spogo/err"
+	"github.com/dionvu/spogo/smart"
+	"github.com/fatih/color"
+	"github.com/joomcode/errorx"
+)
+
+var smartCommand = Command{
+	Name:  "smart",
+	Usage: "[--dry-run] [name...]  sync smart playlists defined in smart-playlists.yaml",
+	Run:   runSmart,
+}
+
+// Evaluates every smart playlist, or only the named ones, and
+// syncs them into spotify. With "--dry-run" the planned changes
+// are printed without modifying any playlist.
+func runSmart(args []string, env *Env) error {
+	fs := flag.NewFlagSet("smart", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	dryRun := fs.Bool("dry-run", false, "print planned changes without applying them")
+
+	if err := fs.Parse(args); err != nil {
+		return errors.Input.Wrap(err, "invalid smart arguments")
+	}
+
+	file, err := smart.Load(env.Config.SmartPlaylistFile())
+	if err != nil {
+		return err
+	}
+
+	names := map[string]bool{}
+	for _, name := range fs.Args() {
+		names[name] = true
+	}
+
+	s, err := env.Session()
+	if err != nil {
+		return err
+	}
+
+	lib := smart.NewLibrary(s)
+	now := time.Now()
+
+	for _, def := range file.Playlists {
+		if len(names) > 0 && !names[def.Name] {
+			continue
+		}
+
+		plan, err := smart.NewPlan(def, lib, now)
+		if err != nil {
+			return signInHint(err, env.Config)
+		}
+
+		fmt.Println(plan)
+
+		if *dryRun || plan.Empty() {
+			continue
+		}
+
+		if err := plan.Apply(s); err != nil {
+			return signInHint(err, env.Config)
+		}
+
+		fmt.Println(color.HiGreenString("  synced"))
+	}
+
+	return nil
+}
+
+// Tells how to sign in again when spotify refuses a scope the
+// session was signed in without, such as sessions from before
+// smart playlists.
+func signInHint(err error, c *config.Config) error {
+	if !errors.IsMissingScopeErr(err) {
+		return err
+	}
+
+	return errors.MissingScope.New("%s: remove %v and %v then run spogo", err.(*errorx.Error).Message(),
+		filepath.Join(c.CachePath(), config.ACCESSTOKENFILE), filepath.Join(c.CachePath(), config.REQUESTTOKENFILE))
+}
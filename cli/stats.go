@@ -0,0 +1,82 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/dionvu/spogo/err"
+	"github.com/dionvu/spogo/stats"
+	"golang.org/x/term"
+)
+
+var statsCommand = Command{
+	Name:  "stats",
+	Usage: "[--since date] [--until date] [--json]  print top tracks, artists, albums and genres, and time listened per day",
+	Run:   runStats,
+}
+
+// Prints the listening stats of the plays recorded, and of spotify's
+// top items over the closest time range. "--since" and "--until"
+// take the same dates as "spogo history plays", the stats covering
+// every play recorded by default.
+func runStats(args []string, env *Env) error {
+	fs := flag.NewFlagSet("stats", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	asJson := fs.Bool("json", false, "print stats as json")
+	sinceFlag := fs.String("since", "", "only plays started since")
+	untilFlag := fs.String("until", "", "only plays started before")
+
+	if err := fs.Parse(args); err != nil {
+		return errors.Input.Wrap(err, "invalid stats arguments")
+	}
+
+	if fs.NArg() > 0 {
+		return errors.Input.New("stats takes no arguments, got %q", fs.Arg(0))
+	}
+
+	now := time.Now()
+
+	since, err := parseDate(*sinceFlag, now, false)
+	if err != nil {
+		return err
+	}
+
+	until, err := parseDate(*untilFlag, now, true)
+	if err != nil {
+		return err
+	}
+
+	s, err := env.Session()
+	if err != nil {
+		return err
+	}
+
+	r, err := stats.New(env.Config, s, since, until)
+	if err != nil {
+		return err
+	}
+
+	if *asJson {
+		b, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return errors.JSONMarshal.Wrap(err, "failed to marshal stats")
+		}
+
+		fmt.Println(string(b))
+		return nil
+	}
+
+	// Output that isn't to a terminal is as wide as the report gets.
+	width, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil {
+		width = stats.MAX_TEXT_WIDTH
+	}
+
+	fmt.Print(r.Text(min(width, stats.MAX_TEXT_WIDTH)))
+
+	return nil
+}
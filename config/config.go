@@ -1,16 +1,17 @@
 package config
 
 import (
+	_ "embed"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/dionvu/spogo/err"
-	"github.com/fatih/color"
 	"gopkg.in/yaml.v3"
 )
 
@@ -20,6 +21,42 @@ const (
 	ACCESSTOKENFILE  = "access-token.json"
 	REQUESTTOKENFILE = "refresh-token.json"
 	DEVICEFILE       = "device.json"
+	SMARTFILE        = "smart-playlists.yaml"
+	HISTORYFILE      = "history.json"
+	DAEMONFILE       = "daemon.pid"
+	PLAYSFILE        = "plays.db"
+	SCROBBLEFILE     = "scrobbles.db"
+	IMAGESFOLDER     = "assets"
+
+	// Size of the image cache when none is configured.
+	DEFAULT_IMAGE_CACHE_MB = 100
+
+	DEFAULT_THRESHOLD   = 20
+	DEFAULT_DEBOUNCE_MS = 300
+)
+
+// The "config.yaml" written on first run, with every option documented.
+//
+//go:embed config.yaml
+var configTemplate []byte
+
+// Ways of signing in to spotify, either with the client ID and secret,
+// or with the client ID and a code generated for each sign in.
+const (
+	AUTH_FLOW_CODE = "authorization_code"
+	AUTH_FLOW_PKCE = "pkce"
+)
+
+// Ways of drawing album art. Auto picks a graphics protocol the
+// terminal supports, falling back to half blocks in terminals
+// with 24-bit color, then ascii.
+const (
+	RENDERER_AUTO      = "auto"
+	RENDERER_KITTY     = "kitty"
+	RENDERER_ITERM     = "iterm"
+	RENDERER_SIXEL     = "sixel"
+	RENDERER_HALFBLOCK = "halfblock"
+	RENDERER_ASCII     = "ascii"
 )
 
 // The struct that holds configuration options from "config.yaml",
@@ -28,8 +65,20 @@ const (
 type Config struct {
 	path      string
 	cachePath string
-	Spotify   Credentials `yaml:"spotify"`
 
+	// The layout of the file, older files are upgraded when loaded.
+	Version int `yaml:"version"`
+
+	Spotify Credentials `yaml:"spotify"`
+
+	// Set by the "--config" flag, instead of "config.yaml"
+	// in the config path.
+	file string
+
+	overrides Overrides
+
+	// Colors from before themes, moved to ThemeOverrides when older
+	// files are upgraded. Only kept to report them if still set.
 	Player struct {
 		StatusBar struct {
 			NowPlaying struct {
@@ -64,7 +113,7 @@ type Config struct {
 		Text struct {
 			Color string `yaml:"color"`
 		} `yaml:"text"`
-	} `yaml:"player"`
+	} `yaml:"player,omitempty"`
 
 	General struct {
 		Box struct {
@@ -74,41 +123,107 @@ type Config struct {
 		ViewStatus struct {
 			Color string `yaml:"color"`
 		} `yaml:"view_status"`
-	} `yaml:"general"`
+	} `yaml:"general,omitempty"`
 
 	Ascii struct {
 		Threshold int  `yaml:"threshold"`
 		Grayscale bool `yaml:"grayscale"`
 		Enabled   bool `yaml:"enabled"`
+
+		// One of the renderers, auto if empty. Threshold only applies
+		// to the ascii renderer, grayscale also to half blocks.
+		Renderer string `yaml:"renderer"`
 	} `yaml:"ascii"`
 
 	ControlBar struct {
 		Enabled bool `yaml:"enabled"`
 	} `yaml:"control_bar"`
+
+	Search struct {
+		// Searches while the user types, instead of
+		// waiting for the query to be submitted.
+		Instant    bool `yaml:"instant"`
+		DebounceMs int  `yaml:"debounce_ms"`
+	} `yaml:"search"`
+
+	// Album art and playlist covers kept on disk, the least
+	// recently used are removed past the size limit.
+	ImageCache struct {
+		MaxSizeMb int `yaml:"max_size_mb"`
+	} `yaml:"image_cache"`
+
+	Keys KeymapConfig `yaml:"keys"`
+
+	// The name of a built in theme or of a file in the themes folder.
+	ThemeName string `yaml:"theme"`
+
+	// Elements of the theme replaced whichever theme is used,
+	// set as they are in a theme file.
+	ThemeOverrides Theme `yaml:"theme_overrides,omitempty"`
+
+	// Replaces the theme's accent colors with ones
+	// taken from the playing track's album art.
+	AlbumColors struct {
+		Enabled bool   `yaml:"enabled"`
+		Mode    string `yaml:"mode"`
+
+		// The terminal's background color, detected if empty, which
+		// the colors are kept readable against.
+		Background string `yaml:"background"`
+	} `yaml:"album_colors"`
+
+	Hooks Hooks `yaml:"hooks"`
+
+	Notifications Notifications `yaml:"notifications"`
+
+	Scrobbling Scrobbling `yaml:"scrobbling"`
+
+	RecentlyPlayed struct {
+		// Lists the plays recorded in the play history along
+		// with the tracks spotify reports as recently played.
+		LocalHistory bool `yaml:"local_history"`
+	} `yaml:"recently_played"`
+
+	// Built from Keys when the config is loaded.
+	keymap *Keymap
+
+	// Loaded from ThemeName when the config is loaded.
+	theme *Theme
+
+	// The "theme_overrides" section, decoded on top of each
+	// theme so only the elements it sets are replaced.
+	themeOverrides *yaml.Node
 }
 
-// Creates spogo config root directory, "config.yaml",
-// and spogo cache directory.
-func New() (*Config, error) {
-	c := &Config{}
+// Creates spogo config root directory and spogo cache directory,
+// or those given by the overrides. "config.yaml" is created
+// separately, see Create.
+func New(o Overrides) (*Config, error) {
+	c := &Config{overrides: o}
+
+	// Sets the root config path, the directory of the
+	// config file if one is given.
+	if file := o.configFile(); file != "" {
+		c.file = file
+		c.path = filepath.Dir(file)
+	} else {
+		path, err := os.UserConfigDir()
+		if err != nil {
+			err = errors.FileOpen.Wrap(err, "failed to get user's home directory")
+			errors.Log(err)
+			return nil, err
+		}
 
-	// Sets the root config path.
-	path, err := os.UserConfigDir()
-	if err != nil {
-		err = errors.FileOpen.Wrap(err, "failed to get user's home directory")
-		errors.Log(err)
-		return nil, err
+		c.path = filepath.Join(path, APPNAME)
 	}
 
-	cd, err := os.UserCacheDir()
+	cachePath, err := o.CachePath()
 	if err != nil {
-		err = errors.FileOpen.Wrap(err, "failed to get user's cache directory")
 		errors.Log(err)
 		return nil, err
 	}
 
 	// Ensures ".config/spogo" exists.
-	c.path = filepath.Join(path, APPNAME)
 	if err := os.MkdirAll(c.path, os.ModePerm); err != nil {
 		err = errors.FileCreate.Wrap(err, fmt.Sprintf("creating file path %v", c.path))
 		errors.Log(err)
@@ -116,87 +231,245 @@ func New() (*Config, error) {
 	}
 
 	// Ensures ".cache/spogo" exists.
-	c.cachePath = filepath.Join(cd, APPNAME)
+	c.cachePath = cachePath
 	if err := os.MkdirAll(c.cachePath, os.ModePerm); err != nil {
 		err = errors.FileCreate.Wrap(err, fmt.Sprintf("creating file path %v", c.cachePath))
 		errors.Log(err)
 		return nil, err
 	}
 
-	// Creates "config.yaml" if it doesn't exist.
-	if !c.Exists() {
-		if err := c.create(); err != nil {
-			return nil, err
-		}
+	return c, nil
+}
+
+// The options used where "config.yaml" doesn't set them.
+func Defaults() *Config {
+	c := &Config{Version: CONFIG_VERSION, ThemeName: DEFAULT_THEME}
+
+	c.Spotify.AuthFlow = AUTH_FLOW_CODE
+
+	c.Ascii.Enabled = true
+	c.Ascii.Threshold = DEFAULT_THRESHOLD
+	c.Ascii.Renderer = RENDERER_AUTO
+
+	c.ControlBar.Enabled = true
+
+	c.Search.DebounceMs = DEFAULT_DEBOUNCE_MS
+
+	c.ImageCache.MaxSizeMb = DEFAULT_IMAGE_CACHE_MB
+
+	c.Keys.Preset = PRESET_DEFAULT
+
+	c.AlbumColors.Mode = ALBUM_COLORS_VIBRANT
+
+	c.Hooks.TimeoutMs = DEFAULT_HOOK_TIMEOUT_MS
+	c.Hooks.MaxRunning = DEFAULT_HOOK_MAX_RUNNING
+
+	c.Notifications.Summary = DEFAULT_NOTIFICATION_SUMMARY
+	c.Notifications.Body = DEFAULT_NOTIFICATION_BODY
+
+	c.Scrobbling.ListenBrainz.Url = DEFAULT_LISTENBRAINZ_URL
+	c.Scrobbling.LastFM.Url = DEFAULT_LASTFM_URL
+	c.Scrobbling.LastFM.AuthUrl = DEFAULT_LASTFM_AUTH_URL
+
+	return c
+}
+
+// The default options written as yaml, as they would be set in "config.yaml".
+func DefaultsYAML() (string, error) {
+	var b strings.Builder
+
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+
+	if err := enc.Encode(Defaults()); err != nil {
+		err = errors.YAML.Wrap(err, "failed to marshal default config")
+		errors.Log(err)
+		return "", err
 	}
 
-	return c, nil
+	return b.String(), nil
 }
 
-// Loads all config options and client ID & client secret from "config.yaml".
+// Loads all config options and client ID & client secret from
+// "config.yaml", then those set by the environment and flags.
 func (c *Config) Load() error {
-	file, err := os.Open(c.FilePath())
-	if err != nil {
-		return errors.FileOpen.Wrap(err, fmt.Sprintf("missing config file: %v", c.FilePath()))
-	}
-	defer file.Close()
+	overrides, problems := c.overrides.options()
 
-	b, err := io.ReadAll(file)
+	b, err := os.ReadFile(c.FilePath())
+	if os.IsNotExist(err) && len(overrides) > 0 {
+		// Every option can be set outside the file, as in containers.
+		b, err = nil, nil
+	}
+	if os.IsNotExist(err) {
+		return errors.FileOpen.Wrap(err, fmt.Sprintf("missing config file: %v, run spogo to create it", c.FilePath()))
+	}
 	if err != nil {
 		err = errors.FileRead.Wrap(err, fmt.Sprintf("failed to read config file: %v", c.FilePath()))
 		errors.Log(err)
 		return err
 	}
 
-	err = yaml.Unmarshal(b, c)
+	root, err := parseYAML(b, c.FilePath())
 	if err != nil {
-		err = errors.YAML.Wrap(err, fmt.Sprintf("failed to unmarshal yaml: %v", string(b)))
 		errors.Log(err)
 		return err
 	}
 
-	return nil
-}
+	problems = append(problems, migrate(root)...)
+	problems = append(problems, applyOverrides(root, overrides)...)
+
+	// Options the file doesn't set keep their defaults.
+	defaults := Defaults()
+	c.locate(defaults)
+	*c = *defaults
 
-// Creates the "config.yaml" file, assuming the config directory,
-// ".config/spogo" (for unix), exists.
-func (c *Config) create() error {
-	file, err := os.Create(c.FilePath())
+	decodeProblems, err := decodeChecked(root, c.FilePath(), c)
 	if err != nil {
-		err = errors.FileCreate.Wrap(err, fmt.Sprintf("creating file %v", c.FilePath()))
 		errors.Log(err)
 		return err
 	}
-	defer file.Close()
 
-	wd, err := os.Getwd()
+	problems = append(problems, decodeProblems...)
+	problems = append(problems, c.checkValues(root)...)
+
+	keymap, err := NewKeymap(c.Keys)
 	if err != nil {
-		err = errors.FileCreate.Wrap(err, "retrieving working directory")
-		errors.Log(err)
-		return err
+		problems = append(problems, problemAt(findOption(root, "keys"), "keys", "%s", message(err)))
 	}
 
-	confileFile, _ := os.Open(filepath.Join(wd, "config", CONFIGFILE))
-	b, _ := io.ReadAll(confileFile)
+	t, err := c.LoadTheme(c.ThemeName)
 	if err != nil {
-		err = errors.FileCreate.Wrap(err, "reading config template file")
+		problems = append(problems, problemAt(findOption(root, "theme"), "theme", "%s", message(err)))
+	}
+
+	if len(problems) > 0 {
+		err = problemsError(c.FilePath(), problems)
 		errors.Log(err)
 		return err
 	}
 
-	_, err = file.WriteString(string(b))
+	c.keymap = keymap
+	c.themeOverrides = findOption(root, "theme_overrides")
+	c.applyThemeOverrides(&t)
+	c.theme = &t
+
+	return nil
+}
+
+// Reads the config file into a new config, leaving this
+// one unchanged should the file be invalid.
+func (c *Config) Reload() (*Config, error) {
+	n := &Config{}
+	c.locate(n)
+
+	if err := n.Load(); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// Copies where the config is read from, and the options
+// overriding it, to n.
+func (c *Config) locate(n *Config) {
+	n.path, n.file, n.cachePath = c.path, c.file, c.cachePath
+	n.overrides = c.overrides
+}
+
+// Replaces the options with those of n, except the spotify
+// credentials, which are only read on start. Not safe to call while
+// the config is read elsewhere, so work in the background is given a
+// copy, and listeners are given the changed options by Update.
+func (c *Config) Apply(n *Config) {
+	spotify := c.Spotify
+	*c = *n
+	c.Spotify = spotify
+}
+
+// When the config file was last modified, zero if it can't be read.
+func (c *Config) ModTime() time.Time {
+	info, err := os.Stat(c.FilePath())
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// Returns the keymap built from the "keys" section,
+// or the default keymap if the config isn't loaded.
+func (c *Config) Keymap() *Keymap {
+	if c.keymap == nil {
+		c.keymap, _ = NewKeymap(KeymapConfig{})
+	}
+	return c.keymap
+}
+
+// Creates the "config.yaml" file from the template, assuming the
+// config directory, ".config/spogo" (for unix), exists.
+func (c *Config) Create() error {
+	return c.write(configTemplate)
+}
+
+// Creates the "config.yaml" file from the template, with the spotify
+// client and theme set to the given ones.
+func (c *Config) CreateWith(spotify Credentials, theme string) error {
+	b, err := setOptions(configTemplate, map[string]string{
+		"spotify.client_id":     spotify.ClientID,
+		"spotify.client_secret": spotify.ClientSecret,
+		"spotify.auth_flow":     spotify.AuthFlow,
+		"theme":                 theme,
+	})
 	if err != nil {
-		err = errors.FileWrite.Wrap(err, fmt.Sprintf("writing to file: %v", file.Name()))
 		errors.Log(err)
 		return err
 	}
 
-	fmt.Printf("Please enter your spotify client ID & client secret: %v\n", color.YellowString(c.FilePath()))
-	os.Exit(0)
+	return c.write(b)
+}
+
+// Only readable by the user, as the file holds the client secret.
+func (c *Config) write(b []byte) error {
+	if err := os.WriteFile(c.FilePath(), b, 0600); err != nil {
+		err = errors.FileWrite.Wrap(err, fmt.Sprintf("writing to file: %v", c.FilePath()))
+		errors.Log(err)
+		return err
+	}
 
 	return nil
 }
 
+// Replaces the values of the dotted options in the yaml file, keeping
+// everything else as written, including comments.
+func setOptions(b []byte, options map[string]string) ([]byte, error) {
+	root := &yaml.Node{}
+	if err := yaml.Unmarshal(b, root); err != nil {
+		return nil, errors.YAML.Wrap(err, "failed to unmarshal config template")
+	}
+
+	lines := strings.Split(string(b), "\n")
+
+	for option, value := range options {
+		node := findOption(root, option)
+		if node == nil || node.Kind != yaml.ScalarNode {
+			return nil, errors.YAML.New("config template is missing option %q", option)
+		}
+
+		// The length of the value as written, with any quotes.
+		length := len(node.Value)
+		switch node.Style {
+		case yaml.DoubleQuotedStyle:
+			length = len(strconv.Quote(node.Value))
+		case yaml.SingleQuotedStyle:
+			length += 2
+		}
+
+		line := lines[node.Line-1]
+		start := node.Column - 1
+		lines[node.Line-1] = line[:start] + strconv.Quote(value) + line[start+length:]
+	}
+
+	return []byte(strings.Join(lines, "\n")), nil
+}
+
 // Returns the config path, ".config/spogo" for unix.
 func (c *Config) Path() string {
 	return c.path
@@ -204,6 +477,9 @@ func (c *Config) Path() string {
 
 // Returns the config file, ".config/spogo/config.yaml" for unix.
 func (c *Config) FilePath() string {
+	if c.file != "" {
+		return c.file
+	}
 	return filepath.Join(c.Path(), CONFIGFILE)
 }
 
@@ -217,6 +493,48 @@ func (c *Config) DeviceFile() string {
 	return filepath.Join(c.CachePath(), DEVICEFILE)
 }
 
+// Returns the smart playlist definitions file,
+// ".config/spogo/smart-playlists.yaml" for unix.
+func (c *Config) SmartPlaylistFile() string {
+	return filepath.Join(c.Path(), SMARTFILE)
+}
+
+// Returns the search history file, ".cache/spogo/history.json" for unix.
+func (c *Config) HistoryFile() string {
+	return filepath.Join(c.CachePath(), HISTORYFILE)
+}
+
+// Returns the file holding the pid of "spogo daemon" while it runs,
+// ".cache/spogo/daemon.pid" for unix.
+func (c *Config) DaemonFile() string {
+	return filepath.Join(c.CachePath(), DAEMONFILE)
+}
+
+// Returns the database of every play recorded,
+// ".cache/spogo/plays.db" for unix.
+func (c *Config) PlaysFile() string {
+	return filepath.Join(c.CachePath(), PLAYSFILE)
+}
+
+// Returns the database of listens yet to be scrobbled,
+// ".cache/spogo/scrobbles.db" for unix.
+func (c *Config) ScrobbleFile() string {
+	return filepath.Join(c.CachePath(), SCROBBLEFILE)
+}
+
+// Returns the image cache folder, ".cache/spogo/assets" for unix.
+func (c *Config) ImagesPath() string {
+	return filepath.Join(c.CachePath(), IMAGESFOLDER)
+}
+
+// Returns the image cache size limit in bytes.
+func (c *Config) ImageCacheSize() int64 {
+	if c.ImageCache.MaxSizeMb <= 0 {
+		return DEFAULT_IMAGE_CACHE_MB << 20
+	}
+	return int64(c.ImageCache.MaxSizeMb) << 20
+}
+
 // Returns true if the config file, "config.yaml", exists.
 func (c *Config) Exists() bool {
 	if _, err := os.ReadFile(c.FilePath()); err != nil {
@@ -228,10 +546,21 @@ func (c *Config) Exists() bool {
 type Credentials struct {
 	ClientID     string `yaml:"client_id"`
 	ClientSecret string `yaml:"client_secret"`
+
+	// How spogo signs in to spotify, the pkce flow
+	// doesn't need the client secret.
+	AuthFlow string `yaml:"auth_flow"`
+}
+
+// Returns true if spogo signs in with only the client ID.
+func (c *Credentials) PKCE() bool {
+	return c.AuthFlow == AUTH_FLOW_PKCE
 }
 
 // Attempts to do the "client credentials" authentication flow
-// to test validity of spotify client ID and client secret.
+// to test validity of spotify client ID and client secret. Returns
+// false without an error only if spotify rejected them, and an error
+// if spotify couldn't be asked.
 func (c *Credentials) Valid() (bool, error) {
 	data := url.Values{}
 	data.Set("grant_type", "client_credentials")
@@ -247,11 +576,23 @@ func (c *Credentials) Valid() (bool, error) {
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	req.SetBasicAuth(c.ClientID, c.ClientSecret)
 
-	if res, err := http.DefaultClient.Do(req); err != nil || res.StatusCode != http.StatusOK {
-		err = errors.HTTP.Wrap(err, fmt.Sprintf("unable to do http request: %v", err))
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		err = errors.HTTP.Wrap(err, fmt.Sprintf("unable to reach spotify: %v", err))
 		errors.Log(err)
 		return false, err
 	}
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusOK:
+		return true, nil
+
+	case http.StatusBadRequest, http.StatusUnauthorized:
+		return false, nil
+	}
 
-	return true, nil
+	err = errors.HTTP.New("spotify failed to check the client: %v", res.Status)
+	errors.Log(err)
+	return false, err
 }
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Changes in the player's state that hooks can run on.
+const (
+	EVENT_TRACK_CHANGED    = "track_changed"
+	EVENT_PLAYBACK_STARTED = "playback_started"
+	EVENT_PLAYBACK_PAUSED  = "playback_paused"
+	EVENT_SEEKED           = "seeked"
+	EVENT_VOLUME_CHANGED   = "volume_changed"
+	EVENT_DEVICE_CHANGED   = "device_changed"
+	EVENT_SHUFFLE_CHANGED  = "shuffle_changed"
+	EVENT_REPEAT_CHANGED   = "repeat_changed"
+	EVENT_CONTEXT_CHANGED  = "context_changed"
+)
+
+var EVENTS = []string{
+	EVENT_TRACK_CHANGED,
+	EVENT_PLAYBACK_STARTED,
+	EVENT_PLAYBACK_PAUSED,
+	EVENT_SEEKED,
+	EVENT_VOLUME_CHANGED,
+	EVENT_DEVICE_CHANGED,
+	EVENT_SHUFFLE_CHANGED,
+	EVENT_REPEAT_CHANGED,
+	EVENT_CONTEXT_CHANGED,
+}
+
+const (
+	DEFAULT_HOOK_TIMEOUT_MS  = 10000
+	DEFAULT_HOOK_MAX_RUNNING = 4
+)
+
+// Commands run on changes in the player's state, from the tui or,
+// while it runs, from "spogo daemon" instead.
+type Hooks struct {
+	// Hooks still running after the timeout are killed.
+	TimeoutMs int `yaml:"timeout_ms"`
+
+	// Hooks started past this many running wait for one to finish.
+	MaxRunning int `yaml:"max_running"`
+
+	// Shell commands run for each event, by the event's name.
+	On map[string][]string `yaml:"on"`
+}
+
+// Reports events in the "hooks.on" section that don't exist.
+func (h Hooks) checkEvents(root *yaml.Node) []Problem {
+	problems := []Problem{}
+
+	node := findOption(root, "hooks.on")
+	if node == nil || node.Kind != yaml.MappingNode {
+		return problems
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i]
+		if slices.Contains(EVENTS, key.Value) {
+			continue
+		}
+
+		suggestion := suggestName(key.Value, EVENTS)
+		if suggestion == "" {
+			suggestion = ", expected one of " + strings.Join(EVENTS, ", ")
+		}
+
+		problems = append(problems, problemAt(key, "hooks.on."+key.Value, "unknown event%s", suggestion))
+	}
+
+	return problems
+}
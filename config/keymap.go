@@ -0,0 +1,456 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dionvu/spogo/err"
+)
+
+// Actions that can be bound to keys.
+const (
+	ACTION_QUIT              = "quit"
+	ACTION_BACK              = "back"
+	ACTION_SELECT            = "select"
+	ACTION_REFRESH           = "refresh"
+	ACTION_PLAY_PAUSE        = "play_pause"
+	ACTION_NEXT_TRACK        = "next_track"
+	ACTION_PREV_TRACK        = "prev_track"
+	ACTION_SEEK_FORWARD      = "seek_forward"
+	ACTION_SEEK_BACKWARD     = "seek_backward"
+	ACTION_VOLUME_UP         = "volume_up"
+	ACTION_VOLUME_DOWN       = "volume_down"
+	ACTION_VOLUME_UP_SMALL   = "volume_up_small"
+	ACTION_VOLUME_DOWN_SMALL = "volume_down_small"
+	ACTION_TOGGLE_SHUFFLE    = "toggle_shuffle"
+	ACTION_TOGGLE_REPEAT     = "toggle_repeat"
+	ACTION_PLAYER_VIEW       = "player_view"
+	ACTION_PLAYLIST_VIEW     = "playlist_view"
+	ACTION_SEARCH_VIEW       = "search_view"
+	ACTION_HELP_VIEW         = "help_view"
+	ACTION_STATS_VIEW        = "stats_view"
+	ACTION_RECENT_VIEW       = "recent_view"
+	ACTION_SELECT_DEVICE     = "select_device"
+	ACTION_ALBUM_TRACKS      = "album_tracks"
+	ACTION_PLAYLIST_TRACKS   = "playlist_tracks"
+	ACTION_TRACK_ALBUM       = "track_album"
+	ACTION_TRACK_ARTIST      = "track_artist"
+	ACTION_COMMAND_PALETTE   = "command_palette"
+	ACTION_CYCLE_THEME       = "cycle_theme"
+	ACTION_NEXT_TAB          = "next_tab"
+	ACTION_PREV_TAB          = "prev_tab"
+)
+
+// The views that can override global bindings.
+const (
+	KEYMAP_VIEW_PLAYER   = "player"
+	KEYMAP_VIEW_PLAYLIST = "playlist"
+	KEYMAP_VIEW_SEARCH   = "search"
+)
+
+const (
+	PRESET_DEFAULT = "default"
+	PRESET_VIM     = "vim"
+	PRESET_EMACS   = "emacs"
+
+	// Written in config files instead of " ", since keys
+	// of a chord are separated by spaces.
+	KEY_SPACE = "space"
+)
+
+var KEYMAP_VIEWS = []string{KEYMAP_VIEW_PLAYER, KEYMAP_VIEW_PLAYLIST, KEYMAP_VIEW_SEARCH}
+
+// Describes a bindable action.
+type ActionInfo struct {
+	Name        string
+	Description string
+
+	// Important actions are still triggered while the user is typing
+	// a search query, as long as they are bound to a single key
+	// that doesn't type text, such as "f1" or "ctrl+p".
+	Important bool
+}
+
+// Every bindable action, in the order they are listed in the help view.
+var ACTIONS = []ActionInfo{
+	{ACTION_PLAY_PAUSE, "Play or pause", false},
+	{ACTION_NEXT_TRACK, "Skip to the next track", false},
+	{ACTION_PREV_TRACK, "Skip to the previous track", false},
+	{ACTION_SEEK_FORWARD, "Seek forward 10 seconds", false},
+	{ACTION_SEEK_BACKWARD, "Seek backward 10 seconds", false},
+	{ACTION_VOLUME_UP, "Volume up 5%", false},
+	{ACTION_VOLUME_DOWN, "Volume down 5%", false},
+	{ACTION_VOLUME_UP_SMALL, "Volume up 1%", false},
+	{ACTION_VOLUME_DOWN_SMALL, "Volume down 1%", false},
+	{ACTION_TOGGLE_SHUFFLE, "Toggle shuffle", false},
+	{ACTION_TOGGLE_REPEAT, "Toggle repeat", false},
+	{ACTION_PLAYER_VIEW, "Go to the player", true},
+	{ACTION_PLAYLIST_VIEW, "Go to playlists", true},
+	{ACTION_SEARCH_VIEW, "Go to search", true},
+	{ACTION_STATS_VIEW, "Show listening stats, again for the next period", true},
+	{ACTION_RECENT_VIEW, "Show recently played tracks", true},
+	{ACTION_HELP_VIEW, "Show help", true},
+	{ACTION_COMMAND_PALETTE, "Open the command palette", false},
+	{ACTION_CYCLE_THEME, "Switch to the next theme", false},
+	{ACTION_SELECT_DEVICE, "Select a playback device", true},
+	{ACTION_ALBUM_TRACKS, "Find a track in the playing album", true},
+	{ACTION_PLAYLIST_TRACKS, "Find a track in the selected playlist", false},
+	{ACTION_TRACK_ALBUM, "Go to the album of the selected or playing track", false},
+	{ACTION_TRACK_ARTIST, "Go to the artist of the selected or playing track", false},
+	{ACTION_NEXT_TAB, "Show the next type of search results", false},
+	{ACTION_PREV_TAB, "Show the previous type of search results", false},
+	{ACTION_SELECT, "Select", true},
+	{ACTION_BACK, "Back", false},
+	{ACTION_REFRESH, "Redraw the screen", false},
+	{ACTION_QUIT, "Quit", false},
+}
+
+// The bindings of the default preset, every other preset
+// starts from these.
+var defaultBindings = map[string][]string{
+	ACTION_QUIT:              {"q", "ctrl+c"},
+	ACTION_BACK:              {"esc"},
+	ACTION_SELECT:            {"enter"},
+	ACTION_REFRESH:           {"ctrl+r"},
+	ACTION_PLAY_PAUSE:        {KEY_SPACE},
+	ACTION_NEXT_TRACK:        {">"},
+	ACTION_PREV_TRACK:        {"<"},
+	ACTION_SEEK_FORWARD:      {"."},
+	ACTION_SEEK_BACKWARD:     {","},
+	ACTION_VOLUME_UP:         {"]"},
+	ACTION_VOLUME_DOWN:       {"["},
+	ACTION_VOLUME_UP_SMALL:   {"}"},
+	ACTION_VOLUME_DOWN_SMALL: {"{"},
+	ACTION_TOGGLE_SHUFFLE:    {"s"},
+	ACTION_TOGGLE_REPEAT:     {"r"},
+	ACTION_PLAYER_VIEW:       {"f1", "ctrl+o"},
+	ACTION_PLAYLIST_VIEW:     {"f2", "ctrl+p"},
+	ACTION_SEARCH_VIEW:       {"f3", "/"},
+	ACTION_HELP_VIEW:         {"f4"},
+	ACTION_STATS_VIEW:        {"f5"},
+	ACTION_RECENT_VIEW:       {"f6"},
+	ACTION_SELECT_DEVICE:     {"ctrl+d"},
+	ACTION_ALBUM_TRACKS:      {"ctrl+a"},
+	ACTION_PLAYLIST_TRACKS:   {"t"},
+	ACTION_TRACK_ALBUM:       {"a"},
+	ACTION_TRACK_ARTIST:      {"A"},
+	ACTION_COMMAND_PALETTE:   {":"},
+	ACTION_CYCLE_THEME:       {"ctrl+t"},
+	ACTION_NEXT_TAB:          {"tab", "right", "l"},
+	ACTION_PREV_TAB:          {"shift+tab", "left", "h"},
+}
+
+// Bindings replaced by each preset.
+var presets = map[string]map[string][]string{
+	PRESET_DEFAULT: {},
+
+	PRESET_VIM: {
+		ACTION_QUIT:          {"q", "Z Z", "ctrl+c"},
+		ACTION_NEXT_TRACK:    {"L", ">"},
+		ACTION_PREV_TRACK:    {"H", "<"},
+		ACTION_SEEK_FORWARD:  {"l", "."},
+		ACTION_SEEK_BACKWARD: {"h", ","},
+		ACTION_VOLUME_UP:     {"+", "]"},
+		ACTION_VOLUME_DOWN:   {"-", "["},
+		ACTION_PLAYER_VIEW:   {"f1", "g p"},
+		ACTION_PLAYLIST_VIEW: {"f2", "g l"},
+		ACTION_SEARCH_VIEW:   {"f3", "/"},
+		ACTION_HELP_VIEW:     {"f4", "?"},
+		ACTION_STATS_VIEW:    {"f5", "g s"},
+		ACTION_RECENT_VIEW:   {"f6", "g r"},
+		ACTION_SELECT_DEVICE: {"g d", "ctrl+d"},
+		ACTION_ALBUM_TRACKS:  {"g a", "ctrl+a"},
+
+		// "h" and "l" seek instead.
+		ACTION_NEXT_TAB: {"tab", "right"},
+		ACTION_PREV_TAB: {"shift+tab", "left"},
+	},
+
+	PRESET_EMACS: {
+		ACTION_QUIT:              {"ctrl+x ctrl+c", "ctrl+c"},
+		ACTION_BACK:              {"esc", "ctrl+g"},
+		ACTION_REFRESH:           {"ctrl+l"},
+		ACTION_NEXT_TRACK:        {"alt+f"},
+		ACTION_PREV_TRACK:        {"alt+b"},
+		ACTION_SEEK_FORWARD:      {"ctrl+f"},
+		ACTION_SEEK_BACKWARD:     {"ctrl+b"},
+		ACTION_VOLUME_UP:         {"alt+="},
+		ACTION_VOLUME_DOWN:       {"alt+-"},
+		ACTION_VOLUME_UP_SMALL:   {"alt+]"},
+		ACTION_VOLUME_DOWN_SMALL: {"alt+["},
+		ACTION_TOGGLE_SHUFFLE:    {"alt+s"},
+		ACTION_TOGGLE_REPEAT:     {"alt+r"},
+		ACTION_PLAYER_VIEW:       {"f1", "ctrl+x p"},
+		ACTION_PLAYLIST_VIEW:     {"f2", "ctrl+x l"},
+		ACTION_SEARCH_VIEW:       {"f3", "ctrl+s"},
+		ACTION_HELP_VIEW:         {"f4", "ctrl+x h"},
+		ACTION_STATS_VIEW:        {"f5", "ctrl+x s"},
+		ACTION_RECENT_VIEW:       {"f6", "ctrl+x r"},
+		ACTION_SELECT_DEVICE:     {"ctrl+x d"},
+		ACTION_ALBUM_TRACKS:      {"ctrl+x a"},
+		ACTION_PLAYLIST_TRACKS:   {"ctrl+x t"},
+		ACTION_TRACK_ALBUM:       {"alt+a"},
+		ACTION_TRACK_ARTIST:      {"alt+A"},
+		ACTION_COMMAND_PALETTE:   {"alt+x"},
+	},
+}
+
+// The "keys" section of "config.yaml". Each binding maps an action to
+// one or more keys. Keys are written as bubbletea names them, such as
+// "ctrl+d", "f1" or "space", and a chord is several keys separated
+// by spaces, for example "g p".
+type KeymapConfig struct {
+	Preset   string                         `yaml:"preset"`
+	Bindings map[string][]string            `yaml:"bindings,omitempty"`
+	Views    map[string]map[string][]string `yaml:"views,omitempty"`
+}
+
+// A sequence of one or more keys.
+type Chord []string
+
+// Renders the chord as it is written in the config.
+func (c Chord) String() string {
+	return strings.Join(c, " ")
+}
+
+// The resolved bindings of every view, built from a preset
+// and the user's overrides.
+type Keymap struct {
+	// The key sequence of each binding mapped to
+	// its action, for every view.
+	views map[string]map[string]string
+
+	// The chords bound to each action, for every view.
+	actions map[string]map[string][]Chord
+}
+
+// Builds the keymap, failing if an action or view is unknown, or if
+// any view ends up with two actions on the same key, or with a chord
+// that starts with another binding.
+func NewKeymap(kc KeymapConfig) (*Keymap, error) {
+	preset := kc.Preset
+	if preset == "" {
+		preset = PRESET_DEFAULT
+	}
+
+	overrides, ok := presets[preset]
+	if !ok {
+		return nil, errors.Keymap.New("unknown keymap preset %q, expected default, vim or emacs", kc.Preset)
+	}
+
+	global := map[string][]string{}
+	for action, keys := range defaultBindings {
+		global[action] = keys
+	}
+
+	for _, layer := range []map[string][]string{overrides, kc.Bindings} {
+		for action, keys := range layer {
+			if !IsAction(action) {
+				return nil, errors.Keymap.New("unknown action %q in keymap", action)
+			}
+			global[action] = keys
+		}
+	}
+
+	km := &Keymap{
+		views:   map[string]map[string]string{},
+		actions: map[string]map[string][]Chord{},
+	}
+
+	for view := range kc.Views {
+		if !isKeymapView(view) {
+			return nil, errors.Keymap.New("unknown view %q in keymap, expected one of: %s", view, strings.Join(KEYMAP_VIEWS, ", "))
+		}
+	}
+
+	for _, view := range KEYMAP_VIEWS {
+		bindings := map[string][]string{}
+		for action, keys := range global {
+			bindings[action] = keys
+		}
+
+		for action, keys := range kc.Views[view] {
+			if !IsAction(action) {
+				return nil, errors.Keymap.New("unknown action %q in keymap for view %q", action, view)
+			}
+			bindings[action] = keys
+		}
+
+		if err := km.add(view, bindings); err != nil {
+			return nil, err
+		}
+	}
+
+	return km, nil
+}
+
+// Adds the bindings of a single view, checking for conflicts.
+func (km *Keymap) add(view string, bindings map[string][]string) error {
+	keys := map[string]string{}
+	actions := map[string][]Chord{}
+
+	// Sorted so conflicts are always reported the same way.
+	names := make([]string, 0, len(bindings))
+	for action := range bindings {
+		names = append(names, action)
+	}
+	sort.Strings(names)
+
+	for _, action := range names {
+		for _, binding := range bindings[action] {
+			chord := ParseChord(binding)
+			if len(chord) == 0 {
+				return errors.Keymap.New("empty key bound to %q", action)
+			}
+
+			seq := chord.String()
+
+			if other, ok := keys[seq]; ok && other != action {
+				return errors.Keymap.New("%q is bound to both %q and %q in view %q", binding, other, action, view)
+			}
+
+			keys[seq] = action
+			actions[action] = append(actions[action], chord)
+		}
+	}
+
+	// A chord can't start with a key sequence that is already
+	// bound, since the shorter binding would always fire first.
+	for seq, action := range keys {
+		for other, otherAction := range keys {
+			if seq != other && strings.HasPrefix(other, seq+" ") {
+				return errors.Keymap.New("%q bound to %q is the start of %q bound to %q in view %q",
+					seq, action, other, otherAction, view)
+			}
+		}
+	}
+
+	km.views[view] = keys
+	km.actions[view] = actions
+
+	return nil
+}
+
+// Splits a binding into its keys, translating "space" to the
+// " " bubbletea reports for the space bar.
+func ParseChord(binding string) Chord {
+	chord := Chord{}
+	for _, key := range strings.Fields(binding) {
+		if key == KEY_SPACE {
+			key = " "
+		}
+		chord = append(chord, key)
+	}
+	return chord
+}
+
+// Looks up the keys pressed so far in the given view. Returns the
+// bound action if they complete a binding, or whether they are
+// the start of a chord and more keys should be awaited.
+func (km *Keymap) Lookup(view string, keys []string) (action string, pending bool) {
+	seq := Chord(keys).String()
+
+	bindings, ok := km.views[view]
+	if !ok {
+		return "", false
+	}
+
+	if action, ok := bindings[seq]; ok {
+		return action, false
+	}
+
+	for other := range bindings {
+		if strings.HasPrefix(other, seq+" ") {
+			return "", true
+		}
+	}
+
+	return "", false
+}
+
+// The chords bound to the action in the given view.
+func (km *Keymap) Chords(view string, action string) []Chord {
+	return km.actions[view][action]
+}
+
+// A short description of the keys bound to the action,
+// such as "f1, ctrl+o", for hints and the help view.
+func (km *Keymap) Describe(view string, action string) string {
+	keys := []string{}
+	for _, c := range km.Chords(view, action) {
+		s := c.String()
+		if s == " " {
+			s = KEY_SPACE
+		}
+		keys = append(keys, s)
+	}
+
+	return strings.Join(keys, ", ")
+}
+
+// The first key bound to the action, for short hints such as
+// "ctrl+d to select a device". Returns "unbound" if none is.
+func (km *Keymap) First(view string, action string) string {
+	chords := km.Chords(view, action)
+	if len(chords) == 0 {
+		return "unbound"
+	}
+
+	s := chords[0].String()
+	if s == " " {
+		return KEY_SPACE
+	}
+
+	return s
+}
+
+// Renders every action and its keys for each view,
+// used as the contents of the help view.
+func (km *Keymap) Help() string {
+	lines := []string{}
+
+	for _, view := range KEYMAP_VIEWS {
+		lines = append(lines, strings.ToUpper(view[:1])+view[1:], "")
+
+		for _, a := range ACTIONS {
+			keys := km.Describe(view, a.Name)
+			if keys == "" {
+				keys = "unbound"
+			}
+			lines = append(lines, fmt.Sprintf("  %-38s %s", a.Description, keys))
+		}
+
+		lines = append(lines, "")
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func IsAction(name string) bool {
+	for _, a := range ACTIONS {
+		if a.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the action's description.
+func Action(name string) ActionInfo {
+	for _, a := range ACTIONS {
+		if a.Name == name {
+			return a
+		}
+	}
+	return ActionInfo{Name: name}
+}
+
+func isKeymapView(view string) bool {
+	for _, v := range KEYMAP_VIEWS {
+		if v == view {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,277 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/dionvu/spogo/err"
+	"gopkg.in/yaml.v3"
+)
+
+// The version of the config file layout. Files without a version are
+// from before versioning, version 0.
+const CONFIG_VERSION = 2
+
+// Upgrades a config file from the version before it.
+type migration struct {
+	// The version the file is upgraded to.
+	version int
+	migrate func(root *yaml.Node)
+}
+
+// Every migration, in order, the last upgrading to CONFIG_VERSION.
+var migrations = []migration{
+	{1, lowercaseOptions},
+	{2, moveLegacyColors},
+}
+
+// Colors set in the "player" and "general" sections before themes
+// existed, and the elements of "theme_overrides" they're moved to.
+// The view status color was never used, so isn't moved.
+var legacyColors = []struct {
+	from string
+	to   string
+}{
+	{"player.status_bar.now_playing.fg", "status_bar.now_playing.fg"},
+	{"player.status_bar.now_playing.bg", "status_bar.now_playing.bg"},
+	{"player.status_bar.now_playing.bold", "status_bar.now_playing.bold"},
+	{"player.status_bar.paused.fg", "status_bar.paused.fg"},
+	{"player.status_bar.paused.bg", "status_bar.paused.bg"},
+	{"player.status_bar.paused.bold", "status_bar.paused.bold"},
+	{"player.status_bar.no_player.fg", "status_bar.no_player.fg"},
+	{"player.status_bar.no_player.bg", "status_bar.no_player.bg"},
+	{"player.status_bar.no_player.bold", "status_bar.no_player.bold"},
+	{"player.labels.color", "labels.fg"},
+	{"player.progress_bar.completed.color", "progress_bar.completed.bg"},
+	{"player.text.color", "text.fg"},
+	{"general.box.color", "box"},
+	{"general.view_status.color", ""},
+}
+
+// Upgrades the document to the current version in memory, one
+// version at a time. Returns the problem of a file newer than spogo,
+// if any.
+func migrate(root *yaml.Node) []Problem {
+	if root.Kind == 0 {
+		return nil
+	}
+
+	version, node := fileVersion(root)
+	if version > CONFIG_VERSION {
+		return []Problem{problemAt(node, "version",
+			"the file is version %d, newer than this spogo's %d, update spogo", version, CONFIG_VERSION)}
+	}
+	if version == CONFIG_VERSION {
+		return nil
+	}
+
+	for _, m := range migrations {
+		if m.version > version {
+			m.migrate(root)
+		}
+	}
+
+	setVersion(root, CONFIG_VERSION)
+
+	return nil
+}
+
+// Rewrites an older config file in the current version, backing up the
+// original next to it first. Loading the config only upgrades it in
+// memory, so checking or reloading it never writes. Returns the path
+// of the backup, empty if the file wasn't upgraded.
+func (c *Config) Upgrade() (string, error) {
+	b, err := os.ReadFile(c.FilePath())
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		err = errors.FileRead.Wrap(err, fmt.Sprintf("failed to read config file: %v", c.FilePath()))
+		errors.Log(err)
+		return "", err
+	}
+
+	// Files that can't be read, or are newer than spogo,
+	// are left to be reported when they're loaded.
+	root, err := parseYAML(b, c.FilePath())
+	if err != nil || root.Kind == 0 {
+		return "", nil
+	}
+
+	version, _ := fileVersion(root)
+	if version >= CONFIG_VERSION {
+		return "", nil
+	}
+
+	migrate(root)
+
+	backup, err := c.rewrite(root, b, version)
+	if err != nil {
+		errors.Log(err)
+		return "", err
+	}
+
+	return backup, nil
+}
+
+// Backs up the original file as "config.yaml.v<version>.bak",
+// then replaces it with the upgraded document.
+func (c *Config) rewrite(root *yaml.Node, original []byte, version int) (string, error) {
+	backup := fmt.Sprintf("%v.v%d.bak", c.FilePath(), version)
+	if err := os.WriteFile(backup, original, 0600); err != nil {
+		return "", errors.FileWrite.Wrap(err, fmt.Sprintf("backing up config file to: %v", backup))
+	}
+
+	var b strings.Builder
+
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+
+	if err := enc.Encode(root); err != nil {
+		return "", errors.YAML.Wrap(err, fmt.Sprintf("failed to marshal upgraded config file: %v", c.FilePath()))
+	}
+
+	if err := c.write([]byte(b.String())); err != nil {
+		return "", err
+	}
+
+	return backup, nil
+}
+
+// Returns the version of the document, and the node setting it.
+func fileVersion(root *yaml.Node) (int, *yaml.Node) {
+	node := findOption(root, "version")
+	if node == nil {
+		return 0, nil
+	}
+
+	// Invalid versions are reported when the file is checked.
+	version, _ := strconv.Atoi(node.Value)
+
+	return version, node
+}
+
+// Sets the version, as the first option of the document.
+func setVersion(root *yaml.Node, version int) {
+	value := strconv.Itoa(version)
+
+	if node := findOption(root, "version"); node != nil {
+		node.Value = value
+		return
+	}
+
+	if len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return
+	}
+
+	mapping := root.Content[0]
+	mapping.Content = append([]*yaml.Node{
+		{Kind: yaml.ScalarNode, Tag: "!!str", Value: "version"},
+		{Kind: yaml.ScalarNode, Tag: "!!int", Value: value},
+	}, mapping.Content...)
+}
+
+// Renames options written with capitals, such as "General" from
+// versions that documented it so, to the lowercase name they're read
+// as. Options also written in lowercase are left to be reported.
+func lowercaseOptions(root *yaml.Node) {
+	var walk func(node *yaml.Node, t reflect.Type)
+	walk = func(node *yaml.Node, t reflect.Type) {
+		if node.Kind != yaml.MappingNode || t.Kind() != reflect.Struct {
+			return
+		}
+
+		fields := yamlFields(t)
+
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+
+			if _, ok := fields[key.Value]; !ok {
+				lower := strings.ToLower(key.Value)
+				if _, ok := fields[lower]; ok && !hasKey(node, lower) {
+					key.Value = lower
+				}
+			}
+
+			if f, ok := fields[key.Value]; ok {
+				walk(node.Content[i+1], f.Type)
+			}
+		}
+	}
+
+	if len(root.Content) > 0 {
+		walk(root.Content[0], reflect.TypeOf(Config{}))
+	}
+}
+
+// Moves the colors of the "player" and "general" sections, which used
+// to be applied over the theme, to "theme_overrides".
+func moveLegacyColors(root *yaml.Node) {
+	for _, l := range legacyColors {
+		if l.to == "" || strings.HasPrefix(l.from, "player.status_bar.") {
+			continue
+		}
+
+		if node := findOption(root, l.from); isSet(node) {
+			setOption(root, append([]string{"theme_overrides"}, strings.Split(l.to, ".")...), node)
+		}
+	}
+
+	// A status bar style given a color replaced the theme's whole
+	// style, so its unset color is moved as empty and its bold as false.
+	for _, style := range []string{"now_playing", "paused", "no_player"} {
+		from := "player.status_bar." + style
+		fg, bg, bold := findOption(root, from+".fg"), findOption(root, from+".bg"), findOption(root, from+".bold")
+
+		if !isSet(fg) && !isSet(bg) {
+			continue
+		}
+
+		setOption(root, []string{"theme_overrides", "status_bar", style, "fg"}, valueOr(fg, "!!str", ""))
+		setOption(root, []string{"theme_overrides", "status_bar", style, "bg"}, valueOr(bg, "!!str", ""))
+		setOption(root, []string{"theme_overrides", "status_bar", style, "bold"}, valueOr(bold, "!!bool", "false"))
+	}
+
+	removeOption(root, "player")
+	removeOption(root, "general")
+}
+
+func isSet(node *yaml.Node) bool {
+	return node != nil && node.Value != ""
+}
+
+// Returns node, or a new scalar of the value if it isn't set.
+func valueOr(node *yaml.Node, tag string, value string) *yaml.Node {
+	if isSet(node) {
+		return node
+	}
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value}
+}
+
+// Removes the top level option from the document.
+func removeOption(root *yaml.Node, name string) {
+	if len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return
+	}
+
+	mapping := root.Content[0]
+
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == name {
+			mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+			return
+		}
+	}
+}
+
+func hasKey(mapping *yaml.Node, name string) bool {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == name {
+			return true
+		}
+	}
+	return false
+}
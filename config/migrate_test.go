@@ -0,0 +1,241 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dionvu/spogo/err"
+)
+
+func TestMigrate(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+
+		// The file once upgraded, the same as the input if it isn't.
+		output   string
+		version  int
+		problems int
+	}{
+		{
+			name: "capitalized options",
+			input: `version: 0
+Ascii:
+  Threshold: 30
+General:
+  Box:
+    color: HiRed
+`,
+			output: `version: 2
+ascii:
+  threshold: 30
+theme_overrides:
+  box: HiRed
+`,
+			version: 2,
+		},
+		{
+			name: "unversioned",
+			input: `# Album art.
+ascii:
+  enabled: false
+`,
+			output: `version: 2
+# Album art.
+ascii:
+  enabled: false
+`,
+			version: 2,
+		},
+		{
+			name: "already lowercase",
+			input: `Ascii:
+  threshold: 30
+ascii:
+  threshold: 40
+`,
+			output: `version: 2
+Ascii:
+  threshold: 30
+ascii:
+  threshold: 40
+`,
+			version: 2,
+		},
+		{
+			name: "legacy colors",
+			input: `version: 1
+theme: nord
+player:
+  status_bar:
+    paused:
+      bg: "#79740e"
+  labels:
+    color: "#b8bb26"
+  progress_bar:
+    completed:
+      color: "#98971a"
+general:
+  view_status:
+    color: "#ffffff"
+`,
+			output: `version: 2
+theme: nord
+theme_overrides:
+  labels:
+    fg: "#b8bb26"
+  progress_bar:
+    completed:
+      bg: "#98971a"
+  status_bar:
+    paused:
+      fg: ""
+      bg: "#79740e"
+      bold: false
+`,
+			version: 2,
+		},
+		{
+			name: "current",
+			input: fmt.Sprintf(`version: %d
+ascii:
+  threshold: 30
+`, CONFIG_VERSION),
+			version: CONFIG_VERSION,
+		},
+		{
+			name: "newer than spogo",
+			input: fmt.Sprintf(`version: %d
+ascii:
+  threshold: 30
+`, CONFIG_VERSION+1),
+			version:  CONFIG_VERSION + 1,
+			problems: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{path: t.TempDir()}
+
+			if err := os.WriteFile(c.FilePath(), []byte(tt.input), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			root, err := parseYAML([]byte(tt.input), c.FilePath())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			original, _ := fileVersion(root)
+
+			if problems := migrate(root); len(problems) != tt.problems {
+				t.Errorf("got problems %v, want %d", problems, tt.problems)
+			}
+
+			if version, _ := fileVersion(root); version != tt.version {
+				t.Errorf("got version %d, want %d", version, tt.version)
+			}
+
+			if _, err := c.Upgrade(); err != nil {
+				t.Fatal(err)
+			}
+
+			output := tt.output
+			if output == "" {
+				output = tt.input
+			}
+
+			b, err := os.ReadFile(c.FilePath())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != output {
+				t.Errorf("got file:\n%s\nwant:\n%s", b, output)
+			}
+
+			backup := fmt.Sprintf("%v.v%d.bak", c.FilePath(), original)
+			b, err = os.ReadFile(backup)
+
+			switch {
+			case tt.output == "" && err == nil:
+				t.Errorf("backed up %v, which wasn't upgraded", backup)
+			case tt.output != "" && err != nil:
+				t.Errorf("no backup: %v", err)
+			case tt.output != "" && string(b) != tt.input:
+				t.Errorf("got backup:\n%s\nwant the original:\n%s", b, tt.input)
+			}
+		})
+	}
+}
+
+// The backup must hold the file as it was before it was rewritten,
+// even if the document was changed before it was upgraded.
+func TestMigrateBacksUpOriginal(t *testing.T) {
+	c := &Config{path: t.TempDir()}
+
+	original := "# Written by hand.\nGeneral:\n  Box:\n    color:   HiRed\n"
+	if err := os.WriteFile(c.FilePath(), []byte(original), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	backup, err := c.Upgrade()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(filepath.Dir(c.FilePath()), CONFIGFILE+".v0.bak"); backup != want {
+		t.Errorf("got backup %v, want %v", backup, want)
+	}
+
+	b, err := os.ReadFile(backup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != original {
+		t.Errorf("got backup %q, want %q", b, original)
+	}
+
+	b, err = os.ReadFile(c.FilePath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), "General") || !strings.Contains(string(b), "box: HiRed") {
+		t.Errorf("the file wasn't upgraded:\n%s", b)
+	}
+}
+
+// Loading the config, as checking and reloading it do,
+// only upgrades it in memory.
+func TestLoadDoesNotUpgradeFile(t *testing.T) {
+	dir := t.TempDir()
+	errors.Init(filepath.Join(dir, "cache"))
+
+	c := &Config{path: dir}
+
+	original := "spotify:\n  client_id: a\n  client_secret: b\nGeneral:\n  Box:\n    color: HiRed\n"
+	if err := os.WriteFile(c.FilePath(), []byte(original), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if c.Theme().Box != "HiRed" {
+		t.Errorf("got box %q, want the upgraded file's HiRed", c.Theme().Box)
+	}
+
+	b, err := os.ReadFile(c.FilePath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != original {
+		t.Errorf("the file was rewritten:\n%s", b)
+	}
+
+	if backups, _ := filepath.Glob(c.FilePath() + ".*.bak"); len(backups) > 0 {
+		t.Errorf("backed up the file as %v", backups)
+	}
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"io"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	DEFAULT_NOTIFICATION_SUMMARY = "{{.Track}}"
+	DEFAULT_NOTIFICATION_BODY    = "{{.Artists}}\n{{.Album}}"
+)
+
+// Desktop notifications of the playing track, replacing the last
+// notification rather than stacking.
+type Notifications struct {
+	Enabled bool `yaml:"enabled"`
+
+	// Notifies only while the terminal running spogo isn't focused,
+	// always from "spogo daemon".
+	WhenUnfocused bool `yaml:"when_unfocused"`
+
+	// Go templates of the notification's title and text,
+	// given the NotificationFields.
+	Summary string `yaml:"summary"`
+	Body    string `yaml:"body"`
+}
+
+// What the notification templates can show, as {{.Track}} and so on.
+type NotificationFields struct {
+	Track   string
+	Artists string
+	Album   string
+}
+
+// Parses a notification template, reporting unknown fields too.
+func NotificationTemplate(text string) (*template.Template, error) {
+	t, err := template.New("notification").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := t.Execute(io.Discard, NotificationFields{}); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// Reports templates that can't be parsed or show unknown fields.
+func (n Notifications) checkTemplates(root *yaml.Node) []Problem {
+	problems := []Problem{}
+
+	for option, text := range map[string]string{
+		"notifications.summary": n.Summary,
+		"notifications.body":    n.Body,
+	} {
+		node := findOption(root, option)
+		if node == nil {
+			continue
+		}
+
+		if _, err := NotificationTemplate(text); err != nil {
+			problems = append(problems, problemAt(node, option, "invalid template: %v", err))
+		}
+	}
+
+	return problems
+}
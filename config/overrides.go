@@ -0,0 +1,267 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/dionvu/spogo/err"
+	"gopkg.in/yaml.v3"
+)
+
+// Every option can be set by an environment variable named after it,
+// such as SPOGO_ASCII_THRESHOLD for "ascii.threshold". These two
+// instead set where the config is read from.
+const (
+	ENV_PREFIX    = "SPOGO_"
+	ENV_CONFIG    = "SPOGO_CONFIG"
+	ENV_CACHE_DIR = "SPOGO_CACHE_DIR"
+
+	// Describes the event to hooks, so spogo run by a
+	// hook doesn't read these as options.
+	ENV_HOOK_PREFIX = "SPOGO_HOOK_"
+)
+
+// Where the config is read from and options set outside "config.yaml",
+// given by flags. Options are taken from, in increasing precedence:
+// the defaults, "config.yaml", SPOGO_* environment variables, then
+// "--set" flags, in the order they are given.
+type Overrides struct {
+	// Used instead of the user's config file and cache directory, the
+	// flags taking precedence over SPOGO_CONFIG and SPOGO_CACHE_DIR.
+	ConfigFile string
+	CacheDir   string
+
+	// Options written as "option=value", such as "ascii.threshold=30".
+	Set []string
+}
+
+// Returns the config file given by the flag or environment, empty if
+// neither gives one.
+func (o Overrides) configFile() string {
+	if o.ConfigFile != "" {
+		return o.ConfigFile
+	}
+	return os.Getenv(ENV_CONFIG)
+}
+
+// Returns the spogo cache directory given by the flag or environment,
+// or ".cache/spogo" (for unix). Errors aren't logged, as the log is
+// kept in this directory.
+func (o Overrides) CachePath() (string, error) {
+	if o.CacheDir != "" {
+		return o.CacheDir, nil
+	}
+
+	if dir := os.Getenv(ENV_CACHE_DIR); dir != "" {
+		return dir, nil
+	}
+
+	cd, err := os.UserCacheDir()
+	if err != nil {
+		return "", errors.FileOpen.Wrap(err, "failed to get user's cache directory")
+	}
+
+	return filepath.Join(cd, APPNAME), nil
+}
+
+// An option set outside "config.yaml", and what set it.
+type override struct {
+	option string
+	value  string
+	source string
+}
+
+// Returns the options set by the environment followed by those set
+// by flags, so that later options take precedence.
+func (o Overrides) options() ([]override, []Problem) {
+	overrides := []override{}
+	problems := []Problem{}
+
+	vars := envOptions()
+
+	env := os.Environ()
+	sort.Strings(env)
+
+	for _, kv := range env {
+		name, value, _ := strings.Cut(kv, "=")
+		if !strings.HasPrefix(name, ENV_PREFIX) || strings.HasPrefix(name, ENV_HOOK_PREFIX) ||
+			name == ENV_CONFIG || name == ENV_CACHE_DIR {
+			continue
+		}
+
+		option, ok := vars[name]
+		if !ok {
+			problems = append(problems, Problem{Option: name, Message: "unknown environment variable" + suggestName(name, keys(vars))})
+			continue
+		}
+
+		overrides = append(overrides, override{option: option, value: value, source: name})
+	}
+
+	for _, set := range o.Set {
+		option, value, ok := strings.Cut(set, "=")
+		if !ok {
+			problems = append(problems, Problem{Option: set, Message: "expected option=value", Source: "--set"})
+			continue
+		}
+
+		if optionType(option) == nil {
+			problems = append(problems, Problem{Option: option, Message: "unknown option" + suggestName(option, values(vars)), Source: "--set"})
+			continue
+		}
+
+		overrides = append(overrides, override{option: option, value: value, source: "--set"})
+	}
+
+	return overrides, problems
+}
+
+// Returns true if any option is set outside "config.yaml".
+func (c *Config) Overridden() bool {
+	overrides, _ := c.overrides.options()
+	return len(overrides) > 0
+}
+
+// Sets the options in the yaml document, replacing those set in the
+// file. Values are read as yaml, except for text options, so that
+// values such as colors starting with "#" aren't read as comments.
+func applyOverrides(root *yaml.Node, overrides []override) []Problem {
+	problems := []Problem{}
+
+	for _, o := range overrides {
+		value := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: o.value}
+
+		if optionType(o.option).Kind() != reflect.String {
+			doc := yaml.Node{}
+			if err := yaml.Unmarshal([]byte(o.value), &doc); err != nil || len(doc.Content) == 0 {
+				problems = append(problems, Problem{Option: o.option, Message: fmt.Sprintf("invalid value %q", o.value), Source: o.source})
+				continue
+			}
+			value = doc.Content[0]
+		}
+
+		setSource(value, o.source)
+		setOption(root, strings.Split(o.option, "."), value)
+	}
+
+	return problems
+}
+
+// Nodes set by overrides aren't at any position in the file, so their
+// line comment names what set them instead, for problems to report.
+func setSource(node *yaml.Node, source string) {
+	node.Line, node.Column = 0, 0
+	node.LineComment = source
+
+	for _, n := range node.Content {
+		setSource(n, source)
+	}
+}
+
+// Sets the option to the value node, creating the sections it is in.
+func setOption(root *yaml.Node, path []string, value *yaml.Node) {
+	if root.Kind != yaml.DocumentNode {
+		*root = yaml.Node{Kind: yaml.DocumentNode}
+	}
+	if len(root.Content) == 0 {
+		root.Content = append(root.Content, &yaml.Node{})
+	}
+
+	node := root.Content[0]
+
+	for i, name := range path {
+		// Sections left empty or set to a single value are replaced.
+		if node.Kind != yaml.MappingNode {
+			*node = yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		}
+
+		var next *yaml.Node
+		for j := 0; j+1 < len(node.Content); j += 2 {
+			if node.Content[j].Value == name {
+				next = node.Content[j+1]
+			}
+		}
+
+		if i == len(path)-1 {
+			if next != nil {
+				*next = *value
+			} else {
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: name}, value)
+			}
+			return
+		}
+
+		if next == nil {
+			next = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: name}, next)
+		}
+
+		node = next
+	}
+}
+
+// Returns the type the dotted option is decoded into, or nil if there
+// is no such option. Sections, maps included, are options too.
+func optionType(option string) reflect.Type {
+	t := reflect.TypeOf(Config{})
+
+	for _, name := range strings.Split(option, ".") {
+		switch t.Kind() {
+		case reflect.Struct:
+			f, ok := yamlFields(t)[name]
+			if !ok {
+				return nil
+			}
+			t = f.Type
+		case reflect.Map:
+			t = t.Elem()
+		default:
+			return nil
+		}
+	}
+
+	return t
+}
+
+// The option each environment variable sets, such as "ascii.threshold"
+// for SPOGO_ASCII_THRESHOLD. Maps, such as "keys.bindings", are set as
+// a whole, written as yaml.
+func envOptions() map[string]string {
+	vars := map[string]string{}
+
+	var walk func(t reflect.Type, option string)
+	walk = func(t reflect.Type, option string) {
+		if t.Kind() != reflect.Struct {
+			vars[ENV_PREFIX+strings.ToUpper(strings.ReplaceAll(option, ".", "_"))] = option
+			return
+		}
+
+		for name, f := range yamlFields(t) {
+			walk(f.Type, join(option, name))
+		}
+	}
+
+	walk(reflect.TypeOf(Config{}), "")
+
+	return vars
+}
+
+func keys(m map[string]string) []string {
+	k := make([]string, 0, len(m))
+	for key := range m {
+		k = append(k, key)
+	}
+	return k
+}
+
+func values(m map[string]string) []string {
+	v := make([]string, 0, len(m))
+	for _, value := range m {
+		v = append(v, value)
+	}
+	return v
+}
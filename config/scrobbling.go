@@ -0,0 +1,35 @@
+package config
+
+const (
+	DEFAULT_LISTENBRAINZ_URL = "https://api.listenbrainz.org"
+	DEFAULT_LASTFM_URL       = "https://ws.audioscrobbler.com/2.0/"
+	DEFAULT_LASTFM_AUTH_URL  = "https://www.last.fm/api/auth/"
+)
+
+// Submits the tracks played to scrobbling services, from the tui, or
+// from "spogo daemon" while it runs. Listens that can't be submitted
+// are queued in the cache directory and retried.
+type Scrobbling struct {
+	ListenBrainz ListenBrainz `yaml:"listenbrainz"`
+	LastFM       LastFM       `yaml:"lastfm"`
+}
+
+// A service taking listens with a user token, as ListenBrainz does.
+type ListenBrainz struct {
+	Enabled bool   `yaml:"enabled"`
+	Url     string `yaml:"url"`
+	Token   string `yaml:"token"`
+}
+
+// A service taking signed requests of an api account, as Last.fm does.
+type LastFM struct {
+	Enabled bool   `yaml:"enabled"`
+	Url     string `yaml:"url"`
+	ApiKey  string `yaml:"api_key"`
+	Secret  string `yaml:"secret"`
+
+	// Given by "spogo scrobble auth lastfm", once the user allows
+	// the api account to scrobble for them at the auth url.
+	SessionKey string `yaml:"session_key"`
+	AuthUrl    string `yaml:"auth_url"`
+}
@@ -0,0 +1,311 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dionvu/spogo/err"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	THEME_GRUVBOX    = "gruvbox"
+	THEME_NORD       = "nord"
+	THEME_CATPPUCCIN = "catppuccin"
+	THEME_MONOCHROME = "monochrome"
+
+	DEFAULT_THEME = THEME_GRUVBOX
+
+	// Album color modes, using either the most common color
+	// of the album art or its most saturated one.
+	ALBUM_COLORS_DOMINANT = "dominant"
+	ALBUM_COLORS_VIBRANT  = "vibrant"
+
+	// User themes are read from "<name>.yaml" in this
+	// folder of the config directory.
+	THEMES_FOLDER        = "themes"
+	THEME_FILE_EXTENSION = ".yaml"
+)
+
+// The look of a single element. Colors are hex codes such as "#98971a"
+// or ansi color numbers, and are left to the terminal if empty.
+type ThemeStyle struct {
+	Foreground string `yaml:"fg"`
+	Background string `yaml:"bg"`
+	Bold       bool   `yaml:"bold"`
+	Faint      bool   `yaml:"faint"`
+	Underline  bool   `yaml:"underline"`
+	Reverse    bool   `yaml:"reverse"`
+}
+
+type ThemeStatusBar struct {
+	NowPlaying ThemeStyle `yaml:"now_playing"`
+	Paused     ThemeStyle `yaml:"paused"`
+	NoPlayer   ThemeStyle `yaml:"no_player"`
+}
+
+type ThemeProgressBar struct {
+	// Completed is drawn as spaces, so it needs a
+	// background color or reverse to be visible.
+	Completed ThemeStyle `yaml:"completed"`
+	Remaining ThemeStyle `yaml:"remaining"`
+}
+
+// The styles of every element spogo draws.
+type Theme struct {
+	Name string `yaml:"name"`
+
+	// A theme file may start from another theme, either a preset or
+	// another file, and only set the elements it changes.
+	Inherits string `yaml:"inherits"`
+
+	StatusBar   ThemeStatusBar   `yaml:"status_bar"`
+	ProgressBar ThemeProgressBar `yaml:"progress_bar"`
+
+	// Labels such as "Artist:" and the text following them.
+	Labels ThemeStyle `yaml:"labels"`
+	Text   ThemeStyle `yaml:"text"`
+
+	// Headings, such as the command palette's.
+	Title ThemeStyle `yaml:"title"`
+
+	// The selected list item and current view, and everything
+	// unselected around them such as other items and hints.
+	Selected ThemeStyle `yaml:"selected"`
+	Muted    ThemeStyle `yaml:"muted"`
+
+	Error ThemeStyle `yaml:"error"`
+
+	// Color of the box around the player when no device is
+	// selected, either a hex code or a name such as "HiGreen".
+	Box string `yaml:"box"`
+}
+
+// The built in themes.
+var themes = map[string]Theme{
+	THEME_GRUVBOX: {
+		Name: THEME_GRUVBOX,
+		StatusBar: ThemeStatusBar{
+			// Bubbletea renders background colors brighter than the actual color,
+			// hence why a color like "#b8bb26" needs to be choosen to match "#98971a".
+			NowPlaying: ThemeStyle{Foreground: "#282828", Background: "#98971a", Bold: true},
+			Paused:     ThemeStyle{Foreground: "#282828", Background: "#79740e", Bold: true},
+			NoPlayer:   ThemeStyle{Foreground: "#282828", Background: "#cc241d", Bold: true},
+		},
+		ProgressBar: ThemeProgressBar{
+			Completed: ThemeStyle{Background: "#98971a"},
+			Remaining: ThemeStyle{Foreground: "0"},
+		},
+		Labels: ThemeStyle{Foreground: "#b8bb26"},
+		Text:   ThemeStyle{Foreground: "#fbf1c7"},
+		Title:  ThemeStyle{Foreground: "#b8bb26"},
+		Muted:  ThemeStyle{Faint: true},
+		Error:  ThemeStyle{Foreground: "#fb4934"},
+		Box:    "#98971a",
+	},
+
+	THEME_NORD: {
+		Name: THEME_NORD,
+		StatusBar: ThemeStatusBar{
+			NowPlaying: ThemeStyle{Foreground: "#2e3440", Background: "#88c0d0", Bold: true},
+			Paused:     ThemeStyle{Foreground: "#2e3440", Background: "#81a1c1", Bold: true},
+			NoPlayer:   ThemeStyle{Foreground: "#2e3440", Background: "#bf616a", Bold: true},
+		},
+		ProgressBar: ThemeProgressBar{
+			Completed: ThemeStyle{Background: "#88c0d0"},
+			Remaining: ThemeStyle{Foreground: "#4c566a"},
+		},
+		Labels:   ThemeStyle{Foreground: "#88c0d0"},
+		Text:     ThemeStyle{Foreground: "#eceff4"},
+		Title:    ThemeStyle{Foreground: "#81a1c1", Bold: true},
+		Selected: ThemeStyle{Foreground: "#eceff4"},
+		Muted:    ThemeStyle{Foreground: "#616e88"},
+		Error:    ThemeStyle{Foreground: "#bf616a"},
+		Box:      "#88c0d0",
+	},
+
+	THEME_CATPPUCCIN: {
+		Name: THEME_CATPPUCCIN,
+		StatusBar: ThemeStatusBar{
+			NowPlaying: ThemeStyle{Foreground: "#1e1e2e", Background: "#cba6f7", Bold: true},
+			Paused:     ThemeStyle{Foreground: "#1e1e2e", Background: "#f9e2af", Bold: true},
+			NoPlayer:   ThemeStyle{Foreground: "#1e1e2e", Background: "#f38ba8", Bold: true},
+		},
+		ProgressBar: ThemeProgressBar{
+			Completed: ThemeStyle{Background: "#cba6f7"},
+			Remaining: ThemeStyle{Foreground: "#45475a"},
+		},
+		Labels:   ThemeStyle{Foreground: "#cba6f7"},
+		Text:     ThemeStyle{Foreground: "#cdd6f4"},
+		Title:    ThemeStyle{Foreground: "#89b4fa", Bold: true},
+		Selected: ThemeStyle{Foreground: "#cdd6f4"},
+		Muted:    ThemeStyle{Foreground: "#7f849c"},
+		Error:    ThemeStyle{Foreground: "#f38ba8"},
+		Box:      "#cba6f7",
+	},
+
+	// Uses no colors, only bold, underline and reverse video, so it
+	// keeps the terminal's own, ideally high contrast, colors.
+	THEME_MONOCHROME: {
+		Name: THEME_MONOCHROME,
+		StatusBar: ThemeStatusBar{
+			NowPlaying: ThemeStyle{Bold: true, Reverse: true},
+			Paused:     ThemeStyle{Reverse: true},
+			NoPlayer:   ThemeStyle{Bold: true, Underline: true},
+		},
+		ProgressBar: ThemeProgressBar{
+			Completed: ThemeStyle{Reverse: true},
+		},
+		Labels:   ThemeStyle{Bold: true},
+		Title:    ThemeStyle{Bold: true, Underline: true},
+		Selected: ThemeStyle{Bold: true},
+		Error:    ThemeStyle{Bold: true, Reverse: true},
+	},
+}
+
+// The theme used when none is configured.
+func DefaultTheme() Theme {
+	return themes[DEFAULT_THEME]
+}
+
+// Returns the folder user themes are read from,
+// ".config/spogo/themes" for unix.
+func (c *Config) ThemesPath() string {
+	return filepath.Join(c.Path(), THEMES_FOLDER)
+}
+
+// The names of the built in themes followed by the user's themes.
+func (c *Config) Themes() []string {
+	names := []string{THEME_GRUVBOX, THEME_NORD, THEME_CATPPUCCIN, THEME_MONOCHROME}
+
+	files, _ := filepath.Glob(filepath.Join(c.ThemesPath(), "*"+THEME_FILE_EXTENSION))
+	sort.Strings(files)
+
+	for _, f := range files {
+		name := strings.TrimSuffix(filepath.Base(f), THEME_FILE_EXTENSION)
+		if _, ok := themes[name]; !ok {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// Returns the built in theme with the given name, or reads it from
+// the themes folder, resolving the themes it inherits from.
+func (c *Config) LoadTheme(name string) (Theme, error) {
+	return c.loadTheme(name, map[string]bool{})
+}
+
+func (c *Config) loadTheme(name string, seen map[string]bool) (Theme, error) {
+	if name == "" {
+		return DefaultTheme(), nil
+	}
+
+	if t, ok := themes[name]; ok {
+		return t, nil
+	}
+
+	if seen[name] {
+		err := errors.Theme.New("theme %q inherits from itself", name)
+		errors.Log(err)
+		return Theme{}, err
+	}
+	seen[name] = true
+
+	path := filepath.Join(c.ThemesPath(), name+THEME_FILE_EXTENSION)
+
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		err = errors.Theme.New("unknown theme %q, expected one of: %s", name, strings.Join(c.Themes(), ", "))
+		errors.Log(err)
+		return Theme{}, err
+	}
+	if err != nil {
+		err = errors.FileRead.Wrap(err, fmt.Sprintf("failed to read theme file: %v", path))
+		errors.Log(err)
+		return Theme{}, err
+	}
+
+	// Only reads the parent's name first, so the file
+	// can then be decoded on top of the parent.
+	parent := struct {
+		Inherits string `yaml:"inherits"`
+	}{}
+
+	if err = yaml.Unmarshal(b, &parent); err != nil {
+		err = errors.YAML.Wrap(err, fmt.Sprintf("failed to unmarshal theme file: %v", path))
+		errors.Log(err)
+		return Theme{}, err
+	}
+
+	t := Theme{}
+	if parent.Inherits != "" {
+		if t, err = c.loadTheme(parent.Inherits, seen); err != nil {
+			return Theme{}, err
+		}
+	}
+
+	root, err := parseYAML(b, path)
+	if err != nil {
+		errors.Log(err)
+		return Theme{}, err
+	}
+
+	problems, err := decodeChecked(root, path, &t)
+	if err != nil {
+		errors.Log(err)
+		return Theme{}, err
+	}
+
+	if problems = append(problems, t.checkValues(root)...); len(problems) > 0 {
+		err = problemsError(path, problems)
+		errors.Log(err)
+		return Theme{}, err
+	}
+
+	t.Name = name
+
+	return t, nil
+}
+
+// Returns the theme named by the "theme" option, or the
+// default theme if the config isn't loaded.
+func (c *Config) Theme() Theme {
+	if c.theme == nil {
+		return DefaultTheme()
+	}
+	return *c.theme
+}
+
+// Replaces the theme until the config is loaded again,
+// keeping the elements set under "theme_overrides".
+func (c *Config) SetTheme(name string) error {
+	t, err := c.LoadTheme(name)
+	if err != nil {
+		return err
+	}
+
+	c.applyThemeOverrides(&t)
+	c.theme = &t
+
+	return nil
+}
+
+// Sets the elements of the theme given under "theme_overrides",
+// leaving the others as the theme sets them.
+func (c *Config) applyThemeOverrides(t *Theme) {
+	if c.themeOverrides == nil {
+		return
+	}
+
+	name := t.Name
+
+	// Problems with the overrides are reported when the config is checked.
+	_ = c.themeOverrides.Decode(t)
+
+	t.Name, t.Inherits = name, ""
+}
@@ -0,0 +1,493 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/dionvu/spogo/err"
+	"github.com/joomcode/errorx"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	MIN_THRESHOLD = 0
+	MAX_THRESHOLD = 255
+
+	// Unknown options are suggested the known option
+	// at most this many edits away, if any.
+	MAX_SUGGESTION_DISTANCE = 2
+)
+
+var AUTH_FLOWS = []string{AUTH_FLOW_CODE, AUTH_FLOW_PKCE}
+
+var RENDERERS = []string{RENDERER_AUTO, RENDERER_KITTY, RENDERER_ITERM, RENDERER_SIXEL, RENDERER_HALFBLOCK, RENDERER_ASCII}
+
+var ALBUM_COLORS_MODES = []string{ALBUM_COLORS_VIBRANT, ALBUM_COLORS_DOMINANT}
+
+// Color names the player's box can be drawn in besides hex codes.
+var BOX_COLORS = []string{
+	"Black", "Blue", "Red", "Green", "Yellow", "Cyan", "Magenta", "White",
+	"HiBlack", "HiBlue", "HiRed", "HiGreen", "HiYellow", "HiCyan", "HiMagenta", "HiWhite",
+}
+
+// Something wrong with an option of a yaml file, at the line and
+// column it is set, which are zero if the file doesn't set it. Options
+// set outside the file have the flag or variable setting them instead.
+type Problem struct {
+	Line    int
+	Column  int
+	Source  string
+	Option  string
+	Message string
+}
+
+func (p Problem) String() string {
+	if p.Line == 0 && p.Source != "" {
+		return fmt.Sprintf("%s (set by %s): %s", p.Option, p.Source, p.Message)
+	}
+	if p.Line == 0 {
+		return fmt.Sprintf("%s: %s", p.Option, p.Message)
+	}
+	return fmt.Sprintf("line %d, column %d: %s: %s", p.Line, p.Column, p.Option, p.Message)
+}
+
+// A problem with the option set at node, which may be nil.
+func problemAt(node *yaml.Node, option string, format string, args ...any) Problem {
+	p := Problem{Option: option, Message: fmt.Sprintf(format, args...)}
+	if node != nil {
+		p.Line, p.Column = node.Line, node.Column
+	}
+	if node != nil && node.Line == 0 {
+		p.Source = node.LineComment
+	}
+	return p
+}
+
+// Lists every problem of the file in one error, the first line
+// naming the file and each following line a problem, in the order
+// they appear in the file.
+func problemsError(path string, problems []Problem) error {
+	slices.SortStableFunc(problems, func(a, b Problem) int {
+		if a.Line != b.Line {
+			return a.Line - b.Line
+		}
+		return a.Column - b.Column
+	})
+
+	lines := []string{fmt.Sprintf("%d problem(s) in %v", len(problems), path)}
+	for _, p := range problems {
+		// Problems may list problems of their own, such as those of a theme file.
+		lines = append(lines, "  "+strings.ReplaceAll(p.String(), "\n", "\n  "))
+	}
+
+	return errors.Config.New("%s", strings.Join(lines, "\n"))
+}
+
+// Reads the yaml file into a node, which keeps the lines and
+// columns of its options.
+func parseYAML(b []byte, path string) (*yaml.Node, error) {
+	root := &yaml.Node{}
+	if err := yaml.Unmarshal(b, root); err != nil {
+		// The yaml error gives the line the syntax error is at.
+		return nil, errors.YAML.Wrap(err, "failed to unmarshal %v: %v", path, strings.TrimPrefix(err.Error(), "yaml: "))
+	}
+
+	return root, nil
+}
+
+// Decodes the yaml document into out, returning the problems with
+// the names and types of its options. Options with problems are left
+// unchanged, so the values of the others can still be checked.
+func decodeChecked(root *yaml.Node, path string, out any) ([]Problem, error) {
+	problems := checkOptions(root, reflect.TypeOf(out).Elem(), "")
+
+	// Type errors are already among the problems.
+	if err := root.Decode(out); err != nil {
+		if _, ok := err.(*yaml.TypeError); !ok {
+			return nil, errors.YAML.Wrap(err, fmt.Sprintf("failed to unmarshal %v", path))
+		}
+	}
+
+	return problems, nil
+}
+
+// Checks every option under node is known and has a value of the
+// type it is decoded into, t, reporting all problems instead of
+// stopping at the first.
+func checkOptions(node *yaml.Node, t reflect.Type, option string) []Problem {
+	switch node.Kind {
+	case 0:
+		// An empty file.
+		return nil
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil
+		}
+		return checkOptions(node.Content[0], t, option)
+	case yaml.AliasNode:
+		return checkOptions(node.Alias, t, option)
+	}
+
+	// Options left empty, such as a section with every line
+	// commented out, keep their default.
+	if node.Tag == "!!null" {
+		return nil
+	}
+
+	problems := []Problem{}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			return []Problem{problemAt(node, option, "expected a section of options")}
+		}
+
+		fields := yamlFields(t)
+
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			name := join(option, key.Value)
+
+			field, ok := fields[key.Value]
+			if !ok {
+				problems = append(problems, problemAt(key, name, "unknown option%s", suggest(key.Value, fields)))
+				continue
+			}
+
+			problems = append(problems, checkOptions(value, field.Type, name)...)
+		}
+
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			return []Problem{problemAt(node, option, "expected a section of options")}
+		}
+
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			problems = append(problems, checkOptions(node.Content[i+1], t.Elem(), join(option, node.Content[i].Value))...)
+		}
+
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			return []Problem{problemAt(node, option, "expected a list, such as [\"a\", \"b\"]")}
+		}
+
+		for i, item := range node.Content {
+			problems = append(problems, checkOptions(item, t.Elem(), fmt.Sprintf("%s[%d]", option, i))...)
+		}
+
+	default:
+		if node.Kind != yaml.ScalarNode {
+			return []Problem{problemAt(node, option, "expected %s", describeType(t))}
+		}
+
+		if err := node.Decode(reflect.New(t).Interface()); err != nil {
+			problems = append(problems, problemAt(node, option, "expected %s, got %q", describeType(t), node.Value))
+		}
+	}
+
+	return problems
+}
+
+// The fields of a struct by the option names they're decoded from.
+func yamlFields(t reflect.Type) map[string]reflect.StructField {
+	fields := map[string]reflect.StructField{}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+
+		fields[name] = f
+	}
+
+	return fields
+}
+
+func describeType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "true or false"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "a whole number"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	default:
+		return "text"
+	}
+}
+
+// Suggests the field closest to an unknown option.
+func suggest(name string, fields map[string]reflect.StructField) string {
+	names := []string{}
+	for known := range fields {
+		names = append(names, known)
+	}
+	return suggestName(strings.ToLower(name), names)
+}
+
+// Suggests the known name closest to an unknown one, if any is
+// close enough to likely be a typo.
+func suggestName(name string, known []string) string {
+	best, bestDistance := "", MAX_SUGGESTION_DISTANCE+1
+
+	for _, known := range known {
+		d := distance(name, known)
+		if d < bestDistance || d == bestDistance && known < best {
+			best, bestDistance = known, d
+		}
+	}
+
+	if best == "" {
+		return ""
+	}
+
+	return fmt.Sprintf(", did you mean %q?", best)
+}
+
+// The levenshtein distance between two strings.
+func distance(a string, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+
+		prev = cur
+	}
+
+	return prev[len(b)]
+}
+
+func join(option string, name string) string {
+	if option == "" {
+		return name
+	}
+	return option + "." + name
+}
+
+// Returns the value node of the dotted option, or nil if it isn't set.
+func findOption(root *yaml.Node, option string) *yaml.Node {
+	node := root
+	if node.Kind == yaml.DocumentNode {
+		if len(node.Content) == 0 {
+			return nil
+		}
+		node = node.Content[0]
+	}
+
+	for _, name := range strings.Split(option, ".") {
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+
+		var next *yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == name {
+				next = node.Content[i+1]
+			}
+		}
+
+		if next == nil {
+			return nil
+		}
+		node = next
+	}
+
+	return node
+}
+
+// Checks the values of the options which can be decoded but still
+// be wrong, such as numbers out of range and unknown colors. Only
+// options set in the file are checked, as the defaults are valid.
+func (c *Config) checkValues(root *yaml.Node) []Problem {
+	problems := []Problem{}
+
+	check := func(option string, ok bool, format string, args ...any) {
+		if node := findOption(root, option); node != nil && !ok {
+			problems = append(problems, problemAt(node, option, format, args...))
+		}
+	}
+
+	check("spotify.auth_flow", slices.Contains(AUTH_FLOWS, c.Spotify.AuthFlow),
+		"expected one of %s, got %q", strings.Join(AUTH_FLOWS, ", "), c.Spotify.AuthFlow)
+
+	check("ascii.threshold", c.Ascii.Threshold >= MIN_THRESHOLD && c.Ascii.Threshold <= MAX_THRESHOLD,
+		"must be from %d to %d, got %d", MIN_THRESHOLD, MAX_THRESHOLD, c.Ascii.Threshold)
+
+	check("ascii.renderer", slices.Contains(RENDERERS, c.Ascii.Renderer),
+		"expected one of %s, got %q", strings.Join(RENDERERS, ", "), c.Ascii.Renderer)
+
+	check("album_colors.mode", slices.Contains(ALBUM_COLORS_MODES, c.AlbumColors.Mode),
+		"expected one of %s, got %q", strings.Join(ALBUM_COLORS_MODES, ", "), c.AlbumColors.Mode)
+
+	check("album_colors.background", c.AlbumColors.Background == "" || IsHexColor(c.AlbumColors.Background),
+		"expected a hex color such as \"#282828\", got %q", c.AlbumColors.Background)
+
+	check("search.debounce_ms", c.Search.DebounceMs >= 0,
+		"can't be negative, got %d", c.Search.DebounceMs)
+
+	check("image_cache.max_size_mb", c.ImageCache.MaxSizeMb >= 0,
+		"can't be negative, got %d", c.ImageCache.MaxSizeMb)
+
+	check("hooks.timeout_ms", c.Hooks.TimeoutMs > 0,
+		"must be positive, got %d", c.Hooks.TimeoutMs)
+
+	check("hooks.max_running", c.Hooks.MaxRunning > 0,
+		"must be positive, got %d", c.Hooks.MaxRunning)
+
+	lb, fm := c.Scrobbling.ListenBrainz, c.Scrobbling.LastFM
+
+	check("scrobbling.listenbrainz.url", IsHttpUrl(lb.Url),
+		"expected an http or https url, got %q", lb.Url)
+
+	check("scrobbling.listenbrainz.enabled", !lb.Enabled || lb.Token != "",
+		"scrobbling to listenbrainz needs scrobbling.listenbrainz.token")
+
+	check("scrobbling.lastfm.url", IsHttpUrl(fm.Url),
+		"expected an http or https url, got %q", fm.Url)
+
+	check("scrobbling.lastfm.auth_url", IsHttpUrl(fm.AuthUrl),
+		"expected an http or https url, got %q", fm.AuthUrl)
+
+	check("scrobbling.lastfm.enabled", !fm.Enabled || fm.ApiKey != "" && fm.Secret != "" && fm.SessionKey != "",
+		"scrobbling to lastfm needs scrobbling.lastfm.api_key, secret and session_key, see \"spogo scrobble auth\"")
+
+	problems = append(problems, c.Hooks.checkEvents(root)...)
+	problems = append(problems, c.Notifications.checkTemplates(root)...)
+
+	for _, l := range legacyColors {
+		node := findOption(root, l.from)
+
+		switch {
+		case node == nil:
+		case l.to == "":
+			problems = append(problems, problemAt(node, l.from, "no longer read"))
+		default:
+			problems = append(problems, problemAt(node, l.from, "no longer read, set theme_overrides.%s instead", l.to))
+		}
+	}
+
+	if node := findOption(root, "theme_overrides"); node != nil {
+		for _, name := range []string{"name", "inherits"} {
+			if n := findOption(node, name); n != nil {
+				problems = append(problems, problemAt(n, "theme_overrides."+name, "only the elements of the theme can be overridden"))
+			}
+		}
+
+		for _, p := range c.ThemeOverrides.checkValues(node) {
+			p.Option = join("theme_overrides", p.Option)
+			problems = append(problems, p)
+		}
+	}
+
+	return problems
+}
+
+// Checks the colors the theme file sets.
+func (t *Theme) checkValues(root *yaml.Node) []Problem {
+	problems := []Problem{}
+
+	styles := map[string]ThemeStyle{
+		"status_bar.now_playing": t.StatusBar.NowPlaying,
+		"status_bar.paused":      t.StatusBar.Paused,
+		"status_bar.no_player":   t.StatusBar.NoPlayer,
+		"progress_bar.completed": t.ProgressBar.Completed,
+		"progress_bar.remaining": t.ProgressBar.Remaining,
+		"labels":                 t.Labels,
+		"text":                   t.Text,
+		"title":                  t.Title,
+		"selected":               t.Selected,
+		"muted":                  t.Muted,
+		"error":                  t.Error,
+	}
+
+	for option, style := range styles {
+		for name, color := range map[string]string{"fg": style.Foreground, "bg": style.Background} {
+			node := findOption(root, option+"."+name)
+			if node != nil && color != "" && !IsColor(color) {
+				problems = append(problems, problemAt(node, option+"."+name, "%s", colorMessage(color)))
+			}
+		}
+	}
+
+	if node := findOption(root, "box"); node != nil && t.Box != "" && !IsBoxColor(t.Box) {
+		problems = append(problems, problemAt(node, "box", "%s", boxColorMessage(t.Box)))
+	}
+
+	return problems
+}
+
+// The message of an error without the type errorx prefixes it with.
+func message(err error) string {
+	if e := errorx.Cast(err); e != nil {
+		return e.Message()
+	}
+	return err.Error()
+}
+
+// Returns true for hex codes, "#rgb" or "#rrggbb".
+func IsHexColor(s string) bool {
+	if !strings.HasPrefix(s, "#") || len(s) != 4 && len(s) != 7 {
+		return false
+	}
+
+	_, err := strconv.ParseUint(s[1:], 16, 32)
+
+	return err == nil
+}
+
+// Returns true for hex codes and ansi color numbers, from 0 to 255.
+func IsColor(s string) bool {
+	if IsHexColor(s) {
+		return true
+	}
+
+	n, err := strconv.Atoi(s)
+
+	return err == nil && n >= 0 && n <= 255
+}
+
+// Returns true for the colors the player's box can be drawn in,
+// "#rrggbb" hex codes or the names of ansi colors.
+func IsBoxColor(s string) bool {
+	return IsHexColor(s) && len(s) == 7 || slices.Contains(BOX_COLORS, s)
+}
+
+// Returns true for absolute http and https urls.
+func IsHttpUrl(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func colorMessage(color string) string {
+	return fmt.Sprintf("expected a hex color such as \"#98971a\" or an ansi color from 0 to 255, got %q", color)
+}
+
+func boxColorMessage(color string) string {
+	return fmt.Sprintf("expected a hex color such as \"#98971a\" or one of %s, got %q", strings.Join(BOX_COLORS, ", "), color)
+}
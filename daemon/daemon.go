@@ -0,0 +1,199 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/dionvu/spogo/config"
+	"github.com/dionvu/spogo/err"
+	"github.com/dionvu/spogo/history"
+	"github.com/dionvu/spogo/hooks"
+	"github.com/dionvu/spogo/notify"
+	"github.com/dionvu/spogo/player"
+	"github.com/dionvu/spogo/scrobble"
+	"github.com/dionvu/spogo/spotify/auth"
+	comp "github.com/dionvu/spogo/tui/views/components"
+)
+
+const CONFIG_POLL_RATE = time.Second * 5
+
+// Everything following the player's state, run by the tui, or by
+// "spogo daemon" to keep following it once the tui is closed.
+type Listeners struct {
+	watcher *player.Watcher
+	events  <-chan player.Event
+
+	// Run from the tui, which leaves events to the daemon while it runs.
+	fromTUI    bool
+	daemonFile string
+
+	hooks     *hooks.Runner
+	notifier  *notify.Notifier
+	recorder  *history.Recorder
+	scrobbler *scrobble.Scrobbler
+
+	// Closed once every event received has been handled.
+	done chan struct{}
+}
+
+// Subscribes to the watcher, handling its events until stopped.
+func Listen(c *config.Config, w *player.Watcher, fromTUI bool) *Listeners {
+	l := &Listeners{
+		watcher:    w,
+		events:     w.Subscribe(),
+		fromTUI:    fromTUI,
+		daemonFile: c.DaemonFile(),
+		hooks:      hooks.New(c.Hooks),
+		notifier:   notify.New(c.Notifications),
+		recorder:   history.NewRecorder(history.NewPlays(c)),
+		done:       make(chan struct{}),
+	}
+
+	l.scrobbler = scrobble.New(c, l.standby)
+	l.recorder.OnPlay(l.scrobbler.Scrobble)
+
+	go l.listen()
+
+	return l
+}
+
+func (l *Listeners) listen() {
+	defer close(l.done)
+
+	for e := range l.events {
+		if l.standby() {
+			// The daemon records the play in progress from now on.
+			l.recorder.Drop()
+			continue
+		}
+
+		l.hooks.Handle(e)
+		l.notifier.Handle(e)
+		l.recorder.Handle(e)
+		l.scrobbler.Handle(e)
+	}
+}
+
+// Returns true while the daemon handles events instead of the tui.
+func (l *Listeners) standby() bool {
+	return l.fromTUI && running(l.daemonFile)
+}
+
+// Switches to the options of the changed config.
+func (l *Listeners) Update(c *config.Config) {
+	l.hooks.Update(c.Hooks)
+	l.notifier.Update(c.Notifications)
+	l.scrobbler.Update(c.Scrobbling)
+}
+
+// Sets whether the terminal running the tui is focused,
+// for notifications only shown while it isn't.
+func (l *Listeners) SetFocused(focused bool) {
+	l.notifier.SetFocused(focused)
+}
+
+// Stops receiving events, recording the play in progress
+// and waiting for the hooks already started.
+func (l *Listeners) Stop() {
+	l.watcher.Unsubscribe(l.events)
+	<-l.done
+	l.recorder.Close()
+	l.scrobbler.Stop()
+	l.hooks.Wait()
+}
+
+// Returns true if "spogo daemon" is running.
+func Running(c *config.Config) bool {
+	return running(c.DaemonFile())
+}
+
+// Reads the pid the daemon wrote, checking the process still exists,
+// as the file is left behind if the daemon is killed.
+func running(file string) bool {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return false
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil || pid <= 0 {
+		return false
+	}
+
+	err = syscall.Kill(pid, 0)
+
+	return err == nil || err == syscall.EPERM
+}
+
+// Follows the player's state without the tui until interrupted, running
+// hooks, sending notifications, recording and scrobbling plays. Only
+// one daemon runs at a time, the tui leaving events to it.
+func Run(c *config.Config, s *auth.Session, p *player.Player) error {
+	file := c.DaemonFile()
+
+	if running(file) {
+		return errors.Daemon.New("spogo daemon is already running, its pid is in %v", file)
+	}
+
+	if err := os.WriteFile(file, []byte(strconv.Itoa(os.Getpid())+"\n"), 0600); err != nil {
+		err = errors.FileWrite.Wrap(err, fmt.Sprintf("failed to write daemon pid file: %v", file))
+		errors.Log(err)
+		return err
+	}
+	defer os.Remove(file)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Notifications show the cached album art.
+	comp.OpenImageCache(c)
+
+	w := player.NewWatcher(p, s)
+	l := Listen(c, w, false)
+
+	go watchConfig(ctx, c, l)
+
+	fmt.Printf("spogo daemon running with pid %d, interrupt to stop\n", os.Getpid())
+
+	err := w.Run(c, ctx.Done())
+
+	l.Stop()
+
+	return err
+}
+
+// Reads "config.yaml" again whenever it changes, keeping the
+// previous options while it is invalid.
+func watchConfig(ctx context.Context, c *config.Config, l *Listeners) {
+	ticker := time.NewTicker(CONFIG_POLL_RATE)
+	defer ticker.Stop()
+
+	last := c.ModTime()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		modTime := c.ModTime()
+		if modTime.Equal(last) {
+			continue
+		}
+		last = modTime
+
+		n, err := c.Reload()
+		if err != nil {
+			continue
+		}
+
+		l.Update(n)
+	}
+}
@@ -16,19 +16,17 @@ var (
 	apiLogger   *log.Logger
 )
 
-// Initiates both the error logger and api call logger.
-func Init() {
-	cacheDir, err := os.UserCacheDir()
-	Catch(err)
-
-	os.Mkdir(filepath.Join(cacheDir, "spogo"), 0777)
-	os.Create(filepath.Join(cacheDir, "spogo", "spogo.log"))
-	logFileErr, err := os.OpenFile(filepath.Join(cacheDir, "spogo", "errors.log"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0666)
+// Initiates both the error logger and api call logger, logging
+// to files in the spogo cache directory, dir.
+func Init(dir string) {
+	os.MkdirAll(dir, 0777)
+	os.Create(filepath.Join(dir, "spogo.log"))
+	logFileErr, err := os.OpenFile(filepath.Join(dir, "errors.log"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0666)
 	if err != nil {
 		log.Fatalf("Failed to open error log file: %v", err)
 	}
 
-	logFileApi, err := os.OpenFile(filepath.Join(cacheDir, "spogo", "api.log"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0666)
+	logFileApi, err := os.OpenFile(filepath.Join(dir, "api.log"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0666)
 	if err != nil {
 		log.Fatalf("Failed to open error log file: %v", err)
 	}
@@ -56,6 +54,15 @@ func Catch(err error) {
 	}
 }
 
+// Like Catch, exiting with the code instead, so
+// scripts can tell a command failed.
+func CatchExit(err error, code int) {
+	if err != nil {
+		fmt.Printf("%v %v\n", color.RedString("Error:"), err.(*errorx.Error).Message())
+		os.Exit(code)
+	}
+}
+
 // Prints the error even if it's nil.
 func Print(err error) {
 	fmt.Printf("%v %v\n", color.RedString("Error:"), err.(*errorx.Error).Message())
@@ -68,6 +75,14 @@ func IsReauthenticationErr(err error) bool {
 	return false
 }
 
+func IsScrobbleRejectedErr(err error) bool {
+	return errorx.GetTypeName(err) == ScrobbleRejected.String()
+}
+
+func IsMissingScopeErr(err error) bool {
+	return errorx.GetTypeName(err) == MissingScope.String()
+}
+
 var (
 	App           = errorx.NewNamespace("app")
 	HTTP          = App.NewType("http")
@@ -83,16 +98,32 @@ var (
 	JSONEncode    = App.NewType("json-encode")
 	JSONDecode    = App.NewType("json-decode")
 	YAML          = App.NewType("yaml")
+	Config        = App.NewType("config")
+	Keymap        = App.NewType("keymap")
+	Theme         = App.NewType("theme")
+	Hook          = App.NewType("hook")
+	Daemon        = App.NewType("daemon")
+	Notification  = App.NewType("notification")
+	Scrobble      = App.NewType("scrobble")
+
+	// Listens a scrobbling service will never accept.
+	ScrobbleRejected = Scrobble.NewSubtype("rejected")
 
 	User             = errorx.NewNamespace("user")
 	Reauthentication = User.NewType("reauthentication")
 	NoFlagProvided   = User.NewType("no-flag")
 	Input            = User.NewType("input")
+	MissingScope     = User.NewType("missing-scope")
 	PlayBack         = errorx.NewNamespace("playback")
 	NoDevice         = PlayBack.NewType("no-device")
 	Jpeg             = PlayBack.NewType("jpeg")
+	PlayerEvent      = PlayBack.NewType("player-event")
 
 	PlayerView             = errorx.NewNamespace("player-view")
 	PlayerViewInvalidState = PlayerView.NewType("invalid-state")
 	PlayerViewImageCache   = PlayerView.NewType("caching-image")
+	PlayerViewImageRender  = PlayerView.NewType("rendering-image")
+
+	SmartPlaylist     = errorx.NewNamespace("smart-playlist")
+	SmartPlaylistRule = SmartPlaylist.NewType("invalid-rule")
 )
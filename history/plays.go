@@ -0,0 +1,194 @@
+package history
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/dionvu/spogo/config"
+	"github.com/dionvu/spogo/err"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	PLAYS_BUCKET = "plays"
+
+	// How long to wait for another spogo writing to the database.
+	PLAYS_LOCK_TIMEOUT = time.Second * 2
+)
+
+// A track listened to, recorded once it was played long enough.
+type Play struct {
+	TrackID    string    `json:"track_id"`
+	Uri        string    `json:"uri"`
+	Name       string    `json:"name"`
+	Artists    []string  `json:"artists"`
+	Album      string    `json:"album"`
+	ContextUri string    `json:"context_uri"`
+	Device     string    `json:"device"`
+	StartedAt  time.Time `json:"started_at"`
+	ListenedMs int       `json:"listened_ms"`
+	DurationMs int       `json:"duration_ms"`
+}
+
+// Every play recorded, in "plays.db" in the cache directory, keyed
+// by when the play started. The database is only opened while it is
+// read or written, so the tui, the daemon and commands can share it.
+type Plays struct {
+	path string
+}
+
+func NewPlays(c *config.Config) *Plays {
+	return &Plays{path: c.PlaysFile()}
+}
+
+// Records the play.
+func (p *Plays) Add(play Play) error {
+	value, err := json.Marshal(play)
+	if err != nil {
+		err = errors.JSONMarshal.Wrap(err, "failed to marshal play")
+		errors.Log(err)
+		return err
+	}
+
+	return p.update(func(b *bolt.Bucket) error {
+		// Plays starting at the same instant are kept apart.
+		key := playKey(play.StartedAt)
+		for b.Get(key) != nil {
+			binary.BigEndian.PutUint64(key, binary.BigEndian.Uint64(key)+1)
+		}
+
+		return b.Put(key, value)
+	})
+}
+
+// Returns the plays started from since until before until, oldest
+// first. Zero times leave the range open on that side.
+func (p *Plays) Between(since time.Time, until time.Time) ([]Play, error) {
+	plays := []Play{}
+
+	err := p.view(func(b *bolt.Bucket) error {
+		c := b.Cursor()
+
+		k, v := c.First()
+		if !since.IsZero() {
+			k, v = c.Seek(playKey(since))
+		}
+
+		for ; k != nil; k, v = c.Next() {
+			if !until.IsZero() && binary.BigEndian.Uint64(k) >= uint64(until.UnixNano()) {
+				break
+			}
+
+			play := Play{}
+			if err := json.Unmarshal(v, &play); err != nil {
+				return errors.JSONUnmarshal.Wrap(err, "failed to unmarshal play")
+			}
+
+			plays = append(plays, play)
+		}
+
+		return nil
+	})
+
+	return plays, err
+}
+
+// Returns up to n of the plays started before until, newest first.
+// A zero until returns the latest plays.
+func (p *Plays) Before(until time.Time, n int) ([]Play, error) {
+	plays := []Play{}
+
+	err := p.view(func(b *bolt.Bucket) error {
+		c := b.Cursor()
+
+		k, v := c.Last()
+		if !until.IsZero() {
+			// Seek finds the first play from until on, or none
+			// if every play started before it.
+			if k, _ = c.Seek(playKey(until)); k == nil {
+				k, v = c.Last()
+			} else {
+				k, v = c.Prev()
+			}
+		}
+
+		for ; k != nil && len(plays) < n; k, v = c.Prev() {
+			play := Play{}
+			if err := json.Unmarshal(v, &play); err != nil {
+				return errors.JSONUnmarshal.Wrap(err, "failed to unmarshal play")
+			}
+
+			plays = append(plays, play)
+		}
+
+		return nil
+	})
+
+	return plays, err
+}
+
+// Plays are stored in order of when they started.
+func playKey(t time.Time) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(t.UnixNano()))
+	return key
+}
+
+func (p *Plays) update(fn func(*bolt.Bucket) error) error {
+	db, err := p.open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(PLAYS_BUCKET))
+		if err != nil {
+			return err
+		}
+		return fn(b)
+	})
+	if err != nil {
+		err = errors.FileWrite.Wrap(err, fmt.Sprintf("failed to write play history: %v", p.path))
+		errors.Log(err)
+		return err
+	}
+
+	return nil
+}
+
+func (p *Plays) view(fn func(*bolt.Bucket) error) error {
+	db, err := p.open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	err = db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(PLAYS_BUCKET))
+		if b == nil {
+			return nil
+		}
+		return fn(b)
+	})
+	if err != nil {
+		err = errors.FileRead.Wrap(err, fmt.Sprintf("failed to read play history: %v", p.path))
+		errors.Log(err)
+		return err
+	}
+
+	return nil
+}
+
+func (p *Plays) open() (*bolt.DB, error) {
+	db, err := bolt.Open(p.path, 0600, &bolt.Options{Timeout: PLAYS_LOCK_TIMEOUT})
+	if err != nil {
+		err = errors.FileOpen.Wrap(err, fmt.Sprintf("failed to open play history: %v", p.path))
+		errors.Log(err)
+		return nil, err
+	}
+
+	return db, nil
+}
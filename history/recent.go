@@ -0,0 +1,227 @@
+package history
+
+import (
+	"sort"
+	"time"
+
+	"github.com/dionvu/spogo/config"
+	"github.com/dionvu/spogo/spotify"
+	"github.com/dionvu/spogo/spotify/auth"
+)
+
+// Spotify records a track as played once it ends, while plays are
+// recorded by when they started, so a play is the same as one of
+// spotify's if spotify's comes within its duration and this long.
+const RECENT_MATCH_SLACK = time.Minute * 2
+
+// A track played, as spotify or the play history recorded it.
+type RecentPlay struct {
+	TrackID    string    `json:"track_id"`
+	Uri        string    `json:"uri"`
+	Name       string    `json:"name"`
+	Artists    []string  `json:"artists"`
+	Album      string    `json:"album"`
+	AlbumUri   string    `json:"album_uri"`
+	DurationMs int       `json:"duration_ms"`
+	PlayedAt   time.Time `json:"played_at"`
+
+	// What the track was played from, empty if played on its own.
+	ContextUri  string `json:"context_uri"`
+	ContextType string `json:"context_type"`
+
+	// Only found in the play history.
+	Local bool `json:"local"`
+}
+
+func recentFromSpotify(h spotify.PlayHistory) RecentPlay {
+	r := RecentPlay{
+		TrackID:    h.Track.ID,
+		Uri:        h.Track.Uri,
+		Name:       h.Track.Name,
+		Artists:    []string{},
+		Album:      h.Track.Album.Name,
+		AlbumUri:   h.Track.Album.Uri,
+		DurationMs: h.Track.DurationMs,
+		PlayedAt:   h.PlayedAt,
+	}
+
+	for _, a := range h.Track.Artists {
+		r.Artists = append(r.Artists, a.Name)
+	}
+
+	if h.Context != nil {
+		r.ContextUri = h.Context.Uri
+		r.ContextType = h.Context.Type
+	}
+
+	return r
+}
+
+func recentFromPlay(p Play) RecentPlay {
+	return RecentPlay{
+		TrackID:     p.TrackID,
+		Uri:         p.Uri,
+		Name:        p.Name,
+		Artists:     p.Artists,
+		Album:       p.Album,
+		DurationMs:  p.DurationMs,
+		PlayedAt:    p.StartedAt,
+		ContextUri:  p.ContextUri,
+		ContextType: spotify.UriType(p.ContextUri),
+		Local:       true,
+	}
+}
+
+// Lists the tracks played from spotify's recently played, along with
+// the play history if "recently_played.local_history" is set, which
+// goes back further than the last 50 tracks spotify keeps.
+type RecentPlays struct {
+	session *auth.Session
+
+	// Nil unless the play history is listed.
+	plays *Plays
+}
+
+func NewRecentPlays(c *config.Config, s *auth.Session) *RecentPlays {
+	r := &RecentPlays{session: s}
+
+	if c.RecentlyPlayed.LocalHistory {
+		r.plays = NewPlays(c)
+	}
+
+	return r
+}
+
+// Returns around limit tracks played before before, or the latest if
+// zero, newest first, and whether older tracks are left. A play of the
+// history may be listed by spotify on the newer page, as spotify times
+// tracks by when they ended, so pages are combined with Merge.
+func (r *RecentPlays) Before(before time.Time, limit int) ([]RecentPlay, bool, error) {
+	items, more, err := spotify.RecentlyPlayed(r.session, before, time.Time{}, limit)
+	if err != nil {
+		return nil, false, err
+	}
+
+	recent := []RecentPlay{}
+	for _, h := range items {
+		recent = append(recent, recentFromSpotify(h))
+	}
+
+	if r.plays == nil {
+		return recent, more, nil
+	}
+
+	plays, err := r.plays.Before(before, limit)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Each source is only complete back to its oldest track, so while
+	// either has more the page ends at the later of the two, leaving
+	// the rest for the next page.
+	var cut time.Time
+	if more && len(recent) > 0 {
+		cut = recent[len(recent)-1].PlayedAt
+	}
+
+	if len(plays) == limit {
+		more = true
+		if oldest := plays[len(plays)-1].StartedAt; oldest.After(cut) {
+			cut = oldest
+		}
+	}
+
+	local := []RecentPlay{}
+	for _, p := range plays {
+		local = append(local, recentFromPlay(p))
+	}
+
+	page := []RecentPlay{}
+	for _, p := range Merge(recent, local) {
+		if !p.PlayedAt.Before(cut) {
+			page = append(page, p)
+		}
+	}
+
+	return page, more, nil
+}
+
+// Returns the tracks played since after, newest first.
+func (r *RecentPlays) After(after time.Time) ([]RecentPlay, error) {
+	items, _, err := spotify.RecentlyPlayed(r.session, time.Time{}, after, spotify.RECENTLY_PLAYED_LIMIT)
+	if err != nil {
+		return nil, err
+	}
+
+	recent := []RecentPlay{}
+	for _, h := range items {
+		recent = append(recent, recentFromSpotify(h))
+	}
+
+	if r.plays == nil {
+		return recent, nil
+	}
+
+	plays, err := r.plays.Between(after.Add(time.Nanosecond), time.Time{})
+	if err != nil {
+		return nil, err
+	}
+
+	local := []RecentPlay{}
+	for _, p := range plays {
+		local = append(local, recentFromPlay(p))
+	}
+
+	return Merge(recent, local), nil
+}
+
+// Combines lists of tracks played, newest first, leaving out plays of
+// the history that spotify lists too, as spotify's know their context.
+func Merge(lists ...[]RecentPlay) []RecentPlay {
+	all := []RecentPlay{}
+	for _, l := range lists {
+		all = append(all, l...)
+	}
+
+	matched := map[int]bool{}
+	merged := []RecentPlay{}
+
+	for _, p := range all {
+		if !p.Local {
+			merged = append(merged, p)
+			continue
+		}
+
+		same := false
+		for i, other := range all {
+			if !other.Local && !matched[i] && p.TrackID == other.TrackID && !other.PlayedAt.Before(p.PlayedAt) &&
+				other.PlayedAt.Sub(p.PlayedAt) <= time.Duration(p.DurationMs)*time.Millisecond+RECENT_MATCH_SLACK {
+				matched[i] = true
+				same = true
+				break
+			}
+		}
+
+		if !same {
+			merged = append(merged, p)
+		}
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].PlayedAt.After(merged[j].PlayedAt)
+	})
+
+	return merged
+}
+
+// The context and track to play the track again where it was played
+// from. Spotify only starts albums and playlists at a given track, so
+// the track is played on its own from anything else.
+func (p RecentPlay) Replay() (contextUri string, uri string) {
+	switch p.ContextType {
+	case "album", "playlist":
+		return p.ContextUri, p.Uri
+	default:
+		return "", p.Uri
+	}
+}
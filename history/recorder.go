@@ -0,0 +1,155 @@
+package history
+
+import (
+	"sync"
+	"time"
+
+	"github.com/dionvu/spogo/player"
+)
+
+// A track counts as played once listened to for this long,
+// or for half of its length if shorter.
+const MIN_PLAY_MS = 30000
+
+// Follows the player's events, recording every track listened to
+// long enough to count as a play.
+type Recorder struct {
+	plays *Plays
+
+	mu sync.Mutex
+
+	// The play in progress, nil if nothing is playing.
+	current *Play
+
+	// When playback last started, zero while paused.
+	playingSince time.Time
+
+	// Called with each play recorded.
+	played []func(Play)
+}
+
+func NewRecorder(p *Plays) *Recorder {
+	return &Recorder{plays: p}
+}
+
+// Calls fn with every play recorded from now on.
+func (r *Recorder) OnPlay(fn func(Play)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.played = append(r.played, fn)
+}
+
+func (r *Recorder) Handle(e player.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	at := e.Changed().Time
+
+	switch e := e.(type) {
+	case player.TrackChanged:
+		r.finish(at)
+		r.start(e.Change)
+
+	case player.PlaybackStarted:
+		if r.current != nil && r.playingSince.IsZero() {
+			r.playingSince = at
+		}
+
+	case player.PlaybackPaused:
+		r.pause(at)
+
+	case player.Seeked:
+		// Going back to the start of a track, as on repeat,
+		// begins another play of it.
+		if r.current != nil && e.ToMs < e.FromMs && e.ToMs <= player.SEEK_TOLERANCE_MS {
+			r.finish(at)
+			r.start(e.Change)
+		}
+	}
+}
+
+// Records the play in progress, as spogo is closing.
+func (r *Recorder) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.finish(time.Now())
+}
+
+// Forgets the play in progress without recording it, as when
+// another recorder takes over.
+func (r *Recorder) Drop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.current = nil
+	r.playingSince = time.Time{}
+}
+
+// Starts a play of the state's track. Progress made before the
+// state was polled is counted as listened, up to the polling rate.
+func (r *Recorder) start(change player.Change) {
+	s := change.State
+	if s == nil || s.Track == nil {
+		return
+	}
+
+	t := s.Track
+	progress := time.Duration(s.ProgressMs) * time.Millisecond
+
+	r.current = &Play{
+		TrackID:    t.ID,
+		Uri:        t.Uri,
+		Name:       t.Name,
+		Artists:    []string{},
+		Album:      t.Album.Name,
+		ContextUri: s.Context.Uri,
+		StartedAt:  change.Time.Add(-progress),
+		DurationMs: t.DurationMs,
+	}
+
+	for _, a := range t.Artists {
+		r.current.Artists = append(r.current.Artists, a.Name)
+	}
+
+	if s.Device != nil {
+		r.current.Device = s.Device.Name
+	}
+
+	if s.IsPlaying {
+		r.playingSince = change.Time.Add(-min(progress, player.POLLING_RATE_STATE_SEC))
+	}
+}
+
+func (r *Recorder) pause(at time.Time) {
+	if r.current != nil && !r.playingSince.IsZero() {
+		r.current.ListenedMs += int(at.Sub(r.playingSince).Milliseconds())
+	}
+	r.playingSince = time.Time{}
+}
+
+// Ends the play in progress, recording it if it was long enough.
+func (r *Recorder) finish(at time.Time) {
+	r.pause(at)
+
+	play := r.current
+	r.current = nil
+
+	if play == nil || play.ListenedMs <= 0 || play.ListenedMs < min(MIN_PLAY_MS, play.DurationMs/2) {
+		return
+	}
+
+	// Polls missed while playing, such as when offline, aren't
+	// known to have been listened through.
+	if play.DurationMs > 0 {
+		play.ListenedMs = min(play.ListenedMs, play.DurationMs)
+	}
+
+	// Errors are logged by Add.
+	r.plays.Add(*play)
+
+	for _, fn := range r.played {
+		fn(*play)
+	}
+}
@@ -0,0 +1,198 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/dionvu/spogo/config"
+	"github.com/dionvu/spogo/err"
+)
+
+const (
+	KIND_QUERY    = "query"
+	KIND_ALBUM    = "album"
+	KIND_PLAYLIST = "playlist"
+	KIND_ARTIST   = "artist"
+
+	// The number of entries kept for search queries,
+	// and separately for recently opened items.
+	MAX_ENTRIES = 100
+)
+
+// A search query, or an album, playlist or artist
+// the user has opened.
+type Entry struct {
+	Kind string    `json:"kind"`
+	Name string    `json:"name"`
+	Uri  string    `json:"uri,omitempty"`
+	Time time.Time `json:"time"`
+}
+
+// The search history and recently opened items, newest first,
+// persisted to "history.json" in the cache directory.
+type Search struct {
+	mu      sync.Mutex
+	path    string
+	Entries []Entry `json:"entries"`
+}
+
+// Loads the search history, starting with an empty
+// history if none has been saved yet.
+func LoadSearch(c *config.Config) (*Search, error) {
+	h := &Search{path: c.HistoryFile()}
+
+	b, err := os.ReadFile(h.path)
+	if os.IsNotExist(err) {
+		return h, nil
+	}
+	if err != nil {
+		err = errors.FileRead.Wrap(err, fmt.Sprintf("failed to read history file: %v", h.path))
+		errors.Log(err)
+		return nil, err
+	}
+
+	if err = json.Unmarshal(b, h); err != nil {
+		err = errors.JSONUnmarshal.Wrap(err, fmt.Sprintf("failed to unmarshal history file: %v", h.path))
+		errors.Log(err)
+		return nil, err
+	}
+
+	return h, nil
+}
+
+// Records a search query.
+func (h *Search) AddQuery(query string) error {
+	return h.add(Entry{Kind: KIND_QUERY, Name: query, Time: time.Now()})
+}
+
+// Records an opened album, playlist or artist.
+func (h *Search) AddItem(kind string, name string, uri string) error {
+	return h.add(Entry{Kind: kind, Name: name, Uri: uri, Time: time.Now()})
+}
+
+// Moves the entry to the front, dropping any older copy of it
+// and the oldest entries of its kind past MAX_ENTRIES.
+func (h *Search) add(e Entry) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	entries := []Entry{e}
+	count := 1
+
+	for _, old := range h.Entries {
+		if old.same(e) {
+			continue
+		}
+
+		if (old.Kind == KIND_QUERY) == (e.Kind == KIND_QUERY) {
+			if count >= MAX_ENTRIES {
+				continue
+			}
+			count++
+		}
+
+		entries = append(entries, old)
+	}
+
+	h.Entries = entries
+
+	return h.save()
+}
+
+// Search queries, newest first.
+func (h *Search) Queries() []Entry {
+	return h.filter(func(e Entry) bool { return e.Kind == KIND_QUERY })
+}
+
+// Recently opened albums, playlists and artists, newest first.
+func (h *Search) Recent() []Entry {
+	return h.filter(func(e Entry) bool { return e.Kind != KIND_QUERY })
+}
+
+// Every entry, newest first.
+func (h *Search) All() []Entry {
+	return h.filter(func(Entry) bool { return true })
+}
+
+func (h *Search) filter(keep func(Entry) bool) []Entry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	entries := []Entry{}
+	for _, e := range h.Entries {
+		if keep(e) {
+			entries = append(entries, e)
+		}
+	}
+
+	return entries
+}
+
+// Deletes a single entry.
+func (h *Search) Remove(e Entry) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	entries := []Entry{}
+	for _, old := range h.Entries {
+		if !old.same(e) {
+			entries = append(entries, old)
+		}
+	}
+
+	h.Entries = entries
+
+	return h.save()
+}
+
+// Deletes every search query if queries is set, and every
+// recently opened item if items is set.
+func (h *Search) Purge(queries bool, items bool) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	entries := []Entry{}
+	for _, e := range h.Entries {
+		if e.Kind == KIND_QUERY && !queries || e.Kind != KIND_QUERY && !items {
+			entries = append(entries, e)
+		}
+	}
+
+	h.Entries = entries
+
+	return h.save()
+}
+
+func (h *Search) save() error {
+	b, err := json.Marshal(h)
+	if err != nil {
+		err = errors.JSONMarshal.Wrap(err, "failed to marshal history")
+		errors.Log(err)
+		return err
+	}
+
+	if err = os.WriteFile(h.path, b, 0600); err != nil {
+		err = errors.FileWrite.Wrap(err, fmt.Sprintf("failed to write history file: %v", h.path))
+		errors.Log(err)
+		return err
+	}
+
+	return nil
+}
+
+// Entries are the same if they refer to the same query or item,
+// regardless of when they were recorded.
+func (e Entry) same(o Entry) bool {
+	if e.Kind != o.Kind {
+		return false
+	}
+
+	if e.Uri != "" || o.Uri != "" {
+		return e.Uri == o.Uri
+	}
+
+	return e.Name == o.Name
+}
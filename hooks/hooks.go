@@ -0,0 +1,311 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/dionvu/spogo/config"
+	"github.com/dionvu/spogo/err"
+	"github.com/dionvu/spogo/player"
+	"github.com/dionvu/spogo/spotify"
+)
+
+const (
+	// Time given to a killed hook to close its output.
+	KILL_WAIT = time.Second
+
+	// The end of a failed hook's error output kept in the log.
+	MAX_STDERR_BYTES = 512
+)
+
+// A track as described to hooks.
+type Track struct {
+	ID         string   `json:"id"`
+	Uri        string   `json:"uri"`
+	Name       string   `json:"name"`
+	Artists    []string `json:"artists"`
+	Album      string   `json:"album"`
+	DurationMs int      `json:"duration_ms"`
+	ImageUrl   string   `json:"image_url,omitempty"`
+}
+
+// The event written as json to a hook's stdin. Device and track
+// are null when there is no active device.
+type Payload struct {
+	Event      string         `json:"event"`
+	Time       time.Time      `json:"time"`
+	Playing    bool           `json:"playing"`
+	ProgressMs int            `json:"progress_ms"`
+	Shuffle    bool           `json:"shuffle"`
+	Repeat     string         `json:"repeat"`
+	Context    player.Context `json:"context"`
+	Device     *player.Device `json:"device"`
+	Track      *Track         `json:"track"`
+
+	// What the value changed from: the previous track, volume, device,
+	// repeat state or context, or for a seek, the position in ms.
+	Previous any `json:"previous,omitempty"`
+}
+
+// Runs the hooks set in "config.yaml" for each event.
+type Runner struct {
+	mu    sync.Mutex
+	hooks config.Hooks
+
+	// Hooks running, signalled when one finishes.
+	running int
+	done    *sync.Cond
+
+	wg sync.WaitGroup
+}
+
+func New(h config.Hooks) *Runner {
+	r := &Runner{hooks: h}
+	r.done = sync.NewCond(&r.mu)
+	return r
+}
+
+// Replaces the hooks, such as when "config.yaml" is changed.
+// Hooks already running are left to finish.
+func (r *Runner) Update(h config.Hooks) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.hooks = h
+	r.done.Broadcast()
+}
+
+// Waits for every hook started to finish.
+func (r *Runner) Wait() {
+	r.wg.Wait()
+}
+
+// Starts the hooks of the event, without waiting for them. Failing
+// hooks, and those killed after the timeout, are logged.
+func (r *Runner) Handle(e player.Event) {
+	name := EventName(e)
+
+	r.mu.Lock()
+	commands := r.hooks.On[name]
+	timeout := time.Duration(r.hooks.TimeoutMs) * time.Millisecond
+	r.mu.Unlock()
+
+	if len(commands) == 0 {
+		return
+	}
+
+	payload := NewPayload(e)
+
+	stdin, err := json.Marshal(payload)
+	if err != nil {
+		errors.Log(errors.JSONMarshal.Wrap(err, "failed to marshal %v event for hooks", name))
+		return
+	}
+
+	env := append(os.Environ(), payload.Env()...)
+
+	for _, command := range commands {
+		r.wg.Add(1)
+
+		go func() {
+			defer r.wg.Done()
+
+			r.acquire()
+			defer r.release()
+
+			if err := run(command, stdin, env, timeout); err != nil {
+				errors.Log(err)
+			}
+		}()
+	}
+}
+
+// Waits until fewer than hooks.max_running hooks are running.
+func (r *Runner) acquire() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for r.running >= r.hooks.MaxRunning {
+		r.done.Wait()
+	}
+	r.running++
+}
+
+func (r *Runner) release() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.running--
+	r.done.Signal()
+}
+
+// Runs the command with sh, killing it along with anything it
+// started once the timeout passes.
+func run(command string, stdin []byte, env []string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	stderr := bytes.Buffer{}
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Env = env
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stderr = &stderr
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = KILL_WAIT
+
+	err := cmd.Run()
+
+	if ctx.Err() == context.DeadlineExceeded {
+		return errors.Hook.New("hook %q killed after %v", command, timeout)
+	}
+	if err != nil {
+		output := stderr.Bytes()
+		if len(output) > MAX_STDERR_BYTES {
+			output = output[len(output)-MAX_STDERR_BYTES:]
+		}
+		if output := strings.TrimSpace(string(output)); output != "" {
+			return errors.Hook.Wrap(err, "hook %q failed: %s", command, output)
+		}
+		return errors.Hook.Wrap(err, "hook %q failed", command)
+	}
+
+	return nil
+}
+
+// Returns the name hooks are set for in "config.yaml", such as
+// "track_changed", of the event.
+func EventName(e player.Event) string {
+	switch e.(type) {
+	case player.TrackChanged:
+		return config.EVENT_TRACK_CHANGED
+	case player.PlaybackStarted:
+		return config.EVENT_PLAYBACK_STARTED
+	case player.PlaybackPaused:
+		return config.EVENT_PLAYBACK_PAUSED
+	case player.Seeked:
+		return config.EVENT_SEEKED
+	case player.VolumeChanged:
+		return config.EVENT_VOLUME_CHANGED
+	case player.DeviceChanged:
+		return config.EVENT_DEVICE_CHANGED
+	case player.ShuffleChanged:
+		return config.EVENT_SHUFFLE_CHANGED
+	case player.RepeatChanged:
+		return config.EVENT_REPEAT_CHANGED
+	case player.ContextChanged:
+		return config.EVENT_CONTEXT_CHANGED
+	}
+	return ""
+}
+
+// Describes the event and the state after it to hooks.
+func NewPayload(e player.Event) Payload {
+	change := e.Changed()
+
+	p := Payload{Event: EventName(e), Time: change.Time}
+
+	if s := change.State; s != nil {
+		p.Playing = s.IsPlaying
+		p.ProgressMs = s.ProgressMs
+		p.Shuffle = s.ShuffleState
+		p.Repeat = s.RepeatState
+		p.Context = s.Context
+		p.Device = s.Device
+		p.Track = newTrack(s.Track)
+	}
+
+	switch e := e.(type) {
+	case player.TrackChanged:
+		if e.Previous != nil {
+			p.Previous = newTrack(e.Previous)
+		}
+	case player.Seeked:
+		p.Previous = e.FromMs
+	case player.VolumeChanged:
+		p.Previous = e.Previous
+	case player.DeviceChanged:
+		if e.Previous != nil {
+			p.Previous = e.Previous
+		}
+	case player.RepeatChanged:
+		p.Previous = e.Previous
+	case player.ContextChanged:
+		p.Previous = e.Previous
+	}
+
+	return p
+}
+
+func newTrack(t *spotify.Track) *Track {
+	if t == nil {
+		return nil
+	}
+
+	track := &Track{
+		ID:         t.ID,
+		Uri:        t.Uri,
+		Name:       t.Name,
+		Artists:    []string{},
+		Album:      t.Album.Name,
+		DurationMs: t.DurationMs,
+	}
+
+	for _, a := range t.Artists {
+		track.Artists = append(track.Artists, a.Name)
+	}
+
+	if len(t.Album.Images) > 0 {
+		track.ImageUrl = t.Album.Images[0].Url
+	}
+
+	return track
+}
+
+// The payload as SPOGO_HOOK_* environment variables, for hooks that
+// don't read json. The device and track are left unset without one.
+func (p Payload) Env() []string {
+	vars := map[string]string{
+		"EVENT":       p.Event,
+		"TIME":        p.Time.Format(time.RFC3339),
+		"PLAYING":     strconv.FormatBool(p.Playing),
+		"PROGRESS_MS": strconv.Itoa(p.ProgressMs),
+		"SHUFFLE":     strconv.FormatBool(p.Shuffle),
+		"REPEAT":      p.Repeat,
+		"CONTEXT_URI": p.Context.Uri,
+	}
+
+	if d := p.Device; d != nil {
+		vars["DEVICE_ID"] = d.ID
+		vars["DEVICE_NAME"] = d.Name
+		vars["VOLUME"] = strconv.Itoa(d.VolumePercent)
+	}
+
+	if t := p.Track; t != nil {
+		vars["TRACK_ID"] = t.ID
+		vars["TRACK_URI"] = t.Uri
+		vars["TRACK_NAME"] = t.Name
+		vars["TRACK_ARTISTS"] = strings.Join(t.Artists, ", ")
+		vars["TRACK_ALBUM"] = t.Album
+		vars["TRACK_DURATION_MS"] = strconv.Itoa(t.DurationMs)
+		vars["TRACK_IMAGE_URL"] = t.ImageUrl
+	}
+
+	env := []string{}
+	for name, value := range vars {
+		env = append(env, config.ENV_HOOK_PREFIX+name+"="+value)
+	}
+
+	return env
+}
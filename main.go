@@ -1,22 +1,56 @@
 package main
 
 import (
+	"fmt"
 	"log"
 	"os"
 	"os/exec"
 
+	"github.com/dionvu/spogo/cli"
 	"github.com/dionvu/spogo/config"
 	"github.com/dionvu/spogo/err"
 	"github.com/dionvu/spogo/player"
 	"github.com/dionvu/spogo/spotify/auth"
 	"github.com/dionvu/spogo/tui"
+	"github.com/fatih/color"
+	"golang.org/x/term"
 )
 
 func main() {
-	errors.Init()
+	overrides, args, err := cli.ParseFlags(os.Args[1:])
+	errors.Catch(err)
+
+	cachePath, err := overrides.CachePath()
+	errors.Catch(err)
+
+	errors.Init(cachePath)
 
-	c, err := config.New()
+	c, err := config.New(overrides)
 	errors.Catch(err)
+
+	if len(args) > 0 {
+		errors.CatchExit(cli.Run(args, c), 1)
+		return
+	}
+
+	// Asks for the spotify client on first run, unless the input
+	// isn't a terminal, leaving the client to be entered in the file.
+	// Not needed when options are set by the environment or flags.
+	if !c.Exists() && !c.Overridden() {
+		if !term.IsTerminal(int(os.Stdin.Fd())) {
+			errors.Catch(c.Create())
+			fmt.Printf("Please enter your spotify client ID & client secret: %v\n", color.YellowString(c.FilePath()))
+			return
+		}
+
+		errors.Catch(tui.RunSetup(c))
+	}
+
+	// Older files are only upgraded on disk here, starting spogo, and
+	// kept as they are by commands, which upgrade them in memory.
+	// The file is still loaded if it can't be rewritten.
+	backup, _ := c.Upgrade()
+
 	errors.Catch(c.Load())
 
 	auth, err := auth.New(c)
@@ -29,6 +63,12 @@ func main() {
 	cmd.Stdout = os.Stdout
 	cmd.Run()
 
+	// Shown once the tui exits, as it takes the whole screen.
+	if backup != "" {
+		fmt.Printf("Upgraded %v to version %d, the original is backed up at %v\n",
+			c.FilePath(), config.CONFIG_VERSION, backup)
+	}
+
 	program := tui.New(auth, player, c)
 	if err := program.Run(); err != nil {
 		log.Fatal(err)
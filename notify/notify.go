@@ -0,0 +1,172 @@
+package notify
+
+import (
+	"html"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/dionvu/spogo/config"
+	"github.com/dionvu/spogo/err"
+	"github.com/dionvu/spogo/player"
+	comp "github.com/dionvu/spogo/tui/views/components"
+	"github.com/godbus/dbus/v5"
+)
+
+// The freedesktop notification service, on the session bus.
+const (
+	DBUS_NAME   = "org.freedesktop.Notifications"
+	DBUS_PATH   = "/org/freedesktop/Notifications"
+	DBUS_NOTIFY = DBUS_NAME + ".Notify"
+
+	// Leaves how long notifications are shown to the server.
+	EXPIRE_DEFAULT = int32(-1)
+)
+
+// Sends a desktop notification whenever the track changes.
+type Notifier struct {
+	mu      sync.Mutex
+	options config.Notifications
+	summary *template.Template
+	body    *template.Template
+
+	// Whether the terminal running spogo is focused,
+	// never for "spogo daemon".
+	focused bool
+
+	// Held while notifying, so notifications replace each other in order.
+	sending sync.Mutex
+
+	conn *dbus.Conn
+
+	// The id of the last notification, which the next one replaces.
+	id uint32
+
+	// The cover of the last track notified of.
+	cover comp.Image
+}
+
+func New(n config.Notifications) *Notifier {
+	notifier := &Notifier{}
+	notifier.Update(n)
+	return notifier
+}
+
+// Replaces the options, such as when "config.yaml" is changed.
+func (n *Notifier) Update(o config.Notifications) {
+	summary, err := config.NotificationTemplate(o.Summary)
+	if err != nil {
+		summary, _ = config.NotificationTemplate(config.DEFAULT_NOTIFICATION_SUMMARY)
+	}
+
+	body, err := config.NotificationTemplate(o.Body)
+	if err != nil {
+		body, _ = config.NotificationTemplate(config.DEFAULT_NOTIFICATION_BODY)
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.options, n.summary, n.body = o, summary, body
+}
+
+// Sets whether the terminal running spogo is focused.
+func (n *Notifier) SetFocused(focused bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.focused = focused
+}
+
+// Notifies of the new track on a track change, without waiting for
+// the cover to be downloaded or the notification to be sent.
+func (n *Notifier) Handle(e player.Event) {
+	changed, ok := e.(player.TrackChanged)
+	if !ok || changed.Track == nil {
+		return
+	}
+
+	n.mu.Lock()
+	skip := !n.options.Enabled || n.options.WhenUnfocused && n.focused
+	summary, body := n.summary, n.body
+	n.mu.Unlock()
+
+	if skip {
+		return
+	}
+
+	track := changed.Track
+
+	fields := config.NotificationFields{
+		Track:   track.Name,
+		Artists: track.ArtistsString(),
+		Album:   track.Album.Name,
+	}
+
+	coverUrl := ""
+	if len(track.Album.Images) > 0 {
+		coverUrl = track.Album.Images[0].Url
+	}
+
+	go func() {
+		if err := n.notify(summary, body, fields, coverUrl); err != nil {
+			errors.Log(err)
+		}
+	}()
+}
+
+func (n *Notifier) notify(summary *template.Template, body *template.Template, fields config.NotificationFields, coverUrl string) error {
+	n.sending.Lock()
+	defer n.sending.Unlock()
+
+	s := strings.Builder{}
+	if err := summary.Execute(&s, fields); err != nil {
+		return errors.Notification.Wrap(err, "failed to write notification summary")
+	}
+
+	// Servers may read the body as markup, so the track's
+	// fields are escaped, leaving any markup of the template.
+	escaped := config.NotificationFields{
+		Track:   html.EscapeString(fields.Track),
+		Artists: html.EscapeString(fields.Artists),
+		Album:   html.EscapeString(fields.Album),
+	}
+
+	b := strings.Builder{}
+	if err := body.Execute(&b, escaped); err != nil {
+		return errors.Notification.Wrap(err, "failed to write notification body")
+	}
+
+	icon := ""
+	hints := map[string]dbus.Variant{}
+
+	if coverUrl != "" {
+		n.cover.Update(coverUrl)
+
+		if n.cover.FilePath != "" {
+			icon = n.cover.FilePath
+			hints["image-path"] = dbus.MakeVariant("file://" + n.cover.FilePath)
+		}
+	}
+
+	if n.conn == nil {
+		conn, err := dbus.ConnectSessionBus()
+		if err != nil {
+			return errors.Notification.Wrap(err, "failed to connect to the session bus")
+		}
+		n.conn = conn
+	}
+
+	call := n.conn.Object(DBUS_NAME, DBUS_PATH).Call(DBUS_NOTIFY, 0,
+		config.APPNAME, n.id, icon, s.String(), b.String(), []string{}, hints, EXPIRE_DEFAULT)
+
+	if err := call.Store(&n.id); err != nil {
+		// Connects again next time, should the bus have restarted.
+		n.conn.Close()
+		n.conn = nil
+
+		return errors.Notification.Wrap(err, "failed to send notification")
+	}
+
+	return nil
+}
@@ -27,9 +27,7 @@ type State struct {
 	ShuffleState bool    `json:"shuffle_state"`
 	RepeatState  string  `json:"repeat_state"`
 
-	Context struct {
-		Type string `json:"type"`
-	} `json:"context"`
+	Context Context `json:"context"`
 
 	// Each State will have either a nil track or episode,
 	// depending on what the user is playing.
@@ -41,6 +39,29 @@ type State struct {
 	Item interface{} `json:"item"`
 }
 
+// The album, playlist or artist being played from,
+// empty if the track was played on its own.
+type Context struct {
+	Type string `json:"type"`
+	Uri  string `json:"uri"`
+}
+
+// Returns a copy of the state that can be changed without changing s,
+// such as to show a change before spotify reports it. Nil if s is nil.
+func (s *State) Clone() *State {
+	if s == nil {
+		return nil
+	}
+
+	c := *s
+	if s.Device != nil {
+		d := *s.Device
+		c.Device = &d
+	}
+
+	return &c
+}
+
 func (p *Player) State(s *auth.Session) (*State, error) {
 	ps := &State{}
 
@@ -0,0 +1,306 @@
+package player
+
+import (
+	"sync"
+	"time"
+
+	"github.com/dionvu/spogo/config"
+	"github.com/dionvu/spogo/err"
+	"github.com/dionvu/spogo/spotify"
+	"github.com/dionvu/spogo/spotify/auth"
+	"github.com/joomcode/errorx"
+)
+
+const (
+	// How far progress can be from where it is expected to be, given
+	// the time since the last state, before it is counted as a seek.
+	SEEK_TOLERANCE_MS = 3000
+
+	// Events not yet received are dropped once a subscriber
+	// falls this far behind, rather than holding up the others.
+	SUBSCRIBER_BUFFER = 64
+)
+
+// A change in the player's state, found by comparing two consecutive
+// states. Each event is one of the types below.
+type Event interface {
+	Changed() Change
+}
+
+// What every event has: the state after the change, nil if there is
+// no longer an active device, and when the change was seen. The state
+// is shared by every subscriber, so it must not be changed.
+type Change struct {
+	State *State
+	Time  time.Time
+}
+
+func (c Change) Changed() Change {
+	return c
+}
+
+// Sent when a different track, or no track, starts playing.
+type TrackChanged struct {
+	Change
+	Previous *spotify.Track
+	Track    *spotify.Track
+}
+
+type PlaybackStarted struct {
+	Change
+}
+
+// Sent when playback is paused, or stops with the device closing.
+type PlaybackPaused struct {
+	Change
+}
+
+// Sent when the track moves to a position other than where it would
+// be from playing, FromMs being where it was expected to be.
+type Seeked struct {
+	Change
+	FromMs int
+	ToMs   int
+}
+
+type VolumeChanged struct {
+	Change
+	Previous int
+	Volume   int
+}
+
+// Sent when playback moves to another device, or when the device
+// becomes active or inactive, in which case Previous or Device is nil.
+type DeviceChanged struct {
+	Change
+	Previous *Device
+	Device   *Device
+}
+
+type ShuffleChanged struct {
+	Change
+	Shuffle bool
+}
+
+type RepeatChanged struct {
+	Change
+	Previous string
+	Repeat   string
+}
+
+type ContextChanged struct {
+	Change
+	Previous Context
+	Context  Context
+}
+
+// Returns the events that take the player from prev to cur, states
+// elapsed apart, either being nil when there was no active device.
+// Shuffle, repeat and volume are only compared between two states,
+// the rest also against no state, such as the first state seen.
+func Diff(prev *State, cur *State, elapsed time.Duration, at time.Time) []Event {
+	change := Change{State: cur, Time: at}
+	events := []Event{}
+
+	prevDevice, curDevice := deviceOf(prev), deviceOf(cur)
+
+	switch {
+	case deviceID(prevDevice) != deviceID(curDevice):
+		events = append(events, DeviceChanged{Change: change, Previous: prevDevice, Device: curDevice})
+	case prevDevice != nil && curDevice != nil && prevDevice.VolumePercent != curDevice.VolumePercent:
+		events = append(events, VolumeChanged{Change: change, Previous: prevDevice.VolumePercent, Volume: curDevice.VolumePercent})
+	}
+
+	if prev != nil && cur != nil {
+		if prev.ShuffleState != cur.ShuffleState {
+			events = append(events, ShuffleChanged{Change: change, Shuffle: cur.ShuffleState})
+		}
+		if prev.RepeatState != cur.RepeatState {
+			events = append(events, RepeatChanged{Change: change, Previous: prev.RepeatState, Repeat: cur.RepeatState})
+		}
+	}
+
+	if contextOf(prev) != contextOf(cur) {
+		events = append(events, ContextChanged{Change: change, Previous: contextOf(prev), Context: contextOf(cur)})
+	}
+
+	prevTrack, curTrack := trackOf(prev), trackOf(cur)
+	trackChanged := trackID(prevTrack) != trackID(curTrack)
+
+	if trackChanged {
+		events = append(events, TrackChanged{Change: change, Previous: prevTrack, Track: curTrack})
+	}
+
+	wasPlaying, isPlaying := prev != nil && prev.IsPlaying, cur != nil && cur.IsPlaying
+
+	switch {
+	case !wasPlaying && isPlaying:
+		events = append(events, PlaybackStarted{Change: change})
+	case wasPlaying && !isPlaying:
+		events = append(events, PlaybackPaused{Change: change})
+	}
+
+	// Where the track would be from playing isn't known if playback
+	// started or paused between the states.
+	if !trackChanged && prev != nil && cur != nil && wasPlaying == isPlaying {
+		expected := prev.ProgressMs
+		if wasPlaying {
+			expected += int(elapsed.Milliseconds())
+		}
+
+		if diff := cur.ProgressMs - expected; diff > SEEK_TOLERANCE_MS || diff < -SEEK_TOLERANCE_MS {
+			events = append(events, Seeked{Change: change, FromMs: expected, ToMs: cur.ProgressMs})
+		}
+	}
+
+	return events
+}
+
+func deviceOf(s *State) *Device {
+	if s == nil {
+		return nil
+	}
+	return s.Device
+}
+
+func deviceID(d *Device) string {
+	if d == nil {
+		return ""
+	}
+	return d.ID
+}
+
+func trackOf(s *State) *spotify.Track {
+	if s == nil {
+		return nil
+	}
+	return s.Track
+}
+
+func trackID(t *spotify.Track) string {
+	if t == nil {
+		return ""
+	}
+	return t.ID
+}
+
+func contextOf(s *State) Context {
+	if s == nil {
+		return Context{}
+	}
+	return s.Context
+}
+
+// Polls the player's state, sending subscribers the events found
+// between each state and the last. The latest state is kept for
+// anything needing it between polls, such as the tui.
+type Watcher struct {
+	player  *Player
+	session *auth.Session
+
+	// Held while polling, so states are compared in the order polled.
+	polling sync.Mutex
+
+	mu          sync.Mutex
+	state       *State
+	polledAt    time.Time
+	sequence    int
+	subscribers []chan Event
+}
+
+func NewWatcher(p *Player, s *auth.Session) *Watcher {
+	return &Watcher{player: p, session: s}
+}
+
+// Returns a channel receiving every event from now on,
+// until it is passed to Unsubscribe.
+func (w *Watcher) Subscribe() <-chan Event {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	ch := make(chan Event, SUBSCRIBER_BUFFER)
+	w.subscribers = append(w.subscribers, ch)
+
+	return ch
+}
+
+// Stops sending events to the channel, and closes it.
+func (w *Watcher) Unsubscribe(ch <-chan Event) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for i, sub := range w.subscribers {
+		if sub == ch {
+			w.subscribers = append(w.subscribers[:i], w.subscribers[i+1:]...)
+			close(sub)
+			return
+		}
+	}
+}
+
+// Returns a copy of the latest state, nil if there is no active device,
+// and a number that changes whenever a new state is polled.
+func (w *Watcher) Latest() (*State, int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.state.Clone(), w.sequence
+}
+
+// Gets the player's state now, sending subscribers the events since
+// the last. No active device counts as a state, nil, but on any other
+// error the last state is kept and the error is returned.
+func (w *Watcher) Poll() (*State, error) {
+	w.polling.Lock()
+	defer w.polling.Unlock()
+
+	state, err := w.player.State(w.session)
+	if err != nil && !errorx.IsOfType(err, errors.NoDevice) {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	events := Diff(w.state, state, now.Sub(w.polledAt), now)
+
+	w.state = state
+	w.polledAt = now
+	w.sequence++
+
+	for _, event := range events {
+		for _, sub := range w.subscribers {
+			select {
+			case sub <- event:
+			default:
+				errors.Log(errors.PlayerEvent.New("dropped player event, subscriber is behind"))
+			}
+		}
+	}
+
+	return state.Clone(), nil
+}
+
+// Polls every POLLING_RATE_STATE_SEC until stop is closed, signing in
+// again when the state can't be polled. Returns the error if signing
+// in fails.
+func (w *Watcher) Run(c *config.Config, stop <-chan struct{}) error {
+	ticker := time.NewTicker(POLLING_RATE_STATE_SEC)
+	defer ticker.Stop()
+
+	for {
+		if _, err := w.Poll(); err != nil {
+			if err := w.session.Reauth(c); err != nil {
+				return err
+			}
+		}
+
+		select {
+		case <-stop:
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
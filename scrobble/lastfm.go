@@ -0,0 +1,218 @@
+package scrobble
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/dionvu/spogo/config"
+	"github.com/dionvu/spogo/err"
+)
+
+const (
+	SERVICE_LASTFM = "lastfm"
+
+	// The most scrobbles Last.fm takes in one request.
+	LASTFM_BATCH = 50
+
+	// Errors of a Last.fm response for requests it won't
+	// take however often they're sent.
+	LASTFM_INVALID_PARAMETERS = 6
+)
+
+// Submits scrobbles to Last.fm, or any service with the same api at
+// its url, signing each request with the api account's secret.
+type LastFM struct {
+	options config.LastFM
+}
+
+func NewLastFM(o config.LastFM) *LastFM {
+	return &LastFM{options: o}
+}
+
+func (fm *LastFM) Name() string {
+	return SERVICE_LASTFM
+}
+
+func (fm *LastFM) BatchSize() int {
+	return LASTFM_BATCH
+}
+
+func (fm *LastFM) NowPlaying(l Listen) error {
+	params := url.Values{}
+	params.Set("artist", l.Artist())
+	params.Set("track", l.Track)
+	params.Set("duration", fmt.Sprint(l.DurationMs/1000))
+	if l.Album != "" {
+		params.Set("album", l.Album)
+	}
+
+	return fm.call("track.updateNowPlaying", params, true, nil)
+}
+
+func (fm *LastFM) Submit(listens []Listen) error {
+	params := url.Values{}
+
+	for i, l := range listens {
+		params.Set(fmt.Sprintf("artist[%d]", i), l.Artist())
+		params.Set(fmt.Sprintf("track[%d]", i), l.Track)
+		params.Set(fmt.Sprintf("duration[%d]", i), fmt.Sprint(l.DurationMs/1000))
+		params.Set(fmt.Sprintf("timestamp[%d]", i), fmt.Sprint(l.StartedAt.Unix()))
+		if l.Album != "" {
+			params.Set(fmt.Sprintf("album[%d]", i), l.Album)
+		}
+	}
+
+	var res struct {
+		Scrobbles struct {
+			Attr struct {
+				Ignored int `json:"ignored"`
+			} `json:"@attr"`
+		} `json:"scrobbles"`
+	}
+
+	if err := fm.call("track.scrobble", params, true, &res); err != nil {
+		return err
+	}
+
+	// Ignored scrobbles, such as those of artists it doesn't know,
+	// are only reported, as sending them again won't change that.
+	if ignored := res.Scrobbles.Attr.Ignored; ignored > 0 {
+		errors.Log(errors.ScrobbleRejected.New("lastfm ignored %d of %d scrobbles", ignored, len(listens)))
+	}
+
+	return nil
+}
+
+// Asks for a token the user then allows to scrobble for
+// them, at the url returned by AuthUrl.
+func (fm *LastFM) Token() (string, error) {
+	var res struct {
+		Token string `json:"token"`
+	}
+
+	err := fm.call("auth.getToken", url.Values{}, false, &res)
+
+	return res.Token, err
+}
+
+// The page where the user allows the api account to scrobble for them.
+func (fm *LastFM) AuthUrl(token string) string {
+	query := url.Values{}
+	query.Set("api_key", fm.options.ApiKey)
+	query.Set("token", token)
+
+	return fm.options.AuthUrl + "?" + query.Encode()
+}
+
+// Exchanges a token the user allowed for the session key scrobbles
+// are sent with, returning it with the name of the user.
+func (fm *LastFM) Session(token string) (name string, key string, err error) {
+	params := url.Values{}
+	params.Set("token", token)
+
+	var res struct {
+		Session struct {
+			Name string `json:"name"`
+			Key  string `json:"key"`
+		} `json:"session"`
+	}
+
+	err = fm.call("auth.getSession", params, false, &res)
+
+	return res.Session.Name, res.Session.Key, err
+}
+
+// Posts the signed method call, decoding the response into out
+// if given. Calls for the user need the session key.
+func (fm *LastFM) call(method string, params url.Values, session bool, out any) error {
+	params.Set("method", method)
+	params.Set("api_key", fm.options.ApiKey)
+	if session {
+		params.Set("sk", fm.options.SessionKey)
+	}
+	params.Set("api_sig", fm.sign(params))
+	params.Set("format", "json")
+
+	req, err := http.NewRequest(http.MethodPost, fm.options.Url, strings.NewReader(params.Encode()))
+	if err != nil {
+		err = errors.HTTPRequest.Wrap(err, "failed to make request to lastfm")
+		errors.Log(err)
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	res, err := client.Do(req)
+	if err != nil {
+		err = errors.Scrobble.Wrap(err, "failed to reach lastfm")
+		errors.Log(err)
+		return err
+	}
+	defer res.Body.Close()
+
+	errors.LogApiCall(fm.options.Url+" "+method, res.StatusCode)
+
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		err = errors.Scrobble.Wrap(err, "failed to read lastfm response")
+		errors.Log(err)
+		return err
+	}
+
+	var failed struct {
+		Error   int    `json:"error"`
+		Message string `json:"message"`
+	}
+	json.Unmarshal(b, &failed)
+
+	switch {
+	case failed.Error == LASTFM_INVALID_PARAMETERS:
+		err = errors.ScrobbleRejected.New("lastfm rejected %s: %s", method, failed.Message)
+	case failed.Error != 0:
+		err = errors.Scrobble.New("lastfm failed %s, error %d: %s", method, failed.Error, failed.Message)
+	case res.StatusCode >= http.StatusBadRequest:
+		err = errors.Scrobble.New("lastfm responded %s to %s", res.Status, method)
+	}
+
+	if err != nil {
+		errors.Log(err)
+		return err
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	if err := json.Unmarshal(b, out); err != nil {
+		err = errors.JSONUnmarshal.Wrap(err, "failed to unmarshal lastfm response")
+		errors.Log(err)
+		return err
+	}
+
+	return nil
+}
+
+// The md5 of every parameter, sorted by name, followed by the secret.
+func (fm *LastFM) sign(params url.Values) string {
+	names := []string{}
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	b := strings.Builder{}
+	for _, name := range names {
+		b.WriteString(name + params.Get(name))
+	}
+	b.WriteString(fm.options.Secret)
+
+	sum := md5.Sum([]byte(b.String()))
+
+	return hex.EncodeToString(sum[:])
+}
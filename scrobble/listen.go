@@ -0,0 +1,68 @@
+package scrobble
+
+import (
+	"time"
+
+	"github.com/dionvu/spogo/history"
+	"github.com/dionvu/spogo/spotify"
+)
+
+const (
+	// Shorter tracks aren't scrobbled.
+	MIN_TRACK_MS = 30000
+
+	// Tracks are scrobbled once listened to for half their
+	// length, or for this long if sooner.
+	SCROBBLE_AFTER_MS = 240000
+)
+
+// A track listened to, as submitted to scrobbling services.
+type Listen struct {
+	TrackID    string    `json:"track_id"`
+	Track      string    `json:"track"`
+	Artists    []string  `json:"artists"`
+	Album      string    `json:"album"`
+	DurationMs int       `json:"duration_ms"`
+	StartedAt  time.Time `json:"started_at"`
+}
+
+// Returns true if the play was long enough to be scrobbled.
+func Eligible(p history.Play) bool {
+	return p.DurationMs > MIN_TRACK_MS && p.ListenedMs >= min(SCROBBLE_AFTER_MS, p.DurationMs/2)
+}
+
+func FromPlay(p history.Play) Listen {
+	return Listen{
+		TrackID:    p.TrackID,
+		Track:      p.Name,
+		Artists:    p.Artists,
+		Album:      p.Album,
+		DurationMs: p.DurationMs,
+		StartedAt:  p.StartedAt,
+	}
+}
+
+func FromTrack(t *spotify.Track, startedAt time.Time) Listen {
+	l := Listen{
+		TrackID:    t.ID,
+		Track:      t.Name,
+		Artists:    []string{},
+		Album:      t.Album.Name,
+		DurationMs: t.DurationMs,
+		StartedAt:  startedAt,
+	}
+
+	for _, a := range t.Artists {
+		l.Artists = append(l.Artists, a.Name)
+	}
+
+	return l
+}
+
+// The first artist, which services taking a single artist are given.
+func (l Listen) Artist() string {
+	if len(l.Artists) == 0 {
+		return ""
+	}
+	return l.Artists[0]
+}
@@ -0,0 +1,152 @@
+package scrobble
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/dionvu/spogo/config"
+	"github.com/dionvu/spogo/err"
+)
+
+const (
+	SERVICE_LISTENBRAINZ = "listenbrainz"
+
+	// The most listens ListenBrainz takes in one request.
+	LISTENBRAINZ_BATCH = 100
+
+	LISTENBRAINZ_SUBMIT = "/1/submit-listens"
+
+	LISTEN_TYPE_SINGLE      = "single"
+	LISTEN_TYPE_IMPORT      = "import"
+	LISTEN_TYPE_PLAYING_NOW = "playing_now"
+)
+
+// Submits listens with a user token to ListenBrainz,
+// or any service with the same api at its url.
+type ListenBrainz struct {
+	url   string
+	token string
+}
+
+func NewListenBrainz(o config.ListenBrainz) *ListenBrainz {
+	return &ListenBrainz{url: strings.TrimSuffix(o.Url, "/"), token: o.Token}
+}
+
+func (lb *ListenBrainz) Name() string {
+	return SERVICE_LISTENBRAINZ
+}
+
+func (lb *ListenBrainz) BatchSize() int {
+	return LISTENBRAINZ_BATCH
+}
+
+func (lb *ListenBrainz) NowPlaying(l Listen) error {
+	return lb.submit(LISTEN_TYPE_PLAYING_NOW, []Listen{l})
+}
+
+func (lb *ListenBrainz) Submit(listens []Listen) error {
+	if len(listens) == 1 {
+		return lb.submit(LISTEN_TYPE_SINGLE, listens)
+	}
+	return lb.submit(LISTEN_TYPE_IMPORT, listens)
+}
+
+type lbListen struct {
+	ListenedAt int64 `json:"listened_at,omitempty"`
+
+	TrackMetadata struct {
+		ArtistName     string `json:"artist_name"`
+		TrackName      string `json:"track_name"`
+		ReleaseName    string `json:"release_name,omitempty"`
+		AdditionalInfo struct {
+			ArtistNames      []string `json:"artist_names"`
+			DurationMs       int      `json:"duration_ms,omitempty"`
+			SpotifyID        string   `json:"spotify_id,omitempty"`
+			OriginUrl        string   `json:"origin_url,omitempty"`
+			MediaPlayer      string   `json:"media_player"`
+			SubmissionClient string   `json:"submission_client"`
+			MusicService     string   `json:"music_service"`
+		} `json:"additional_info"`
+	} `json:"track_metadata"`
+}
+
+func (lb *ListenBrainz) submit(listenType string, listens []Listen) error {
+	payload := []lbListen{}
+
+	for _, l := range listens {
+		item := lbListen{}
+
+		// Listens playing now have no time yet.
+		if listenType != LISTEN_TYPE_PLAYING_NOW {
+			item.ListenedAt = l.StartedAt.Unix()
+		}
+
+		m := &item.TrackMetadata
+		m.ArtistName = strings.Join(l.Artists, ", ")
+		m.TrackName = l.Track
+		m.ReleaseName = l.Album
+
+		info := &m.AdditionalInfo
+		info.ArtistNames = l.Artists
+		info.DurationMs = l.DurationMs
+		info.MediaPlayer = "Spotify"
+		info.SubmissionClient = config.APPNAME
+		info.MusicService = "spotify.com"
+
+		if l.TrackID != "" {
+			info.SpotifyID = "https://open.spotify.com/track/" + l.TrackID
+			info.OriginUrl = info.SpotifyID
+		}
+
+		payload = append(payload, item)
+	}
+
+	b, err := json.Marshal(map[string]any{"listen_type": listenType, "payload": payload})
+	if err != nil {
+		err = errors.JSONMarshal.Wrap(err, "failed to marshal listens")
+		errors.Log(err)
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, lb.url+LISTENBRAINZ_SUBMIT, bytes.NewReader(b))
+	if err != nil {
+		err = errors.HTTPRequest.Wrap(err, "failed to make request to submit listens")
+		errors.Log(err)
+		return err
+	}
+	req.Header.Set("Authorization", "Token "+lb.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := client.Do(req)
+	if err != nil {
+		err = errors.Scrobble.Wrap(err, "failed to reach listenbrainz")
+		errors.Log(err)
+		return err
+	}
+	defer res.Body.Close()
+
+	errors.LogApiCall(lb.url+LISTENBRAINZ_SUBMIT, res.StatusCode)
+
+	if res.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	var body struct {
+		Error string `json:"error"`
+	}
+	json.NewDecoder(io.LimitReader(res.Body, MAX_ERROR_BYTES)).Decode(&body)
+
+	// Bad requests are listens it won't take however often they're sent,
+	// unlike a wrong token or the service being down.
+	if res.StatusCode == http.StatusBadRequest {
+		err = errors.ScrobbleRejected.New("listenbrainz rejected the listens: %s", body.Error)
+	} else {
+		err = errors.Scrobble.New("listenbrainz responded %s: %s", res.Status, body.Error)
+	}
+
+	errors.Log(err)
+	return err
+}
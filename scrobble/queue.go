@@ -0,0 +1,176 @@
+package scrobble
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/dionvu/spogo/config"
+	"github.com/dionvu/spogo/err"
+	bolt "go.etcd.io/bbolt"
+)
+
+// How long to wait for another spogo writing to the queue.
+const QUEUE_LOCK_TIMEOUT = time.Second * 2
+
+// A listen waiting to be submitted, by its place in the queue.
+type Queued struct {
+	Key    uint64 `json:"key"`
+	Listen Listen `json:"listen"`
+}
+
+// The listens yet to be submitted, in "scrobbles.db" in the cache
+// directory, with a bucket for each service. Like the play history,
+// the database is only opened while it is read or written.
+type Queue struct {
+	path string
+}
+
+func NewQueue(c *config.Config) *Queue {
+	return &Queue{path: c.ScrobbleFile()}
+}
+
+// Queues the listen for each of the services.
+func (q *Queue) Push(services []string, l Listen) error {
+	value, err := json.Marshal(l)
+	if err != nil {
+		err = errors.JSONMarshal.Wrap(err, "failed to marshal listen")
+		errors.Log(err)
+		return err
+	}
+
+	return q.update(func(tx *bolt.Tx) error {
+		for _, service := range services {
+			b, err := tx.CreateBucketIfNotExists([]byte(service))
+			if err != nil {
+				return err
+			}
+
+			key, err := b.NextSequence()
+			if err != nil {
+				return err
+			}
+
+			if err := b.Put(queueKey(key), value); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Returns up to n of the oldest listens queued for the service.
+func (q *Queue) Peek(service string, n int) ([]Queued, error) {
+	queued := []Queued{}
+
+	err := q.view(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(service))
+		if b == nil {
+			return nil
+		}
+
+		c := b.Cursor()
+		for k, v := c.First(); k != nil && len(queued) < n; k, v = c.Next() {
+			l := Listen{}
+			if err := json.Unmarshal(v, &l); err != nil {
+				return errors.JSONUnmarshal.Wrap(err, "failed to unmarshal listen")
+			}
+
+			queued = append(queued, Queued{Key: binary.BigEndian.Uint64(k), Listen: l})
+		}
+
+		return nil
+	})
+
+	return queued, err
+}
+
+// Removes submitted listens from the service's queue.
+func (q *Queue) Remove(service string, queued ...Queued) error {
+	return q.update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(service))
+		if b == nil {
+			return nil
+		}
+
+		for _, item := range queued {
+			if err := b.Delete(queueKey(item.Key)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Returns the listens queued for every service, oldest first.
+func (q *Queue) Pending() (map[string][]Queued, error) {
+	pending := map[string][]Queued{}
+
+	err := q.view(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+			return b.ForEach(func(k, v []byte) error {
+				l := Listen{}
+				if err := json.Unmarshal(v, &l); err != nil {
+					return errors.JSONUnmarshal.Wrap(err, "failed to unmarshal listen")
+				}
+
+				pending[string(name)] = append(pending[string(name)], Queued{Key: binary.BigEndian.Uint64(k), Listen: l})
+				return nil
+			})
+		})
+	})
+
+	return pending, err
+}
+
+func queueKey(key uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, key)
+	return b
+}
+
+func (q *Queue) update(fn func(*bolt.Tx) error) error {
+	db, err := q.open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := db.Update(fn); err != nil {
+		err = errors.FileWrite.Wrap(err, fmt.Sprintf("failed to write scrobble queue: %v", q.path))
+		errors.Log(err)
+		return err
+	}
+
+	return nil
+}
+
+func (q *Queue) view(fn func(*bolt.Tx) error) error {
+	db, err := q.open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := db.View(fn); err != nil {
+		err = errors.FileRead.Wrap(err, fmt.Sprintf("failed to read scrobble queue: %v", q.path))
+		errors.Log(err)
+		return err
+	}
+
+	return nil
+}
+
+func (q *Queue) open() (*bolt.DB, error) {
+	db, err := bolt.Open(q.path, 0600, &bolt.Options{Timeout: QUEUE_LOCK_TIMEOUT})
+	if err != nil {
+		err = errors.FileOpen.Wrap(err, fmt.Sprintf("failed to open scrobble queue: %v", q.path))
+		errors.Log(err)
+		return nil, err
+	}
+
+	return db, nil
+}
@@ -0,0 +1,272 @@
+package scrobble
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/dionvu/spogo/config"
+	"github.com/dionvu/spogo/err"
+	"github.com/dionvu/spogo/history"
+	"github.com/dionvu/spogo/player"
+)
+
+const (
+	REQUEST_TIMEOUT = time.Second * 15
+
+	// Services failing are retried after waiting this long,
+	// twice as long after each failure up to the most.
+	RETRY_MIN_WAIT = time.Second * 30
+	RETRY_MAX_WAIT = time.Hour
+
+	// How often the queue is checked for listens to retry.
+	RETRY_CHECK_RATE = time.Second * 30
+
+	// Only the start of error responses is read.
+	MAX_ERROR_BYTES = 4096
+)
+
+var client = &http.Client{Timeout: REQUEST_TIMEOUT}
+
+// A scrobbling service listens are submitted to.
+type Service interface {
+	Name() string
+
+	// The most listens submitted at once.
+	BatchSize() int
+
+	NowPlaying(l Listen) error
+	Submit(listens []Listen) error
+}
+
+// Returns the services enabled by the options.
+func Services(o config.Scrobbling) []Service {
+	services := []Service{}
+
+	if o.ListenBrainz.Enabled {
+		services = append(services, NewListenBrainz(o.ListenBrainz))
+	}
+
+	if o.LastFM.Enabled {
+		services = append(services, NewLastFM(o.LastFM))
+	}
+
+	return services
+}
+
+// When a failing service is next tried.
+type retry struct {
+	at   time.Time
+	wait time.Duration
+}
+
+// Sends the track playing to the enabled services, and queues every
+// play long enough to be scrobbled, sending the queue in the background.
+type Scrobbler struct {
+	queue *Queue
+
+	// Reports while another spogo sends the queue instead.
+	standby func() bool
+
+	mu       sync.Mutex
+	services []Service
+	retries  map[string]retry
+
+	wake chan struct{}
+	stop chan struct{}
+	done chan struct{}
+}
+
+// Starts sending the queue, until stopped.
+func New(c *config.Config, standby func() bool) *Scrobbler {
+	s := &Scrobbler{
+		queue:   NewQueue(c),
+		standby: standby,
+		retries: map[string]retry{},
+		wake:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	s.Update(c.Scrobbling)
+
+	go s.run()
+
+	return s
+}
+
+// Replaces the options, such as when "config.yaml" is changed,
+// trying every service again straight away.
+func (s *Scrobbler) Update(o config.Scrobbling) {
+	s.mu.Lock()
+	s.services = Services(o)
+	s.retries = map[string]retry{}
+	s.mu.Unlock()
+
+	s.notify()
+}
+
+// Sends the track as playing now, when it changes or is resumed.
+func (s *Scrobbler) Handle(e player.Event) {
+	switch e.(type) {
+	case player.TrackChanged, player.PlaybackStarted:
+	default:
+		return
+	}
+
+	state := e.Changed().State
+	if state == nil || state.Track == nil || !state.IsPlaying || state.Track.DurationMs <= MIN_TRACK_MS {
+		return
+	}
+
+	l := FromTrack(state.Track, e.Changed().Time)
+
+	s.mu.Lock()
+	services := s.services
+	s.mu.Unlock()
+
+	// Playing now is only worth sending as it happens, so it isn't
+	// queued, and errors are logged by the services.
+	for _, service := range services {
+		go service.NowPlaying(l)
+	}
+}
+
+// Queues the play if it was long enough to be scrobbled.
+func (s *Scrobbler) Scrobble(p history.Play) {
+	if !Eligible(p) {
+		return
+	}
+
+	s.mu.Lock()
+	names := []string{}
+	for _, service := range s.services {
+		names = append(names, service.Name())
+	}
+	s.mu.Unlock()
+
+	if len(names) == 0 {
+		return
+	}
+
+	// Errors are logged by Push.
+	if err := s.queue.Push(names, FromPlay(p)); err == nil {
+		s.notify()
+	}
+}
+
+// Stops sending the queue, leaving what's left of it for next time.
+func (s *Scrobbler) Stop() {
+	close(s.stop)
+	<-s.done
+}
+
+func (s *Scrobbler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Scrobbler) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(RETRY_CHECK_RATE)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-s.wake:
+		case <-ticker.C:
+		}
+
+		if s.standby() {
+			continue
+		}
+
+		s.mu.Lock()
+		services := s.services
+		s.mu.Unlock()
+
+		for _, service := range services {
+			s.send(service)
+		}
+	}
+}
+
+// Sends the service's queue, unless it is waiting to be retried,
+// backing off further if it fails again.
+func (s *Scrobbler) send(service Service) {
+	name := service.Name()
+
+	s.mu.Lock()
+	r := s.retries[name]
+	s.mu.Unlock()
+
+	if time.Now().Before(r.at) {
+		return
+	}
+
+	err := Send(s.queue, service, s.stop)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err == nil {
+		delete(s.retries, name)
+		return
+	}
+
+	r.wait = min(max(r.wait*2, RETRY_MIN_WAIT), RETRY_MAX_WAIT)
+	r.at = time.Now().Add(r.wait)
+	s.retries[name] = r
+}
+
+// Submits the service's queue in batches until it is empty, returning
+// the error of a batch that failed, which stays queued. Listens the
+// service rejects are dropped. Stops early once stop is closed, if given.
+func Send(q *Queue, service Service, stop <-chan struct{}) error {
+	for {
+		select {
+		case <-stop:
+			return nil
+		default:
+		}
+
+		queued, err := q.Peek(service.Name(), service.BatchSize())
+		if err != nil || len(queued) == 0 {
+			return err
+		}
+
+		listens := []Listen{}
+		for _, item := range queued {
+			listens = append(listens, item.Listen)
+		}
+
+		err = service.Submit(listens)
+
+		switch {
+		case err == nil:
+		case !errors.IsScrobbleRejectedErr(err):
+			return err
+
+		// Sends the listens of a rejected batch one by one,
+		// only dropping those rejected again.
+		case len(queued) > 1:
+			for i, item := range queued {
+				err := service.Submit([]Listen{item.Listen})
+				if err != nil && !errors.IsScrobbleRejectedErr(err) {
+					// Keeps the listens not yet sent.
+					q.Remove(service.Name(), queued[:i]...)
+					return err
+				}
+			}
+		}
+
+		if err := q.Remove(service.Name(), queued...); err != nil {
+			return err
+		}
+	}
+}
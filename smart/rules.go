@@ -0,0 +1,397 @@
+package smart
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dionvu/spogo/err"
+	"github.com/dionvu/spogo/spotify"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	SOURCE_LIKED    = "liked"
+	SOURCE_PLAYLIST = "playlist:"
+
+	SORT_ADDED    = "added"
+	SORT_NAME     = "name"
+	SORT_ARTIST   = "artist"
+	SORT_RELEASED = "released"
+	SORT_DURATION = "duration"
+
+	ORDER_ASC  = "asc"
+	ORDER_DESC = "desc"
+)
+
+// The contents of "smart-playlists.yaml".
+type File struct {
+	Playlists []Definition `yaml:"playlists"`
+}
+
+// A smart playlist declaration. The tracks of every source are
+// gathered, filtered by every rule, sorted, and then synced into
+// the target playlist.
+type Definition struct {
+	// Used to name the target playlist when it has to be created.
+	Name string `yaml:"name"`
+
+	// The id or uri of the playlist the result is synced into. If
+	// empty, a playlist owned by the user named Name is used, and
+	// created if it doesn't exist.
+	Target string `yaml:"target"`
+
+	// Either "liked" for the user's "Liked Songs", or
+	// "playlist:<id or uri>".
+	Sources []string `yaml:"sources"`
+
+	// A track must satisfy every rule to be included.
+	Rules []Rule `yaml:"rules"`
+
+	Sort struct {
+		By    string `yaml:"by"`
+		Order string `yaml:"order"`
+	} `yaml:"sort"`
+
+	// The maximum number of tracks, 0 for no limit.
+	Limit int `yaml:"limit"`
+}
+
+// A single rule, a track must satisfy every field that is set.
+type Rule struct {
+	// How recently the track was added to its source,
+	// for example "30d", "2w" or "12h".
+	AddedWithin string `yaml:"added_within"`
+
+	// Artist names or ids, at least one of the track's
+	// artists must be present.
+	Artists []string `yaml:"artists"`
+
+	// Artist names or ids, none of the track's artists
+	// may be present.
+	ExcludeArtists []string `yaml:"exclude_artists"`
+
+	// Playlist ids or uris whose tracks are excluded.
+	ExcludePlaylists []string `yaml:"exclude_playlists"`
+
+	// Inclusive album release year range.
+	ReleasedAfter  int `yaml:"released_after"`
+	ReleasedBefore int `yaml:"released_before"`
+
+	// Track duration bounds, for example "2m" or "90s".
+	MinDuration string `yaml:"min_duration"`
+	MaxDuration string `yaml:"max_duration"`
+}
+
+// A track considered by the rule engine, along with
+// the time it was added to the source it came from.
+type Item struct {
+	Track   spotify.Track
+	AddedAt time.Time
+}
+
+// Returns true if the item should be kept.
+type Filter func(Item) bool
+
+// Reads every smart playlist definition from the given file.
+func Load(path string) (*File, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		err = errors.FileRead.Wrap(err, fmt.Sprintf("failed to read smart playlist file: %v", path))
+		errors.Log(err)
+		return nil, err
+	}
+
+	f := &File{}
+
+	if err = yaml.Unmarshal(b, f); err != nil {
+		err = errors.YAML.Wrap(err, fmt.Sprintf("failed to unmarshal smart playlist file: %v", path))
+		errors.Log(err)
+		return nil, err
+	}
+
+	for _, def := range f.Playlists {
+		if err := def.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return f, nil
+}
+
+// Ensures the definition can be evaluated without
+// contacting spotify.
+func (d Definition) Validate() error {
+	if d.Name == "" && d.Target == "" {
+		return errors.SmartPlaylistRule.New("smart playlist requires a name or a target")
+	}
+
+	if len(d.Sources) == 0 {
+		return errors.SmartPlaylistRule.New("smart playlist %q has no sources", d.Name)
+	}
+
+	for _, src := range d.Sources {
+		if src != SOURCE_LIKED && !strings.HasPrefix(src, SOURCE_PLAYLIST) {
+			return errors.SmartPlaylistRule.New("smart playlist %q has unknown source %q", d.Name, src)
+		}
+	}
+
+	switch d.Sort.By {
+	case "", SORT_ADDED, SORT_NAME, SORT_ARTIST, SORT_RELEASED, SORT_DURATION:
+	default:
+		return errors.SmartPlaylistRule.New("smart playlist %q has unknown sort %q", d.Name, d.Sort.By)
+	}
+
+	switch d.Sort.Order {
+	case "", ORDER_ASC, ORDER_DESC:
+	default:
+		return errors.SmartPlaylistRule.New("smart playlist %q has unknown sort order %q", d.Name, d.Sort.Order)
+	}
+
+	for _, r := range d.Rules {
+		for _, s := range []string{r.AddedWithin, r.MinDuration, r.MaxDuration} {
+			if s == "" {
+				continue
+			}
+			if _, err := ParseAge(s); err != nil {
+				return errors.SmartPlaylistRule.Wrap(err, "smart playlist %q", d.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Builds the filters of the rule. Playlists that are excluded
+// are fetched through the library.
+func (r Rule) Filters(lib *Library, now time.Time) ([]Filter, error) {
+	filters := []Filter{}
+
+	if r.AddedWithin != "" {
+		age, err := ParseAge(r.AddedWithin)
+		if err != nil {
+			return nil, err
+		}
+
+		since := now.Add(-age)
+		filters = append(filters, func(i Item) bool {
+			return i.AddedAt.After(since)
+		})
+	}
+
+	if len(r.Artists) > 0 {
+		artists := newMatchSet(r.Artists)
+		filters = append(filters, func(i Item) bool {
+			return artists.anyArtist(i.Track.Artists)
+		})
+	}
+
+	if len(r.ExcludeArtists) > 0 {
+		artists := newMatchSet(r.ExcludeArtists)
+		filters = append(filters, func(i Item) bool {
+			return !artists.anyArtist(i.Track.Artists)
+		})
+	}
+
+	if len(r.ExcludePlaylists) > 0 {
+		excluded := map[string]bool{}
+
+		for _, p := range r.ExcludePlaylists {
+			items, err := lib.Source(SOURCE_PLAYLIST + p)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, item := range items {
+				excluded[item.Track.Uri] = true
+			}
+		}
+
+		filters = append(filters, func(i Item) bool {
+			return !excluded[i.Track.Uri]
+		})
+	}
+
+	if r.ReleasedAfter > 0 {
+		filters = append(filters, func(i Item) bool {
+			return releaseYear(i.Track) >= r.ReleasedAfter
+		})
+	}
+
+	if r.ReleasedBefore > 0 {
+		filters = append(filters, func(i Item) bool {
+			year := releaseYear(i.Track)
+			return year != 0 && year <= r.ReleasedBefore
+		})
+	}
+
+	if r.MinDuration != "" {
+		d, err := ParseAge(r.MinDuration)
+		if err != nil {
+			return nil, err
+		}
+
+		filters = append(filters, func(i Item) bool {
+			return i.Track.DurationMs >= int(d.Milliseconds())
+		})
+	}
+
+	if r.MaxDuration != "" {
+		d, err := ParseAge(r.MaxDuration)
+		if err != nil {
+			return nil, err
+		}
+
+		filters = append(filters, func(i Item) bool {
+			return i.Track.DurationMs <= int(d.Milliseconds())
+		})
+	}
+
+	return filters, nil
+}
+
+// Gathers the tracks of every source, drops duplicates, applies
+// every rule, and sorts and limits the result.
+func (d Definition) Evaluate(lib *Library, now time.Time) ([]Item, error) {
+	filters := []Filter{}
+
+	for _, r := range d.Rules {
+		f, err := r.Filters(lib, now)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f...)
+	}
+
+	seen := map[string]bool{}
+	result := []Item{}
+
+	for _, src := range d.Sources {
+		items, err := lib.Source(src)
+		if err != nil {
+			return nil, err
+		}
+
+	items:
+		for _, item := range items {
+			if seen[item.Track.Uri] {
+				continue
+			}
+
+			for _, keep := range filters {
+				if !keep(item) {
+					continue items
+				}
+			}
+
+			seen[item.Track.Uri] = true
+			result = append(result, item)
+		}
+	}
+
+	d.sort(result)
+
+	if d.Limit > 0 && len(result) > d.Limit {
+		result = result[:d.Limit]
+	}
+
+	return result, nil
+}
+
+// Sorts the items in place by the definition's sort options,
+// added date descending if none are given.
+func (d Definition) sort(items []Item) {
+	desc := d.Sort.Order == ORDER_DESC
+
+	// Newest first unless asked otherwise.
+	if (d.Sort.By == "" || d.Sort.By == SORT_ADDED) && d.Sort.Order == "" {
+		desc = true
+	}
+
+	less := func(i, j int) bool {
+		a, b := items[i], items[j]
+
+		switch d.Sort.By {
+		case SORT_NAME:
+			return strings.ToLower(a.Track.Name) < strings.ToLower(b.Track.Name)
+		case SORT_ARTIST:
+			return strings.ToLower(a.Track.ArtistsString()) < strings.ToLower(b.Track.ArtistsString())
+		case SORT_RELEASED:
+			return a.Track.Album.ReleaseDate < b.Track.Album.ReleaseDate
+		case SORT_DURATION:
+			return a.Track.DurationMs < b.Track.DurationMs
+		default:
+			return a.AddedAt.Before(b.AddedAt)
+		}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		if desc {
+			return less(j, i)
+		}
+		return less(i, j)
+	})
+}
+
+// Parses a duration such as "30d", "2w", "12h", "90s" or "3m".
+// Unlike time.ParseDuration, days and weeks are supported.
+func ParseAge(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+
+	units := map[string]time.Duration{
+		"s": time.Second,
+		"m": time.Minute,
+		"h": time.Hour,
+		"d": 24 * time.Hour,
+		"w": 7 * 24 * time.Hour,
+	}
+
+	if len(s) < 2 {
+		return 0, errors.SmartPlaylistRule.New("invalid duration %q", s)
+	}
+
+	unit, ok := units[s[len(s)-1:]]
+	if !ok {
+		return 0, errors.SmartPlaylistRule.New("invalid duration unit in %q", s)
+	}
+
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil || n < 0 {
+		return 0, errors.SmartPlaylistRule.New("invalid duration %q", s)
+	}
+
+	return time.Duration(n) * unit, nil
+}
+
+// The year of the album's release date, or 0 if unknown.
+func releaseYear(t spotify.Track) int {
+	if len(t.Album.ReleaseDate) < 4 {
+		return 0
+	}
+
+	year, _ := strconv.Atoi(t.Album.ReleaseDate[:4])
+	return year
+}
+
+// A case insensitive set of artist names and ids.
+type matchSet map[string]bool
+
+func newMatchSet(values []string) matchSet {
+	m := matchSet{}
+	for _, v := range values {
+		m[strings.ToLower(strings.TrimPrefix(v, "spotify:artist:"))] = true
+	}
+	return m
+}
+
+func (m matchSet) anyArtist(artists []spotify.Artist) bool {
+	for _, a := range artists {
+		if m[strings.ToLower(a.Name)] || m[strings.ToLower(a.ID)] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,264 @@
+package smart
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dionvu/spogo/spotify"
+	"github.com/dionvu/spogo/spotify/auth"
+	"github.com/fatih/color"
+)
+
+// Lazily fetches and caches the track sources used by smart
+// playlists, so several definitions sharing a source, such as
+// "Liked Songs", only fetch it once.
+type Library struct {
+	session *auth.Session
+	sources map[string][]Item
+}
+
+func NewLibrary(s *auth.Session) *Library {
+	return &Library{
+		session: s,
+		sources: map[string][]Item{},
+	}
+}
+
+// Returns every item of the source, fetching it if it
+// hasn't been already.
+func (lib *Library) Source(src string) ([]Item, error) {
+	if strings.HasPrefix(src, SOURCE_PLAYLIST) {
+		src = SOURCE_PLAYLIST + PlaylistID(strings.TrimPrefix(src, SOURCE_PLAYLIST))
+	}
+
+	if items, ok := lib.sources[src]; ok {
+		return items, nil
+	}
+
+	var (
+		saved []spotify.SavedTrack
+		err   error
+	)
+
+	if src == SOURCE_LIKED {
+		saved, err = spotify.SavedTracks(lib.session)
+	} else {
+		saved, err = spotify.PlaylistItems(lib.session, strings.TrimPrefix(src, SOURCE_PLAYLIST))
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]Item, len(saved))
+	for i, st := range saved {
+		items[i] = Item{Track: st.Track, AddedAt: st.AddedAt}
+	}
+
+	lib.sources[src] = items
+
+	return items, nil
+}
+
+// The changes required to make the target
+// playlist match a definition's result.
+type Plan struct {
+	Definition Definition
+
+	// The target playlist's id, empty if it will be created.
+	PlaylistID string
+
+	Add    []Item
+	Remove []Item
+
+	// Whether the tracks kept would be out of the definition's order,
+	// in which case the playlist's tracks are replaced with the result.
+	Reorder bool
+
+	// The definition's result, in order.
+	result []Item
+}
+
+// Evaluates the definition and compares the result with the
+// current contents of the target playlist. Only tracks missing from
+// the target are added and only tracks no longer matching are removed,
+// unless the playlist would then be out of the definition's order.
+func NewPlan(def Definition, lib *Library, now time.Time) (*Plan, error) {
+	result, err := def.Evaluate(lib, now)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Plan{Definition: def}
+
+	p.PlaylistID, err = lib.findTarget(def)
+	if err != nil {
+		return nil, err
+	}
+
+	current := []Item{}
+	if p.PlaylistID != "" {
+		// Always refetch, the target may also be a source.
+		saved, err := spotify.PlaylistItems(lib.session, p.PlaylistID)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, st := range saved {
+			current = append(current, Item{Track: st.Track, AddedAt: st.AddedAt})
+		}
+	}
+
+	p.compare(result, current)
+
+	return p, nil
+}
+
+// Finds the tracks to add to and remove from the current tracks to
+// match the result, and whether the playlist would then be out of
+// the result's order, tracks being added to its end.
+func (p *Plan) compare(result []Item, current []Item) {
+	p.result = result
+
+	wanted := map[string]bool{}
+	for _, item := range result {
+		wanted[item.Track.Uri] = true
+	}
+
+	existing := map[string]bool{}
+	order := []string{}
+
+	for _, item := range current {
+		if wanted[item.Track.Uri] {
+			order = append(order, item.Track.Uri)
+		} else if !existing[item.Track.Uri] {
+			p.Remove = append(p.Remove, item)
+		}
+		existing[item.Track.Uri] = true
+	}
+
+	for _, item := range result {
+		if !existing[item.Track.Uri] {
+			p.Add = append(p.Add, item)
+			order = append(order, item.Track.Uri)
+		}
+	}
+
+	p.Reorder = !slices.Equal(order, uris(result))
+}
+
+// Returns the id of the definition's target playlist, or an empty
+// string if the definition is matched by name and no such playlist
+// exists yet.
+func (lib *Library) findTarget(def Definition) (string, error) {
+	if def.Target != "" {
+		return PlaylistID(def.Target), nil
+	}
+
+	playlists, err := spotify.UserPlaylists(lib.session)
+	if err != nil {
+		return "", err
+	}
+
+	for _, p := range *playlists {
+		if p.Name == def.Name {
+			return p.ID, nil
+		}
+	}
+
+	return "", nil
+}
+
+// True if the target playlist already has the definition's result.
+func (p Plan) Empty() bool {
+	return p.PlaylistID != "" && len(p.Add) == 0 && len(p.Remove) == 0 && !p.Reorder
+}
+
+// Applies the plan, creating the target playlist if required. Tracks
+// out of order are replaced with the result in as few requests as
+// spotify allows, which resets the dates they were added.
+func (p *Plan) Apply(s *auth.Session) error {
+	if p.Empty() {
+		return nil
+	}
+
+	if p.PlaylistID == "" {
+		user, err := spotify.New(s)
+		if err != nil {
+			return err
+		}
+
+		pl, err := spotify.CreatePlaylist(s, user.ID, p.Definition.Name, "Smart playlist managed by spogo")
+		if err != nil {
+			return err
+		}
+
+		p.PlaylistID = pl.ID
+	}
+
+	if p.Reorder {
+		return spotify.ReplacePlaylistTracks(s, p.PlaylistID, uris(p.result))
+	}
+
+	if err := spotify.RemovePlaylistTracks(s, p.PlaylistID, uris(p.Remove)); err != nil {
+		return err
+	}
+
+	return spotify.AddPlaylistTracks(s, p.PlaylistID, uris(p.Add))
+}
+
+// Renders the plan as a human readable list of changes.
+func (p Plan) String() string {
+	target := p.PlaylistID
+	if target == "" {
+		target = "new playlist"
+	}
+
+	lines := []string{fmt.Sprintf("%s (%s)", color.HiGreenString(p.Definition.displayName()), target)}
+
+	for _, item := range p.Remove {
+		lines = append(lines, color.RedString("  - ")+item.Track.Name+" - "+item.Track.ArtistsString())
+	}
+
+	for _, item := range p.Add {
+		lines = append(lines, color.GreenString("  + ")+item.Track.Name+" - "+item.Track.ArtistsString())
+	}
+
+	summary := fmt.Sprintf("  %d to add, %d to remove", len(p.Add), len(p.Remove))
+	if p.Reorder {
+		summary += ", reordering the playlist"
+	}
+
+	lines = append(lines, summary)
+
+	return strings.Join(lines, "\n")
+}
+
+func (d Definition) displayName() string {
+	if d.Name != "" {
+		return d.Name
+	}
+	return d.Target
+}
+
+// Strips a playlist uri or url down to its id.
+func PlaylistID(s string) string {
+	s = strings.TrimPrefix(s, "spotify:playlist:")
+	s = strings.TrimPrefix(s, "https://open.spotify.com/playlist/")
+
+	if i := strings.Index(s, "?"); i >= 0 {
+		s = s[:i]
+	}
+
+	return s
+}
+
+func uris(items []Item) []string {
+	u := make([]string, len(items))
+	for i, item := range items {
+		u[i] = item.Track.Uri
+	}
+	return u
+}
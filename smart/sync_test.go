@@ -0,0 +1,68 @@
+package smart
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/dionvu/spogo/spotify"
+)
+
+// Items of tracks named by letter, such as "abc".
+func items(names string) []Item {
+	result := []Item{}
+	for _, name := range strings.Split(names, "") {
+		result = append(result, Item{Track: spotify.Track{Name: name, Uri: "spotify:track:" + name}})
+	}
+	return result
+}
+
+func names(items []Item) string {
+	s := ""
+	for _, item := range items {
+		s += item.Track.Name
+	}
+	return s
+}
+
+func TestPlanOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		result  string
+		current string
+
+		add     string
+		remove  string
+		reorder bool
+	}{
+		{name: "new playlist", result: "abc", add: "abc"},
+		{name: "unchanged", result: "abc", current: "abc"},
+		{name: "added to the end", result: "abcd", current: "abc", add: "d"},
+		{name: "removed", result: "ac", current: "abc", remove: "b"},
+		{name: "added before others", result: "dabc", current: "abc", add: "d", reorder: true},
+		{name: "sorted differently", result: "cba", current: "abc", reorder: true},
+		{name: "removed and resorted", result: "ca", current: "abc", remove: "b", reorder: true},
+		{name: "duplicates", result: "ab", current: "abb", reorder: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Plan{}
+			p.compare(items(tt.result), items(tt.current))
+
+			if got := names(p.Add); got != tt.add {
+				t.Errorf("got add %q, want %q", got, tt.add)
+			}
+			if got := names(p.Remove); got != tt.remove {
+				t.Errorf("got remove %q, want %q", got, tt.remove)
+			}
+			if p.Reorder != tt.reorder {
+				t.Errorf("got reorder %v, want %v", p.Reorder, tt.reorder)
+			}
+
+			if p.Reorder && !slices.Equal(uris(p.result), uris(items(tt.result))) {
+				t.Errorf("replaces the playlist with %q, want %q", names(p.result), tt.result)
+			}
+		})
+	}
+}
@@ -7,4 +7,9 @@ const (
 	UserModifyPlaybackState = "user-modify-playback-state"
 	UserPlaylistRead        = "playlist-read-private"
 	UserReadCollab          = "playlist-read-collaborative"
+	UserLibraryRead         = "user-library-read"
+	UserPlaylistModify      = "playlist-modify-private"
+	UserPlaylistModifyPub   = "playlist-modify-public"
+	UserTopRead             = "user-top-read"
+	UserReadRecentlyPlayed  = "user-read-recently-played"
 )
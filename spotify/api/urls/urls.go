@@ -14,6 +14,15 @@ const (
 	PLAYERCURRENT = "https://api.spotify.com/v1/me/player/currently-playing"
 
 	PLAYLISTS = "https://api.spotify.com/v1/me/playlists"
+	PLAYLIST  = "https://api.spotify.com/v1/playlists/"
+	USERS     = "https://api.spotify.com/v1/users/"
+
+	SAVEDTRACKS = "https://api.spotify.com/v1/me/tracks"
+
+	TOPARTISTS = "https://api.spotify.com/v1/me/top/artists"
+	TOPTRACKS  = "https://api.spotify.com/v1/me/top/tracks"
+
+	RECENTLYPLAYED = "https://api.spotify.com/v1/me/player/recently-played"
 
 	SEARCH = "https://api.spotify.com/v1/search"
 
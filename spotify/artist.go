@@ -4,4 +4,10 @@ type Artist struct {
 	ID   string `json:"id"`
 	Name string `json:"name"`
 	Uri  string `json:"uri"`
+
+	// Only present on full artist objects, such
+	// as those returned by a search.
+	Genres     []string  `json:"genres"`
+	Followers  Followers `json:"followers"`
+	Popularity int       `json:"popularity"`
 }
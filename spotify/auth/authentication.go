@@ -1,6 +1,9 @@
 package auth
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -26,6 +29,9 @@ const (
 	REDIRECT_URI = "http://localhost:42069/callback"
 	URI          = "http://localhost:42069"
 	PORT         = "42069"
+
+	// Encoded as 64 characters of base64.
+	PKCE_VERIFIER_BYTES = 48
 )
 
 var (
@@ -33,6 +39,11 @@ var (
 	state        string
 	clientID     string
 	clientSecret string
+
+	// Set for the pkce flow, which proves the code is exchanged by
+	// the same program that asked for it instead of with the secret.
+	pkce     bool
+	verifier string
 )
 
 // Authenticate is set to only run checks after the access token expiry
@@ -43,7 +54,12 @@ var (
 // file.
 func (s *Session) Authenticate(c *config.Config) error {
 	if time.Now().After(s.AccessToken.Expiry) {
-		validCred, _ := c.Spotify.Valid()
+		// Without a secret there is nothing to check before signing in.
+		validCred := c.Spotify.PKCE()
+		if !validCred {
+			validCred, _ = c.Spotify.Valid()
+		}
+
 		if !validCred {
 			fmt.Printf("%v %v %v\n", color.RedString("Error:"),
 				"invalid spotify client credentials:", color.YellowString(c.FilePath()))
@@ -81,6 +97,7 @@ func getNewTokens(s *Session, c *config.Config) error {
 		// For handlers access.
 		clientID = c.Spotify.ClientID
 		clientSecret = c.Spotify.ClientSecret
+		pkce = c.Spotify.PKCE()
 
 		http.HandleFunc("/", startAuth)
 		http.HandleFunc("/callback", completeAuth)
@@ -99,6 +116,11 @@ func getNewTokens(s *Session, c *config.Config) error {
 	query.Set("redirect_uri", REDIRECT_URI)
 	query.Set("code", code)
 
+	if pkce {
+		query.Set("client_id", clientID)
+		query.Set("code_verifier", verifier)
+	}
+
 	ep := "https://accounts.spotify.com/api/token"
 	req, err := http.NewRequest(http.MethodPost, ep, strings.NewReader(query.Encode()))
 	if err != nil {
@@ -108,7 +130,9 @@ func getNewTokens(s *Session, c *config.Config) error {
 	}
 
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	req.SetBasicAuth(c.Spotify.ClientID, c.Spotify.ClientSecret)
+	if !pkce {
+		req.SetBasicAuth(c.Spotify.ClientID, c.Spotify.ClientSecret)
+	}
 
 	res, err := http.DefaultClient.Do(req)
 	if err != nil {
@@ -191,9 +215,22 @@ func startAuth(w http.ResponseWriter, r *http.Request) {
 		scopes.UserModifyPlaybackState,
 		scopes.UserPlaylistRead,
 		scopes.UserReadCollab,
+		scopes.UserLibraryRead,
+		scopes.UserPlaylistModify,
+		scopes.UserPlaylistModifyPub,
+		scopes.UserTopRead,
+		scopes.UserReadRecentlyPlayed,
 	}, " "))
 	query.Set("state", state)
 
+	if pkce {
+		verifier = newVerifier()
+		challenge := sha256.Sum256([]byte(verifier))
+
+		query.Set("code_challenge_method", "S256")
+		query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	}
+
 	req, err := http.NewRequest(http.MethodGet, spotifyurls.SPOTIFYAUTHURL, strings.NewReader(query.Encode()))
 	if err != nil {
 		log.Fatal(errors.HTTPRequest.Wrap(err, "unable to create new http request for spotify authentication url"))
@@ -206,6 +243,15 @@ func startAuth(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, fmt.Sprintf("%s?%s", spotifyurls.SPOTIFYAUTHURL, query.Encode()), http.StatusTemporaryRedirect)
 }
 
+// A random code verifier, kept until the code is exchanged.
+func newVerifier() string {
+	b := make([]byte, PKCE_VERIFIER_BYTES)
+	if _, err := rand.Read(b); err != nil {
+		log.Fatalf("unable to generate pkce code verifier: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
 // After user is redirected to the redirect uri, ensures valid state
 // and fetches the authentication code.
 func completeAuth(w http.ResponseWriter, r *http.Request) {
@@ -269,6 +315,10 @@ func (t *AccessToken) Refresh(refreshToken *RefreshToken, c *config.Config) erro
 	query.Set("grant_type", "refresh_token")
 	query.Set("refresh_token", refreshToken.String())
 
+	if c.Spotify.PKCE() {
+		query.Set("client_id", c.Spotify.ClientID)
+	}
+
 	ep := "https://accounts.spotify.com/api/token"
 	req, err := http.NewRequest(http.MethodPost, ep, strings.NewReader(query.Encode()))
 	if err != nil {
@@ -278,7 +328,9 @@ func (t *AccessToken) Refresh(refreshToken *RefreshToken, c *config.Config) erro
 	}
 
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	req.SetBasicAuth(c.Spotify.ClientID, c.Spotify.ClientSecret)
+	if !c.Spotify.PKCE() {
+		req.SetBasicAuth(c.Spotify.ClientID, c.Spotify.ClientSecret)
+	}
 
 	res, err := http.DefaultClient.Do(req)
 	if res.StatusCode != http.StatusOK || err != nil {
@@ -302,6 +354,12 @@ func (t *AccessToken) Refresh(refreshToken *RefreshToken, c *config.Config) erro
 
 	t.Update(t.String(), c)
 
+	// Refresh tokens of the pkce flow are replaced on every refresh.
+	rotated := RefreshToken{}
+	if err = json.Unmarshal(b, &rotated); err == nil && rotated.Token != "" {
+		refreshToken.Update(rotated.Token, c)
+	}
+
 	return nil
 }
 
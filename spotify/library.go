@@ -0,0 +1,118 @@
+package spotify
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/dionvu/spogo/err"
+	"github.com/dionvu/spogo/spotify/api/headers"
+	"github.com/dionvu/spogo/spotify/api/urls"
+	"github.com/dionvu/spogo/spotify/auth"
+)
+
+const (
+	SAVED_TRACKS_PAGE_LIMIT    = 50
+	PLAYLIST_TRACKS_PAGE_LIMIT = 100
+)
+
+// A track paired with the time it was added to
+// the user's library or to a playlist.
+type SavedTrack struct {
+	AddedAt time.Time `json:"added_at"`
+	Track   Track     `json:"track"`
+}
+
+// Retrieves every track in the user's "Liked Songs",
+// most recently added first.
+func SavedTracks(s *auth.Session) ([]SavedTrack, error) {
+	query := url.Values{}
+	query.Set("limit", fmt.Sprint(SAVED_TRACKS_PAGE_LIMIT))
+
+	return savedTrackPages(s, spotifyurls.SAVEDTRACKS+"?"+query.Encode(), "your liked songs")
+}
+
+// Retrieves every track of a playlist along with the time
+// each track was added. Unlike PlaylistTracks, every page
+// of the playlist is fetched.
+func PlaylistItems(s *auth.Session, playlistID string) ([]SavedTrack, error) {
+	query := url.Values{}
+	query.Set("limit", fmt.Sprint(PLAYLIST_TRACKS_PAGE_LIMIT))
+
+	return savedTrackPages(s, spotifyurls.PLAYLIST+playlistID+"/tracks?"+query.Encode(), "the tracks of playlist "+playlistID)
+}
+
+// Follows the "next" links of a paged track response starting at
+// ep, collecting every item. Local files and removed tracks, which
+// spotify returns without a uri, are skipped. Sessions signed in
+// before spogo asked for the scope ep needs are refused with a
+// MissingScope error naming what.
+func savedTrackPages(s *auth.Session, ep string, what string) ([]SavedTrack, error) {
+	tracks := []SavedTrack{}
+
+	for ep != "" {
+		req, err := http.NewRequest(http.MethodGet, ep, nil)
+		if err != nil {
+			err = errors.HTTPRequest.Wrap(err, "failed to make request for tracks")
+			errors.Log(err)
+			return nil, err
+		}
+		req.Header.Add(headers.Auth, "Bearer "+s.AccessToken.String())
+
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			err = errors.HTTP.WrapWithNoMessage(err)
+			errors.Log(err)
+			return nil, err
+		}
+
+		errors.LogApiCall(ep, res.StatusCode)
+
+		if res.StatusCode == 401 {
+			res.Body.Close()
+			err = errors.Reauthentication.NewWithNoMessage()
+			errors.Log(err)
+			return nil, err
+		}
+
+		if res.StatusCode == http.StatusForbidden {
+			res.Body.Close()
+			err = errors.MissingScope.New("spotify refused to share %s, sign in again to allow it", what)
+			errors.Log(err)
+			return nil, err
+		}
+
+		if res.StatusCode >= http.StatusBadRequest {
+			res.Body.Close()
+			err = errors.HTTP.New("bad request")
+			errors.Log(err)
+			return nil, err
+		}
+
+		var page struct {
+			Items []SavedTrack `json:"items"`
+			Next  string       `json:"next"`
+		}
+
+		err = json.NewDecoder(res.Body).Decode(&page)
+		res.Body.Close()
+		if err != nil {
+			err = errors.JSONDecode.Wrap(err, "failed to decode tracks response")
+			errors.Log(err)
+			return nil, err
+		}
+
+		for _, item := range page.Items {
+			if item.Track.Uri == "" || item.Track.ID == "" {
+				continue
+			}
+			tracks = append(tracks, item)
+		}
+
+		ep = page.Next
+	}
+
+	return tracks, nil
+}
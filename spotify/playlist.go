@@ -2,8 +2,11 @@ package spotify
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strings"
 
 	"github.com/dionvu/spogo/err"
 	"github.com/dionvu/spogo/spotify/api/headers"
@@ -34,14 +37,84 @@ type Playlist struct {
 	} `json:"owner"`
 }
 
+// The most playlists spotify returns in one page.
+const PLAYLISTS_PAGE_LIMIT = 50
+
 type Followers struct {
 	Total int `json:"total"`
 }
 
+// Retrieves every playlist the user owns or follows, following
+// each page of "/me/playlists" until there is no next one.
 func UserPlaylists(s *auth.Session) (*[]Playlist, error) {
-	req, err := http.NewRequest(http.MethodGet, spotifyurls.PLAYLISTS, nil)
+	query := url.Values{}
+	query.Set("limit", fmt.Sprint(PLAYLISTS_PAGE_LIMIT))
+
+	playlists := []Playlist{}
+
+	for ep := spotifyurls.PLAYLISTS + "?" + query.Encode(); ep != ""; {
+		req, err := http.NewRequest(http.MethodGet, ep, nil)
+		if err != nil {
+			return nil, errors.HTTPRequest.Wrap(err, "failed to make request for playlists")
+		}
+		req.Header.Add(headers.Auth, "Bearer "+s.AccessToken.String())
+
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			err = errors.HTTP.WrapWithNoMessage(err)
+			errors.Log(err)
+			return nil, err
+		}
+
+		errors.LogApiCall(ep, res.StatusCode)
+
+		if res.StatusCode == 401 {
+			res.Body.Close()
+			err = errors.Reauthentication.NewWithNoMessage()
+			errors.Log(err)
+			return nil, err
+		}
+
+		if res.StatusCode >= http.StatusBadRequest {
+			res.Body.Close()
+			err = errors.HTTP.New("bad request")
+			errors.Log(err)
+			return nil, err
+		}
+
+		var page struct {
+			Items []Playlist `json:"items"`
+			Next  string     `json:"next"`
+		}
+
+		err = json.NewDecoder(res.Body).Decode(&page)
+		res.Body.Close()
+		if err != nil {
+			err = errors.JSONDecode.Wrap(err, "failed to decode playlists response")
+			errors.Log(err)
+			return nil, err
+		}
+
+		playlists = append(playlists, page.Items...)
+
+		ep = page.Next
+	}
+
+	return &playlists, nil
+}
+
+func PlaylistTracks(s *auth.Session, playlistID string) (*[]Track, error) {
+	type PlaylistTrack struct {
+		Track Track `json:"track"`
+	}
+
+	url := "https://api.spotify.com/v1/playlists/" + playlistID + "/tracks"
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
 	if err != nil {
-		return nil, errors.HTTPRequest.Wrap(err, "failed to make request for playlists")
+		err = errors.HTTPRequest.Wrap(err, "failed to make request for playlists")
+		errors.Log(err)
+		return nil, err
 	}
 	req.Header.Add(headers.Auth, "Bearer "+s.AccessToken.String())
 
@@ -71,75 +144,209 @@ func UserPlaylists(s *auth.Session) (*[]Playlist, error) {
 		return nil, err
 	}
 
-	pr := &playlistsSearchResponse{}
+	var response struct {
+		Items []PlaylistTrack `json:"items"`
+	}
 
-	err = json.Unmarshal(b, pr)
+	err = json.Unmarshal(b, &response)
 	if err != nil {
 		err = errors.JSONUnmarshal.Wrap(err, "failed to unmarshal playlists response")
 		errors.Log(err)
 		return nil, err
 	}
 
-	return &pr.Items, nil
+	tracks := []Track{}
+
+	for _, playlistTrack := range response.Items {
+		tracks = append(tracks, playlistTrack.Track)
+	}
+
+	return &tracks, nil
 }
 
-func PlaylistTracks(s *auth.Session, playlistID string) (*[]Track, error) {
-	type PlaylistTrack struct {
-		Track Track `json:"track"`
+// The maximum number of tracks spotify accepts in a
+// single add, remove or replace request.
+const PLAYLIST_MODIFY_LIMIT = 100
+
+// Appends the tracks with the given uris to the end of the
+// playlist, split into as many requests as required.
+func AddPlaylistTracks(s *auth.Session, playlistID string, uris []string) error {
+	for start := 0; start < len(uris); start += PLAYLIST_MODIFY_LIMIT {
+		end := min(start+PLAYLIST_MODIFY_LIMIT, len(uris))
+
+		payload := struct {
+			Uris []string `json:"uris"`
+		}{
+			Uris: uris[start:end],
+		}
+
+		if err := modifyPlaylist(s, http.MethodPost, playlistID, payload); err != nil {
+			return err
+		}
 	}
 
-	url := "https://api.spotify.com/v1/playlists/" + playlistID + "/tracks"
+	return nil
+}
 
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+// Removes every occurrence of the tracks with the given uris from
+// the playlist, split into as many requests as required.
+func RemovePlaylistTracks(s *auth.Session, playlistID string, uris []string) error {
+	type trackUri struct {
+		Uri string `json:"uri"`
+	}
+
+	for start := 0; start < len(uris); start += PLAYLIST_MODIFY_LIMIT {
+		end := min(start+PLAYLIST_MODIFY_LIMIT, len(uris))
+
+		payload := struct {
+			Tracks []trackUri `json:"tracks"`
+		}{}
+
+		for _, uri := range uris[start:end] {
+			payload.Tracks = append(payload.Tracks, trackUri{Uri: uri})
+		}
+
+		if err := modifyPlaylist(s, http.MethodDelete, playlistID, payload); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Replaces every track of the playlist with the tracks with the
+// given uris, in order. Only the first tracks are replaced in one
+// request, the rest are added after them.
+func ReplacePlaylistTracks(s *auth.Session, playlistID string, uris []string) error {
+	first := min(len(uris), PLAYLIST_MODIFY_LIMIT)
+
+	payload := struct {
+		Uris []string `json:"uris"`
+	}{
+		Uris: append([]string{}, uris[:first]...),
+	}
+
+	if err := modifyPlaylist(s, http.MethodPut, playlistID, payload); err != nil {
+		return err
+	}
+
+	return AddPlaylistTracks(s, playlistID, uris[first:])
+}
+
+// Sends a single add, remove or replace request to the playlist's
+// tracks endpoint with the payload as the json body.
+func modifyPlaylist(s *auth.Session, method string, playlistID string, payload interface{}) error {
+	j, err := json.Marshal(payload)
 	if err != nil {
-		err = errors.HTTPRequest.Wrap(err, "failed to make request for playlists")
+		err = errors.JSONMarshal.WrapWithNoMessage(err)
 		errors.Log(err)
-		return nil, err
+		return err
+	}
+
+	ep := spotifyurls.PLAYLIST + playlistID + "/tracks"
+
+	req, err := http.NewRequest(method, ep, strings.NewReader(string(j)))
+	if err != nil {
+		err = errors.HTTPRequest.Wrap(err, "failed to make request to modify playlist: %v", playlistID)
+		errors.Log(err)
+		return err
 	}
 	req.Header.Add(headers.Auth, "Bearer "+s.AccessToken.String())
+	req.Header.Add(headers.ContentType, headers.ApplicationJson)
 
 	res, err := http.DefaultClient.Do(req)
 	if err != nil {
 		err = errors.HTTP.WrapWithNoMessage(err)
 		errors.Log(err)
-		return nil, err
+		return err
 	}
+	defer res.Body.Close()
+
+	errors.LogApiCall(ep, res.StatusCode)
 
 	if res.StatusCode == 401 {
 		err = errors.Reauthentication.NewWithNoMessage()
 		errors.Log(err)
-		return nil, err
+		return err
+	}
+
+	// Also refused for playlists the user doesn't own.
+	if res.StatusCode == http.StatusForbidden {
+		err = errors.MissingScope.New("spotify refused to change playlist %v, either it isn't yours, or sign in again to allow it", playlistID)
+		errors.Log(err)
+		return err
 	}
 
 	if res.StatusCode >= http.StatusBadRequest {
 		err = errors.HTTP.New("bad request")
 		errors.Log(err)
-		return nil, err
+		return err
 	}
 
-	b, err := io.ReadAll(res.Body)
+	return nil
+}
+
+// Creates a new, empty, private playlist owned by the user.
+func CreatePlaylist(s *auth.Session, userID string, name string, description string) (*Playlist, error) {
+	payload := map[string]interface{}{
+		"name":        name,
+		"description": description,
+		"public":      false,
+	}
+
+	j, err := json.Marshal(payload)
 	if err != nil {
-		err = errors.HTTP.Wrap(err, "failed to read response body")
+		err = errors.JSONMarshal.WrapWithNoMessage(err)
 		errors.Log(err)
 		return nil, err
 	}
 
-	var response struct {
-		Items []PlaylistTrack `json:"items"`
+	ep := spotifyurls.USERS + userID + "/playlists"
+
+	req, err := http.NewRequest(http.MethodPost, ep, strings.NewReader(string(j)))
+	if err != nil {
+		err = errors.HTTPRequest.Wrap(err, "failed to make request to create playlist: %v", name)
+		errors.Log(err)
+		return nil, err
 	}
+	req.Header.Add(headers.Auth, "Bearer "+s.AccessToken.String())
+	req.Header.Add(headers.ContentType, headers.ApplicationJson)
 
-	err = json.Unmarshal(b, &response)
+	res, err := http.DefaultClient.Do(req)
 	if err != nil {
-		err = errors.JSONUnmarshal.Wrap(err, "failed to unmarshal playlists response")
+		err = errors.HTTP.WrapWithNoMessage(err)
 		errors.Log(err)
 		return nil, err
 	}
+	defer res.Body.Close()
 
-	tracks := []Track{}
+	errors.LogApiCall(ep, res.StatusCode)
 
-	for _, playlistTrack := range response.Items {
-		tracks = append(tracks, playlistTrack.Track)
+	if res.StatusCode == 401 {
+		err = errors.Reauthentication.NewWithNoMessage()
+		errors.Log(err)
+		return nil, err
 	}
 
-	return &tracks, nil
+	if res.StatusCode == http.StatusForbidden {
+		err = errors.MissingScope.New("spotify refused to create playlist %v, sign in again to allow it", name)
+		errors.Log(err)
+		return nil, err
+	}
+
+	if res.StatusCode >= http.StatusBadRequest {
+		err = errors.HTTP.New("bad request")
+		errors.Log(err)
+		return nil, err
+	}
+
+	p := &Playlist{}
+
+	if err = json.NewDecoder(res.Body).Decode(p); err != nil {
+		err = errors.JSONDecode.Wrap(err, "failed to decode created playlist")
+		errors.Log(err)
+		return nil, err
+	}
+
+	return p, nil
 }
@@ -0,0 +1,171 @@
+package spotify
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/dionvu/spogo/err"
+)
+
+const (
+	FILTER_ARTIST = "artist"
+	FILTER_ALBUM  = "album"
+	FILTER_TRACK  = "track"
+	FILTER_YEAR   = "year"
+	FILTER_GENRE  = "genre"
+	FILTER_ISRC   = "isrc"
+	FILTER_UPC    = "upc"
+	FILTER_TAG    = "tag"
+
+	TAG_NEW     = "new"
+	TAG_HIPSTER = "hipster"
+)
+
+// The field filters spotify understands in a search query.
+var SEARCH_FILTERS = []string{
+	FILTER_ARTIST, FILTER_ALBUM, FILTER_TRACK, FILTER_YEAR,
+	FILTER_GENRE, FILTER_ISRC, FILTER_UPC, FILTER_TAG,
+}
+
+var (
+	yearPattern  = regexp.MustCompile(`^\d{4}$`)
+	rangePattern = regexp.MustCompile(`^(\d{4})-(\d{4})$`)
+)
+
+// A search query split into free text and field filters,
+// for example `daft artist:"daft punk" year:1990-1999`.
+type Query struct {
+	Text    string
+	Filters []QueryFilter
+}
+
+type QueryFilter struct {
+	Field string
+	Value string
+}
+
+// Parses the user's search input. Words of the form "field:value" are
+// treated as filters if field is one of SEARCH_FILTERS, anything else
+// is kept as free text. Values containing spaces may be quoted. An
+// error describing the first invalid filter is returned along with
+// the parsed query.
+func ParseQuery(input string) (Query, error) {
+	q := Query{}
+	text := []string{}
+
+	for _, word := range splitQuery(input) {
+		field, value, ok := strings.Cut(word, ":")
+		field = strings.ToLower(field)
+
+		if !ok || !isSearchFilter(field) {
+			text = append(text, word)
+			continue
+		}
+
+		q.Filters = append(q.Filters, QueryFilter{
+			Field: field,
+			Value: strings.Trim(value, `"`),
+		})
+	}
+
+	q.Text = strings.Join(text, " ")
+
+	return q, q.Validate()
+}
+
+// Returns an error describing the first invalid filter.
+func (q Query) Validate() error {
+	if q.Text == "" && len(q.Filters) == 0 {
+		return errors.Input.New("empty search query")
+	}
+
+	for _, f := range q.Filters {
+		if f.Value == "" {
+			return errors.Input.New("%s: missing value", f.Field)
+		}
+
+		switch f.Field {
+		case FILTER_YEAR:
+			if yearPattern.MatchString(f.Value) {
+				continue
+			}
+
+			m := rangePattern.FindStringSubmatch(f.Value)
+			if m == nil {
+				return errors.Input.New("year: expected 1999 or 1990-1999")
+			}
+
+			from, _ := strconv.Atoi(m[1])
+			to, _ := strconv.Atoi(m[2])
+			if from > to {
+				return errors.Input.New("year: %d is after %d", from, to)
+			}
+
+		case FILTER_TAG:
+			if f.Value != TAG_NEW && f.Value != TAG_HIPSTER {
+				return errors.Input.New("tag: expected new or hipster")
+			}
+		}
+	}
+
+	return nil
+}
+
+// Renders the query in the format expected by the
+// "q" parameter of the search endpoint.
+func (q Query) String() string {
+	parts := []string{}
+
+	if q.Text != "" {
+		parts = append(parts, q.Text)
+	}
+
+	for _, f := range q.Filters {
+		value := f.Value
+		if strings.Contains(value, " ") {
+			value = `"` + value + `"`
+		}
+
+		parts = append(parts, f.Field+":"+value)
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func isSearchFilter(field string) bool {
+	for _, f := range SEARCH_FILTERS {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Splits the input on spaces, keeping quoted sections together.
+func splitQuery(input string) []string {
+	words := []string{}
+	word := strings.Builder{}
+	quoted := false
+
+	for _, r := range input {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			word.WriteRune(r)
+		case r == ' ' && !quoted:
+			if word.Len() > 0 {
+				words = append(words, word.String())
+				word.Reset()
+			}
+		default:
+			word.WriteRune(r)
+		}
+	}
+
+	if word.Len() > 0 {
+		words = append(words, word.String())
+	}
+
+	return words
+}
@@ -0,0 +1,71 @@
+package spotify
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/dionvu/spogo/spotify/api/urls"
+	"github.com/dionvu/spogo/spotify/auth"
+)
+
+// The most recently played tracks spotify returns at once.
+const RECENTLY_PLAYED_LIMIT = 50
+
+// What a track was played from, such as an album or playlist.
+// Nil for tracks played on their own.
+type PlayContext struct {
+	Type string `json:"type"`
+	Uri  string `json:"uri"`
+}
+
+// A track the user played, at the time spotify recorded it.
+type PlayHistory struct {
+	Track    Track        `json:"track"`
+	PlayedAt time.Time    `json:"played_at"`
+	Context  *PlayContext `json:"context"`
+}
+
+// Retrieves up to limit tracks played before before, or after after
+// if given instead, newest first. Spotify only keeps the last 50
+// tracks played, so more reports whether it has older ones left.
+func RecentlyPlayed(s *auth.Session, before time.Time, after time.Time, limit int) (items []PlayHistory, more bool, err error) {
+	query := url.Values{}
+	query.Set("limit", fmt.Sprint(min(limit, RECENTLY_PLAYED_LIMIT)))
+
+	// Cursors are unix milliseconds, and only one can be given.
+	switch {
+	case !before.IsZero():
+		query.Set("before", fmt.Sprint(before.UnixMilli()))
+	case !after.IsZero():
+		query.Set("after", fmt.Sprint(after.UnixMilli()))
+	}
+
+	var page struct {
+		Items []PlayHistory `json:"items"`
+		Next  string        `json:"next"`
+	}
+
+	err = getScoped(s, spotifyurls.RECENTLYPLAYED+"?"+query.Encode(), "your recently played tracks", &page)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return page.Items, page.Next != "", nil
+}
+
+// The kind of item a uri such as "spotify:playlist:..." names,
+// "collection" for liked songs, "spotify:user:<id>:collection".
+func UriType(uri string) string {
+	parts := strings.Split(uri, ":")
+
+	switch {
+	case len(parts) < 3:
+		return ""
+	case parts[len(parts)-1] == "collection":
+		return "collection"
+	default:
+		return parts[1]
+	}
+}
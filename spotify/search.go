@@ -1,6 +1,7 @@
 package spotify
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -17,6 +18,9 @@ const (
 	TRACK_TYPE    = "track"
 	ALBUM_TYPE    = "album"
 	PLAYLIST_TYPE = "playlist"
+	ARTIST_TYPE   = "artist"
+	SHOW_TYPE     = "show"
+	EPISODE_TYPE  = "episode"
 )
 
 type SearchResult struct {
@@ -26,6 +30,10 @@ type SearchResult struct {
 	Artists   []*Artist
 	Shows     []*Show
 	Episodes  []*Episode
+
+	// The total number of results available for
+	// each search type, used for pagination.
+	Totals map[string]int
 }
 
 type searchResponse struct {
@@ -68,14 +76,32 @@ type EpisodesSearchResponse struct {
 }
 
 func Search(input string, searchType []string, limit int, s *auth.Session) (*SearchResult, error) {
+	return SearchPage(input, searchType, limit, 0, "", s)
+}
+
+// Searches for a single page of results starting at offset. If market is
+// not empty, only content available in that country is returned.
+func SearchPage(input string, searchType []string, limit int, offset int, market string, s *auth.Session) (*SearchResult, error) {
+	return SearchPageContext(context.Background(), input, searchType, limit, offset, market, s)
+}
+
+// Same as SearchPage, but the request is aborted once ctx is cancelled,
+// for example when the user changes the query before a live search
+// has completed.
+func SearchPageContext(ctx context.Context, input string, searchType []string, limit int, offset int, market string, s *auth.Session) (*SearchResult, error) {
 	r := &searchResponse{}
 
 	query := url.Values{}
 	query.Set("q", input)
 	query.Set("type", strings.Join(searchType, ","))
 	query.Set("limit", fmt.Sprint(limit))
+	query.Set("offset", fmt.Sprint(offset))
+
+	if market != "" {
+		query.Set("market", market)
+	}
 
-	req, err := http.NewRequest(http.MethodGet, spotifyurls.SEARCH+"?"+query.Encode(), nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, spotifyurls.SEARCH+"?"+query.Encode(), nil)
 	if err != nil {
 		err = errors.HTTPRequest.Wrap(err, fmt.Sprintf("failed to make request for search query: %v", input))
 		errors.Log(err)
@@ -85,10 +111,16 @@ func Search(input string, searchType []string, limit int, s *auth.Session) (*Sea
 
 	res, err := http.DefaultClient.Do(req)
 	if err != nil {
+		// Cancellation is expected, so it isn't logged.
+		if ctx.Err() != nil {
+			return nil, errors.HTTPRequest.Wrap(err, "search cancelled")
+		}
+
 		err = errors.HTTPRequest.WrapWithNoMessage(err)
 		errors.Log(err)
 		return nil, err
 	}
+	defer res.Body.Close()
 
 	errors.LogApiCall(spotifyurls.SEARCH, res.StatusCode)
 
@@ -117,6 +149,14 @@ func Search(input string, searchType []string, limit int, s *auth.Session) (*Sea
 		Artists:   []*Artist{},
 		Shows:     []*Show{},
 		Episodes:  []*Episode{},
+		Totals: map[string]int{
+			TRACK_TYPE:    r.Tracks.Total,
+			ALBUM_TYPE:    r.Albums.Total,
+			PLAYLIST_TYPE: r.Playlists.Total,
+			ARTIST_TYPE:   r.Artists.Total,
+			SHOW_TYPE:     r.Shows.Total,
+			EPISODE_TYPE:  r.Episodes.Total,
+		},
 	}
 
 	for _, track := range r.Tracks.Items {
@@ -0,0 +1,88 @@
+package spotify
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	SEARCH_CACHE_SIZE = 32
+	SEARCH_CACHE_TTL  = 10 * time.Minute
+)
+
+// An in memory least recently used cache of search results, keyed by
+// the query, the searched types and the market. Entries older than the
+// ttl are treated as missing.
+type SearchCache struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type searchCacheEntry struct {
+	key     string
+	result  *SearchResult
+	created time.Time
+}
+
+func NewSearchCache(size int, ttl time.Duration) *SearchCache {
+	return &SearchCache{
+		size:    size,
+		ttl:     ttl,
+		order:   list.New(),
+		entries: map[string]*list.Element{},
+	}
+}
+
+// Builds the cache key for a search. The query should already be
+// normalized through ParseQuery so equivalent filters share a key.
+func SearchCacheKey(query string, searchType []string, market string) string {
+	return strings.Join([]string{query, strings.Join(searchType, ","), market}, "\x00")
+}
+
+// Returns the cached result for key, if it exists and hasn't expired.
+func (c *SearchCache) Get(key string) (*SearchResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := el.Value.(*searchCacheEntry)
+	if time.Since(entry.created) > c.ttl {
+		c.order.Remove(el)
+		delete(c.entries, key)
+		return nil, false
+	}
+
+	c.order.MoveToFront(el)
+
+	return entry.result, true
+}
+
+// Stores the result, evicting the least recently used
+// entry if the cache is full.
+func (c *SearchCache) Put(key string, result *SearchResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[key]; ok {
+		el.Value = &searchCacheEntry{key: key, result: result, created: time.Now()}
+		c.order.MoveToFront(el)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&searchCacheEntry{key: key, result: result, created: time.Now()})
+
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*searchCacheEntry).key)
+	}
+}
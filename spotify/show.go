@@ -5,6 +5,7 @@ type Show struct {
 	ID            string  `json:"id"`
 	Images        []Image `json:"images"`
 	Name          string  `json:"name"`
+	Publisher     string  `json:"publisher"`
 	Uri           string  `json:"uri"`
 	TotalEpisodes int     `json:"total_episodes"`
 }
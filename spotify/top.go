@@ -0,0 +1,114 @@
+package spotify
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/dionvu/spogo/err"
+	"github.com/dionvu/spogo/spotify/api/headers"
+	"github.com/dionvu/spogo/spotify/api/urls"
+	"github.com/dionvu/spogo/spotify/auth"
+)
+
+// The periods spotify computes the user's top items over.
+const (
+	// Roughly the last 4 weeks.
+	TIME_RANGE_SHORT = "short_term"
+
+	// Roughly the last 6 months.
+	TIME_RANGE_MEDIUM = "medium_term"
+
+	// Roughly the last year.
+	TIME_RANGE_LONG = "long_term"
+
+	TOP_ITEMS_LIMIT = 50
+)
+
+// Retrieves the artists the user listened to most over the time
+// range, most listened first. Artists are full objects, with genres.
+func TopArtists(s *auth.Session, timeRange string, limit int) ([]Artist, error) {
+	var page struct {
+		Items []Artist `json:"items"`
+	}
+
+	if err := topItems(s, spotifyurls.TOPARTISTS, timeRange, limit, &page); err != nil {
+		return nil, err
+	}
+
+	return page.Items, nil
+}
+
+// Retrieves the tracks the user listened to most over the
+// time range, most listened first.
+func TopTracks(s *auth.Session, timeRange string, limit int) ([]Track, error) {
+	var page struct {
+		Items []Track `json:"items"`
+	}
+
+	if err := topItems(s, spotifyurls.TOPTRACKS, timeRange, limit, &page); err != nil {
+		return nil, err
+	}
+
+	return page.Items, nil
+}
+
+// Decodes the first page of top items from ep into page.
+func topItems(s *auth.Session, ep string, timeRange string, limit int, page any) error {
+	query := url.Values{}
+	query.Set("time_range", timeRange)
+	query.Set("limit", fmt.Sprint(min(limit, TOP_ITEMS_LIMIT)))
+
+	return getScoped(s, ep+"?"+query.Encode(), "your top artists and tracks", page)
+}
+
+// Decodes the response of ep into out. Sessions signed in before
+// spogo asked for the scope ep needs are refused with a MissingScope
+// error naming what, until the user signs in again.
+func getScoped(s *auth.Session, ep string, what string, out any) error {
+	req, err := http.NewRequest(http.MethodGet, ep, nil)
+	if err != nil {
+		err = errors.HTTPRequest.Wrap(err, "failed to make request for %s", what)
+		errors.Log(err)
+		return err
+	}
+	req.Header.Add(headers.Auth, "Bearer "+s.AccessToken.String())
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		err = errors.HTTP.WrapWithNoMessage(err)
+		errors.Log(err)
+		return err
+	}
+	defer res.Body.Close()
+
+	errors.LogApiCall(ep, res.StatusCode)
+
+	if res.StatusCode == 401 {
+		err = errors.Reauthentication.NewWithNoMessage()
+		errors.Log(err)
+		return err
+	}
+
+	if res.StatusCode == http.StatusForbidden {
+		err = errors.MissingScope.New("spotify refused to share %s, sign in again to allow it", what)
+		errors.Log(err)
+		return err
+	}
+
+	if res.StatusCode >= http.StatusBadRequest {
+		err = errors.HTTP.New("bad request")
+		errors.Log(err)
+		return err
+	}
+
+	err = json.NewDecoder(res.Body).Decode(out)
+	if err != nil {
+		err = errors.JSONDecode.Wrap(err, "failed to decode %s response", what)
+		errors.Log(err)
+		return err
+	}
+
+	return nil
+}
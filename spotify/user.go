@@ -13,6 +13,10 @@ type User struct {
 	Email       string `json:"email"`
 	ID          string `json:"id"`
 
+	// ISO 3166-1 alpha-2 country code, used as the
+	// market for searches.
+	Country string `json:"country"`
+
 	ExternalURLs struct {
 		Spotify string `json:"spotify"`
 	} `json:"external_urls"`
@@ -32,15 +36,32 @@ type User struct {
 
 func New(s *auth.Session) (*User, error) {
 	ep := "https://api.spotify.com/v1/me"
-	req, _ := http.NewRequest(http.MethodGet, ep, nil)
+	req, err := http.NewRequest(http.MethodGet, ep, nil)
+	if err != nil {
+		err = errors.HTTPRequest.Wrap(err, "failed to make request for user")
+		errors.Log(err)
+		return nil, err
+	}
 
 	req.Header.Add("Authorization", "Bearer "+s.AccessToken.String())
 
-	res, _ := http.DefaultClient.Do(req)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		err = errors.HTTP.WrapWithNoMessage(err)
+		errors.Log(err)
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 401 {
+		err = errors.Reauthentication.NewWithNoMessage()
+		errors.Log(err)
+		return nil, err
+	}
 
 	u := &User{}
 
-	err := json.NewDecoder(res.Body).Decode(u)
+	err = json.NewDecoder(res.Body).Decode(u)
 	if err != nil {
 		err = errors.JSONDecode.Wrap(err, "failed to decode user response")
 		errors.Log(err)
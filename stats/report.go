@@ -0,0 +1,286 @@
+package stats
+
+import (
+	"cmp"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dionvu/spogo/config"
+	"github.com/dionvu/spogo/err"
+	"github.com/dionvu/spogo/history"
+	"github.com/dionvu/spogo/spotify"
+	"github.com/dionvu/spogo/spotify/auth"
+)
+
+const (
+	// How many entries each top list keeps.
+	TOP_LIMIT = 10
+
+	// How many of spotify's top artists genres are counted from.
+	GENRE_ARTISTS = 50
+
+	DAY_FORMAT = time.DateOnly
+)
+
+// A span of time to report on, paired with the spotify time
+// range closest to it.
+type Period struct {
+	Name      string
+	Days      int
+	TimeRange string
+}
+
+// The periods the stats view cycles through. A period of
+// zero days covers every play recorded.
+var PERIODS = []Period{
+	{"Last 4 weeks", 28, spotify.TIME_RANGE_SHORT},
+	{"Last 6 months", 182, spotify.TIME_RANGE_MEDIUM},
+	{"All time", 0, spotify.TIME_RANGE_LONG},
+}
+
+// Returns the start of the period, zero for all time.
+func (p Period) Since(now time.Time) time.Time {
+	if p.Days == 0 {
+		return time.Time{}
+	}
+
+	return startOfDay(now).AddDate(0, 0, 1-p.Days)
+}
+
+// Returns the spotify time range closest to listening from since
+// until until, the longest for an open start.
+func TimeRange(since time.Time, until time.Time) string {
+	if since.IsZero() {
+		return spotify.TIME_RANGE_LONG
+	}
+
+	days := int(until.Sub(since).Hours() / 24)
+
+	switch {
+	case days <= 56:
+		return spotify.TIME_RANGE_SHORT
+	case days <= 365:
+		return spotify.TIME_RANGE_MEDIUM
+	default:
+		return spotify.TIME_RANGE_LONG
+	}
+}
+
+// An artist, track or album with how much it was listened to.
+type Count struct {
+	Name string `json:"name"`
+
+	// The artists of a track or album.
+	Artists string `json:"artists,omitempty"`
+
+	Plays      int `json:"plays"`
+	ListenedMs int `json:"listened_ms"`
+}
+
+// A genre with how many of spotify's top artists have it.
+type Genre struct {
+	Name    string `json:"name"`
+	Artists int    `json:"artists"`
+}
+
+// How long was listened to on a day.
+type Day struct {
+	Date       string `json:"date"`
+	ListenedMs int    `json:"listened_ms"`
+}
+
+// Listening statistics over a span of time, from the plays recorded
+// by spogo along with spotify's own top artists and tracks.
+type Report struct {
+	// Zero since for every play recorded.
+	Since time.Time `json:"since"`
+	Until time.Time `json:"until"`
+
+	Plays      int `json:"plays"`
+	ListenedMs int `json:"listened_ms"`
+
+	TopTracks  []Count `json:"top_tracks"`
+	TopArtists []Count `json:"top_artists"`
+	TopAlbums  []Count `json:"top_albums"`
+
+	// Every day from the first play, or since, until until.
+	Days []Day `json:"days"`
+
+	// The spotify time range of the lists below.
+	TimeRange      string  `json:"time_range"`
+	SpotifyTracks  []Count `json:"spotify_top_tracks"`
+	SpotifyArtists []Count `json:"spotify_top_artists"`
+
+	// The genres most of spotify's top artists share.
+	Genres []Genre `json:"top_genres"`
+
+	// Why spotify's lists are missing, if they are.
+	Note string `json:"note,omitempty"`
+}
+
+// Builds the report of listening from since until until. Spotify's
+// lists are left out with a note when they can't be fetched, unless
+// the session needs to be authenticated again.
+func New(c *config.Config, s *auth.Session, since time.Time, until time.Time) (Report, error) {
+	if until.IsZero() {
+		until = time.Now()
+	}
+
+	plays, err := history.NewPlays(c).Between(since, until)
+	if err != nil {
+		return Report{}, err
+	}
+
+	r := FromPlays(plays, since, until)
+	r.TimeRange = TimeRange(since, until)
+
+	artists, err := spotify.TopArtists(s, r.TimeRange, GENRE_ARTISTS)
+	if err == nil {
+		var tracks []spotify.Track
+		tracks, err = spotify.TopTracks(s, r.TimeRange, TOP_LIMIT)
+		if err == nil {
+			r.AddTop(artists, tracks)
+		}
+	}
+
+	switch {
+	case err == nil:
+	case errors.IsReauthenticationErr(err):
+		return Report{}, err
+	case errors.IsMissingScopeErr(err):
+		r.Note = fmt.Sprintf("Spotify's top artists, tracks and genres need spogo to be signed in again, remove %v and %v then run spogo",
+			filepath.Join(c.CachePath(), config.ACCESSTOKENFILE), filepath.Join(c.CachePath(), config.REQUESTTOKENFILE))
+	default:
+		r.Note = "Spotify's top artists, tracks and genres are unavailable, see the error log"
+	}
+
+	return r, nil
+}
+
+// Counts the plays, which are expected to be oldest first.
+func FromPlays(plays []history.Play, since time.Time, until time.Time) Report {
+	r := Report{
+		Since:      since,
+		Until:      until,
+		TopTracks:  []Count{},
+		TopArtists: []Count{},
+		TopAlbums:  []Count{},
+		Days:       []Day{},
+
+		SpotifyTracks:  []Count{},
+		SpotifyArtists: []Count{},
+		Genres:         []Genre{},
+	}
+
+	tracks := counter{}
+	artists := counter{}
+	albums := counter{}
+	days := map[string]int{}
+
+	for _, p := range plays {
+		r.Plays++
+		r.ListenedMs += p.ListenedMs
+
+		byArtists := strings.Join(p.Artists, ", ")
+
+		tracks.add(p.TrackID, Count{Name: p.Name, Artists: byArtists}, 1, p.ListenedMs)
+		albums.add(p.Album+"\x00"+byArtists, Count{Name: p.Album, Artists: byArtists}, 1, p.ListenedMs)
+
+		for _, a := range p.Artists {
+			artists.add(a, Count{Name: a}, 1, p.ListenedMs)
+		}
+
+		days[p.StartedAt.Local().Format(DAY_FORMAT)] += p.ListenedMs
+	}
+
+	r.TopTracks = tracks.top(TOP_LIMIT)
+	r.TopArtists = artists.top(TOP_LIMIT)
+	r.TopAlbums = albums.top(TOP_LIMIT)
+
+	first := since
+	if first.IsZero() && len(plays) > 0 {
+		first = plays[0].StartedAt
+	}
+
+	if !first.IsZero() {
+		for day := startOfDay(first.Local()); day.Before(until); day = day.AddDate(0, 0, 1) {
+			date := day.Format(DAY_FORMAT)
+			r.Days = append(r.Days, Day{Date: date, ListenedMs: days[date]})
+		}
+	}
+
+	return r
+}
+
+// Adds spotify's top artists and tracks, counting
+// the genres of the artists.
+func (r *Report) AddTop(artists []spotify.Artist, tracks []spotify.Track) {
+	genres := counter{}
+
+	for i, a := range artists {
+		if i < TOP_LIMIT {
+			r.SpotifyArtists = append(r.SpotifyArtists, Count{Name: a.Name})
+		}
+
+		for _, g := range a.Genres {
+			genres.add(g, Count{Name: g}, 1, 0)
+		}
+	}
+
+	for i, t := range tracks {
+		if i >= TOP_LIMIT {
+			break
+		}
+		r.SpotifyTracks = append(r.SpotifyTracks, Count{Name: t.Name, Artists: t.ArtistsString()})
+	}
+
+	// As artists are ranked, genres shared by as many
+	// artists stay in order of their highest ranked artist.
+	for _, g := range genres.top(TOP_LIMIT) {
+		r.Genres = append(r.Genres, Genre{Name: g.Name, Artists: g.Plays})
+	}
+}
+
+// Counts keyed items, keeping the order they were first seen in.
+type counter struct {
+	keys   []string
+	counts map[string]*Count
+}
+
+func (c *counter) add(key string, item Count, plays int, ms int) {
+	if c.counts == nil {
+		c.counts = map[string]*Count{}
+	}
+
+	count, ok := c.counts[key]
+	if !ok {
+		count = &item
+		c.counts[key] = count
+		c.keys = append(c.keys, key)
+	}
+
+	count.Plays += plays
+	count.ListenedMs += ms
+}
+
+// Returns the limit items played most, breaking ties by time listened.
+func (c *counter) top(limit int) []Count {
+	counts := []Count{}
+	for _, key := range c.keys {
+		counts = append(counts, *c.counts[key])
+	}
+
+	slices.SortStableFunc(counts, func(a, b Count) int {
+		return cmp.Or(cmp.Compare(b.Plays, a.Plays), cmp.Compare(b.ListenedMs, a.ListenedMs))
+	})
+
+	return counts[:min(limit, len(counts))]
+}
+
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
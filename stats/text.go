@@ -0,0 +1,158 @@
+package stats
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dionvu/spogo/spotify"
+	"github.com/mattn/go-runewidth"
+)
+
+const (
+	BAR_FULL = "█"
+
+	// Reports are rendered between these widths, cutting names short to fit.
+	MIN_TEXT_WIDTH = 40
+	MAX_TEXT_WIDTH = 100
+
+	DAY_LABEL_FORMAT = "2006-01-02 Mon"
+)
+
+// Blocks filling one more eighth of a cell each, for bars
+// finer than whole cells.
+var BAR_EIGHTHS = []string{"", "▏", "▎", "▍", "▌", "▋", "▊", "▉"}
+
+// How spotify's time ranges are described.
+var TIME_RANGE_NAMES = map[string]string{
+	spotify.TIME_RANGE_SHORT:  "last 4 weeks",
+	spotify.TIME_RANGE_MEDIUM: "last 6 months",
+	spotify.TIME_RANGE_LONG:   "last year",
+}
+
+// Renders value as a bar of up to width cells, which a value of max fills.
+func Bar(value int, max int, width int) string {
+	if max <= 0 || value <= 0 || width <= 0 {
+		return ""
+	}
+
+	eighths := min(value, max) * width * 8 / max
+
+	return strings.Repeat(BAR_FULL, eighths/8) + BAR_EIGHTHS[eighths%8]
+}
+
+// Formats a length of time in hours and minutes, such as "3h 12m".
+func Duration(ms int) string {
+	d := time.Duration(ms) * time.Millisecond
+
+	hours := int(d.Hours())
+	minutes := int(d.Minutes()) % 60
+
+	if hours == 0 {
+		return fmt.Sprintf("%dm", minutes)
+	}
+
+	return fmt.Sprintf("%dh %dm", hours, minutes)
+}
+
+// Renders the report as plain text no wider than width,
+// with a bar chart of the time listened per day.
+func (r Report) Text(width int) string {
+	width = max(width, MIN_TEXT_WIDTH)
+
+	b := strings.Builder{}
+
+	until := r.Until.Format(DAY_FORMAT)
+	if r.Since.IsZero() {
+		fmt.Fprintf(&b, "Listening stats, all time until %s\n", until)
+	} else {
+		fmt.Fprintf(&b, "Listening stats, %s to %s\n", r.Since.Format(DAY_FORMAT), until)
+	}
+
+	fmt.Fprintf(&b, "%d plays, %s listened\n", r.Plays, Duration(r.ListenedMs))
+
+	writeCounts(&b, "Top tracks", r.TopTracks, width, true)
+	writeCounts(&b, "Top artists", r.TopArtists, width, true)
+	writeCounts(&b, "Top albums", r.TopAlbums, width, true)
+	writeDays(&b, r.Days, width)
+
+	if r.Note != "" {
+		fmt.Fprintf(&b, "\n%s\n", r.Note)
+		return b.String()
+	}
+
+	fmt.Fprintf(&b, "\nOn spotify, %s\n", TIME_RANGE_NAMES[r.TimeRange])
+
+	writeCounts(&b, "Top artists", r.SpotifyArtists, width, false)
+	writeCounts(&b, "Top tracks", r.SpotifyTracks, width, false)
+
+	if len(r.Genres) > 0 {
+		b.WriteString("\nTop genres\n")
+
+		for i, g := range r.Genres {
+			fmt.Fprintf(&b, "%4d  %s %3d %s\n", i+1, fit(g.Name, width-19), g.Artists, plural(g.Artists, "artist"))
+		}
+	}
+
+	return b.String()
+}
+
+// Writes a numbered list, with plays and time listened if counted.
+func writeCounts(b *strings.Builder, title string, counts []Count, width int, counted bool) {
+	if len(counts) == 0 {
+		return
+	}
+
+	fmt.Fprintf(b, "\n%s\n", title)
+
+	for i, c := range counts {
+		name := c.Name
+		if c.Artists != "" {
+			name += " - " + c.Artists
+		}
+
+		if !counted {
+			fmt.Fprintf(b, "%4d  %s\n", i+1, runewidth.Truncate(name, width-6, "…"))
+			continue
+		}
+
+		suffix := fmt.Sprintf(" %4d %-5s %8s", c.Plays, plural(c.Plays, "play"), Duration(c.ListenedMs))
+		fmt.Fprintf(b, "%4d  %s%s\n", i+1, fit(name, width-6-len(suffix)), suffix)
+	}
+}
+
+func writeDays(b *strings.Builder, days []Day, width int) {
+	most := 0
+	for _, d := range days {
+		most = max(most, d.ListenedMs)
+	}
+
+	if most == 0 {
+		return
+	}
+
+	b.WriteString("\nListening time per day\n")
+
+	chart := width - len(DAY_LABEL_FORMAT) - 2 - 10
+
+	for _, d := range days {
+		label := d.Date
+		if t, err := time.Parse(DAY_FORMAT, d.Date); err == nil {
+			label = t.Format(DAY_LABEL_FORMAT)
+		}
+
+		fmt.Fprintf(b, "%s  %s %8s\n", label, runewidth.FillRight(Bar(d.ListenedMs, most, chart), chart), Duration(d.ListenedMs))
+	}
+}
+
+func plural(n int, word string) string {
+	if n == 1 {
+		return word
+	}
+	return word + "s"
+}
+
+// Cuts s short or pads it to exactly width cells.
+func fit(s string, width int) string {
+	return runewidth.FillRight(runewidth.Truncate(s, width, "…"), width)
+}
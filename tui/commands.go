@@ -0,0 +1,358 @@
+package tui
+
+import (
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/dionvu/spogo/config"
+	"github.com/dionvu/spogo/err"
+	"github.com/dionvu/spogo/history"
+	"github.com/dionvu/spogo/player"
+	"github.com/dionvu/spogo/spotify"
+	"github.com/dionvu/spogo/tui/views"
+	comp "github.com/dionvu/spogo/tui/views/components"
+)
+
+// A command run from the command palette. Every keymap action is also
+// a command, named after the action with spaces, such as "toggle shuffle".
+type command struct {
+	views.PaletteEntry
+
+	// Commands requiring arguments are completed,
+	// rather than run, when none are given.
+	needsArgs bool
+
+	run func(p *Program, args []string) (tea.Cmd, error)
+}
+
+// Commands taking arguments, which have no key binding.
+var paletteCommands = []command{
+	{
+		PaletteEntry: views.PaletteEntry{Name: "shuffle", Args: "<on|off>", Description: "Turn shuffle on or off"},
+		needsArgs:    true,
+		run: func(p *Program, args []string) (tea.Cmd, error) {
+			state, err := parseSwitch(args)
+			if err != nil {
+				return nil, err
+			}
+
+			p.checkReauth(p.player.Shuffle(state, p.session))
+
+			if p.PlayerState() != nil {
+				p.PlayerState().ShuffleState = state
+			}
+
+			return nil, nil
+		},
+	},
+	{
+		PaletteEntry: views.PaletteEntry{Name: "repeat", Args: "<on|off>", Description: "Turn repeat on or off"},
+		needsArgs:    true,
+		run: func(p *Program, args []string) (tea.Cmd, error) {
+			state, err := parseSwitch(args)
+			if err != nil {
+				return nil, err
+			}
+
+			p.checkReauth(p.player.Repeat(state, p.session))
+
+			if p.PlayerState() != nil {
+				p.PlayerState().RepeatState = DISABLED
+				if state {
+					p.PlayerState().RepeatState = "context"
+				}
+			}
+
+			return nil, nil
+		},
+	},
+	{
+		PaletteEntry: views.PaletteEntry{Name: "volume", Args: "<0-100>", Description: "Set the volume"},
+		needsArgs:    true,
+		run: func(p *Program, args []string) (tea.Cmd, error) {
+			vol, err := strconv.Atoi(strings.TrimSuffix(strings.Join(args, ""), "%"))
+			if err != nil || !player.IsValidVolume(vol) {
+				return nil, errors.Input.New("volume: expected a number from 0 to 100")
+			}
+
+			// Spotify doesn't have a volume control for mobile devices.
+			if p.player.Device() == nil || p.player.Device().IsMobile() {
+				return nil, errors.Input.New("volume: the device has no volume control")
+			}
+
+			p.checkReauth(p.player.SetVolume(p.session, vol))
+
+			if p.PlayerState() != nil && p.PlayerState().Device != nil {
+				p.PlayerState().Device.VolumePercent = vol
+			}
+
+			return nil, nil
+		},
+	},
+	{
+		PaletteEntry: views.PaletteEntry{Name: "device", Args: "<name>", Description: "Transfer playback to a device"},
+		needsArgs:    true,
+		run: func(p *Program, args []string) (tea.Cmd, error) {
+			devices, err := player.GetDevices(p.session)
+			if errors.IsReauthenticationErr(err) {
+				p.currentView = views.REAUTH_VIEW
+				return nil, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			d := findDevice(devices, strings.Join(args, " "))
+			if d == nil {
+				return nil, errors.Input.New("device: no device named %q", strings.Join(args, " "))
+			}
+
+			p.player.SetDevice(d, p.config)
+			p.player.Resume(p.session, p.PlayerState() != nil && p.PlayerState().IsPlaying)
+
+			return nil, nil
+		},
+	},
+	{
+		PaletteEntry: views.PaletteEntry{Name: "theme", Args: "<name>", Description: "Switch to a theme"},
+		needsArgs:    true,
+		run: func(p *Program, args []string) (tea.Cmd, error) {
+			if err := p.config.SetTheme(strings.Join(args, " ")); err != nil {
+				return nil, err
+			}
+
+			comp.SetTheme(p.config.Theme())
+
+			return nil, nil
+		},
+	},
+	{
+		PaletteEntry: views.PaletteEntry{Name: "go to artist", Args: "[name]", Description: "Search for the playing or named artist"},
+		run: func(p *Program, args []string) (tea.Cmd, error) {
+			return p.goTo(spotify.FILTER_ARTIST, views.ARTIST, args)
+		},
+	},
+	{
+		PaletteEntry: views.PaletteEntry{Name: "go to album", Args: "[name]", Description: "Search for the playing or named album"},
+		run: func(p *Program, args []string) (tea.Cmd, error) {
+			return p.goTo(spotify.FILTER_ALBUM, views.ALBUM, args)
+		},
+	},
+	{
+		PaletteEntry: views.PaletteEntry{Name: "search", Args: "<query>", Description: "Search for a query"},
+		needsArgs:    true,
+		run: func(p *Program, args []string) (tea.Cmd, error) {
+			query := strings.Join(args, " ")
+			if _, err := spotify.ParseQuery(query); err != nil {
+				return nil, err
+			}
+
+			p.search.Record(history.KIND_QUERY, query, EMPTY)
+			return p.showResults(query, p.search.SelectedType()), nil
+		},
+	},
+}
+
+// Every command of the palette. Actions list the keys bound
+// to them in the view the palette was opened from.
+func (p *Program) commands() []command {
+	km := p.config.Keymap()
+	view := keymapView(p.previousView)
+
+	cmds := []command{}
+
+	for _, a := range config.ACTIONS {
+		if a.Name == config.ACTION_COMMAND_PALETTE {
+			continue
+		}
+
+		action := a.Name
+
+		cmds = append(cmds, command{
+			PaletteEntry: views.PaletteEntry{
+				Name:        strings.ReplaceAll(action, "_", " "),
+				Description: a.Description,
+				Keys:        km.Describe(view, action),
+			},
+			run: func(p *Program, _ []string) (tea.Cmd, error) {
+				return p.runAction(action), nil
+			},
+		})
+	}
+
+	return append(cmds, paletteCommands...)
+}
+
+func (p *Program) paletteEntries() []views.PaletteEntry {
+	entries := []views.PaletteEntry{}
+	for _, c := range p.commands() {
+		entries = append(entries, c.PaletteEntry)
+	}
+	return entries
+}
+
+// Handles key presses while the command palette is open. Enter runs
+// the selected command in the view the palette was opened from,
+// keeping the palette open to show the error if it fails.
+func (p *Program) updatePalette(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "ctrl+c":
+		return tea.Quit
+
+	case "esc":
+		p.currentView = p.previousView
+		return nil
+
+	case "enter":
+		entry, args, ok := p.palette.Selected()
+		if !ok {
+			return nil
+		}
+
+		for _, c := range p.commands() {
+			if c.Name != entry.Name {
+				continue
+			}
+
+			if c.needsArgs && len(args) == 0 {
+				p.palette = p.palette.Complete()
+				return nil
+			}
+
+			p.currentView = p.previousView
+
+			cmd, err := c.run(p, args)
+			if err != nil {
+				p.currentView = views.COMMAND_VIEW
+				p.palette = p.palette.SetError(err)
+			}
+
+			return cmd
+		}
+
+		return nil
+	}
+
+	var cmd tea.Cmd
+	p.palette, cmd = p.palette.Update(msg)
+
+	return cmd
+}
+
+// Handles key presses while the help view is open. It is closed
+// by the key that opened it, back, "q" or "esc", rather than
+// quitting, and any other key scrolls it.
+func (p *Program) updateHelp(msg tea.KeyMsg) tea.Cmd {
+	if msg.String() == "ctrl+c" {
+		return tea.Quit
+	}
+
+	action, pending := p.resolveKey(msg.String())
+	if pending {
+		return nil
+	}
+
+	switch {
+	case msg.String() == "q", msg.String() == "esc",
+		action == config.ACTION_HELP_VIEW, action == config.ACTION_BACK:
+		p.currentView = p.previousView
+		return nil
+	}
+
+	var cmd tea.Cmd
+	p.help, cmd = p.help.Update(msg)
+
+	return cmd
+}
+
+// Searches for the given artist or album, or the one of the track
+// selected in the recent view or else the playing track if no name
+// is given, showing the results.
+func (p *Program) goTo(filter string, searchType string, args []string) (tea.Cmd, error) {
+	name := strings.Join(args, " ")
+
+	if played := p.recent.Selected(); name == EMPTY && p.currentView == views.RECENT_VIEW && played != nil {
+		switch filter {
+		case spotify.FILTER_ARTIST:
+			if len(played.Artists) == 0 {
+				return nil, errors.Input.New("the selected track has no artist")
+			}
+			name = played.Artists[0]
+		case spotify.FILTER_ALBUM:
+			name = played.Album
+		}
+	}
+
+	if name == EMPTY {
+		if p.PlayerState() == nil || p.PlayerState().Track == nil {
+			return nil, errors.Input.New("nothing is playing")
+		}
+
+		track := p.PlayerState().Track
+
+		switch filter {
+		case spotify.FILTER_ARTIST:
+			if len(track.Artists) == 0 {
+				return nil, errors.Input.New("the playing track has no artist")
+			}
+			name = track.Artists[0].Name
+		case spotify.FILTER_ALBUM:
+			name = track.Album.Name
+		}
+	}
+
+	query := spotify.Query{Filters: []spotify.QueryFilter{{Field: filter, Value: name}}}
+
+	return p.showResults(query.String(), searchType), nil
+}
+
+// Searches for the query and switches to its results, which
+// are shown once the returned command has fetched them.
+func (p *Program) showResults(query string, searchType string) tea.Cmd {
+	p.search.Input = p.search.Input.SetQuery(query).HideCursor()
+	p.currentView = views.SEARCH_VIEW_RESULTS
+
+	return p.search.Refresh(query, searchType)
+}
+
+// Switches to reauthenticating if the error requires it.
+func (p *Program) checkReauth(err error) {
+	if errors.IsReauthenticationErr(err) {
+		p.currentView = views.REAUTH_VIEW
+	}
+}
+
+// Finds a device by name, ignoring case. An exact match
+// is preferred over a name starting with the given one.
+func findDevice(devices *[]player.Device, name string) *player.Device {
+	if devices == nil || name == EMPTY {
+		return nil
+	}
+
+	name = strings.ToLower(name)
+
+	var prefix *player.Device
+	for i, d := range *devices {
+		switch {
+		case strings.ToLower(d.Name) == name:
+			return &(*devices)[i]
+		case prefix == nil && strings.HasPrefix(strings.ToLower(d.Name), name):
+			prefix = &(*devices)[i]
+		}
+	}
+
+	return prefix
+}
+
+// Parses "on" or "off".
+func parseSwitch(args []string) (bool, error) {
+	switch strings.ToLower(strings.Join(args, " ")) {
+	case ENABLED:
+		return true, nil
+	case DISABLED:
+		return false, nil
+	default:
+		return false, errors.Input.New("expected on or off")
+	}
+}
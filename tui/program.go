@@ -1,10 +1,12 @@
 package tui
 
 import (
+	"log"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/dionvu/spogo/config"
+	"github.com/dionvu/spogo/daemon"
 	"github.com/dionvu/spogo/player"
 	"github.com/dionvu/spogo/spotify/auth"
 	"github.com/dionvu/spogo/tui/views"
@@ -31,6 +33,13 @@ type Program struct {
 	playlistView views.Playlist
 	search       views.Search
 	help         views.Help
+	palette      views.Palette
+	stats        views.Stats
+	recent       views.Recent
+
+	// The view to return to when the help view
+	// or command palette is closed.
+	previousView string
 
 	terminal comp.Terminal
 
@@ -38,49 +47,106 @@ type Program struct {
 
 	player *player.Player
 
+	// Polls the player's state, sending its changes to events.
+	watcher *player.Watcher
+	events  <-chan player.Event
+
+	// Runs hooks, notifications and records plays on the
+	// watcher's events, unless "spogo daemon" does.
+	listeners *daemon.Listeners
+
 	config *config.Config
+
+	// Keys pressed so far of an incomplete chord.
+	pendingKeys []string
+
+	// When "config.yaml" was last read, and the error
+	// reading it if it has since become invalid.
+	configModTime time.Time
+	configErr     error
 }
 
 type tickMsg struct{}
 
+// Sent for each change in the player's state.
+type playerEventMsg struct {
+	event player.Event
+}
+
 func New(
-	auth *auth.Session, player *player.Player,
+	auth *auth.Session, pl *player.Player,
 	config *config.Config,
 ) *Program {
 	p := &Program{
 		session:     auth,
-		player:      player,
+		player:      pl,
+		watcher:     player.NewWatcher(pl, auth),
 		config:      config,
 		currentView: views.PLAYER_VIEW,
-		help:        views.NewHelpView(),
+		help:        views.NewHelpView(config.Keymap().Help()),
+
+		configModTime: config.ModTime(),
 	}
 
-	if initialState, _ := player.State(auth); initialState == nil {
-		player.Resume(auth, false)
+	if initialState, _ := pl.State(auth); initialState == nil {
+		pl.Resume(auth, false)
 	}
 
 	p.terminal.Width, p.terminal.Height = comp.GetTerminalSize()
+	p.terminal.UpdateSize()
 
-	p.playerView = views.NewPlayerView(auth, player, config)
+	comp.SetTheme(config.Theme())
+	comp.OpenImageCache(config)
+
+	if config.AlbumColors.Enabled {
+		comp.DetectBackground(config)
+	}
+
+	p.playerView = views.NewPlayerView(auth, pl, p.watcher, config)
+	p.events = p.watcher.Subscribe()
+	p.listeners = daemon.Listen(config, p.watcher, true)
+	p.listeners.SetFocused(true)
 	p.playlistView = views.NewPlaylistView(auth, p.terminal, config)
 	p.search = views.NewSearch(p.session, p.config)
+	p.stats = views.NewStats(p.session, p.config)
+	p.recent = views.NewRecent(p.session, p.config, p.playlistView.UserPlaylists)
 
 	return p
 }
 
 func (program *Program) Run() error {
-	tp := tea.NewProgram(program, tea.WithAltScreen(), tea.WithMouseCellMotion())
-	if _, err := tp.Run(); err != nil {
-		return err
-	}
+	tp := tea.NewProgram(program, tea.WithAltScreen(), tea.WithMouseCellMotion(), tea.WithReportFocus())
+	_, err := tp.Run()
 
-	return nil
+	program.listeners.Stop()
+
+	return err
 }
 
 func (p *Program) Init() tea.Cmd {
-	p.playerView.UpdateStateLoop(p.session, p.config)
+	// A copy, as the config is replaced when it is reloaded. Signing
+	// in only needs the credentials, which are never reloaded.
+	cfg := *p.config
+
+	go func() {
+		if err := p.watcher.Run(&cfg, nil); err != nil {
+			log.Fatal("ERR: Failed to reauthenticate: ", err)
+		}
+	}()
+
+	return tea.Batch(
+		tea.Tick(UPDATE_RATE_SEC, func(time.Time) tea.Msg {
+			return tickMsg{}
+		}),
+		p.watchConfig(),
+		p.nextEvent(),
+		p.search.LoadMarket(),
+	)
+}
 
-	return tea.Tick(UPDATE_RATE_SEC, func(time.Time) tea.Msg {
-		return tickMsg{}
-	})
+// Waits for the next change in the player's state.
+func (p *Program) nextEvent() tea.Cmd {
+	return func() tea.Msg {
+		return playerEventMsg{event: <-p.events}
+	}
 }
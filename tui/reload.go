@@ -0,0 +1,118 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/dionvu/spogo/config"
+	"github.com/dionvu/spogo/tui/views"
+	comp "github.com/dionvu/spogo/tui/views/components"
+	"github.com/joomcode/errorx"
+)
+
+const CONFIG_POLL_RATE = time.Second
+
+// Sent when "config.yaml" hasn't changed since it was last checked.
+type configPollMsg struct{}
+
+// Sent when "config.yaml" has changed, carrying the config read
+// from it, or the error making it invalid.
+type configChangedMsg struct {
+	config  *config.Config
+	modTime time.Time
+	err     error
+}
+
+// Checks "config.yaml" for changes, reading it again once it
+// has been modified since it was last read.
+func (p *Program) watchConfig() tea.Cmd {
+	// A copy, as the config is replaced while it is read.
+	cfg := *p.config
+	last := p.configModTime
+
+	return tea.Tick(CONFIG_POLL_RATE, func(time.Time) tea.Msg {
+		modTime := cfg.ModTime()
+		if modTime.Equal(last) {
+			return configPollMsg{}
+		}
+
+		n, err := cfg.Reload()
+
+		return configChangedMsg{config: n, modTime: modTime, err: err}
+	})
+}
+
+// Switches every view to the changed config. Invalid configs are
+// kept from being used, showing their error until it is fixed.
+func (p *Program) configChanged(msg configChangedMsg) tea.Cmd {
+	p.configModTime = msg.modTime
+	p.configErr = msg.err
+
+	if msg.err != nil {
+		return p.watchConfig()
+	}
+
+	p.config.Apply(msg.config)
+	p.listeners.Update(p.config)
+
+	comp.SetTheme(p.config.Theme())
+
+	// The terminal is only asked for its background on start.
+	if p.config.AlbumColors.Enabled && p.config.AlbumColors.Background != EMPTY {
+		comp.DetectBackground(p.config)
+	}
+	p.playerView.ApplyAlbumColors()
+
+	p.pendingKeys = nil
+	p.help = views.NewHelpView(p.config.Keymap().Help())
+
+	return p.watchConfig()
+}
+
+// Shows why the config couldn't be reloaded on the first line of
+// the view, which is usually empty padding.
+func (p *Program) withConfigError(view string) string {
+	if p.configErr == nil {
+		return view
+	}
+
+	message := p.configErr.Error()
+	if err := errorx.Cast(p.configErr); err != nil {
+		message = err.Message()
+		if err.Cause() != nil {
+			message = err.Cause().Error()
+		}
+	}
+
+	// Only the first problem fits, the rest are listed by "spogo config check".
+	// Problems are listed a line each after the first, indented
+	// by two spaces, more for problems of a theme file.
+	if lines := strings.Split(message, "\n"); len(lines) > 1 {
+		message = strings.TrimSpace(lines[1])
+
+		more := 0
+		for _, l := range lines[2:] {
+			if !strings.HasPrefix(l, "   ") {
+				more++
+			}
+		}
+
+		if more > 0 {
+			message += fmt.Sprintf(" (and %d more)", more)
+		}
+	}
+
+	line := comp.Style.Error.Render(
+		comp.Content("config.yaml: " + message + ", using the previous config").AdjustFit(p.terminal.Width).String(),
+	)
+
+	lines := strings.SplitN(view, "\n", 2)
+	if strings.TrimSpace(lines[0]) == EMPTY {
+		lines[0] = line
+		return strings.Join(lines, "\n")
+	}
+
+	return line + "\n" + view
+}
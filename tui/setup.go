@@ -0,0 +1,337 @@
+package tui
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/dionvu/spogo/config"
+	"github.com/dionvu/spogo/err"
+	"github.com/dionvu/spogo/spotify/auth"
+	comp "github.com/dionvu/spogo/tui/views/components"
+	"github.com/joomcode/errorx"
+)
+
+// The steps of the first run setup, in order.
+const (
+	SETUP_CLIENT_ID = iota
+	SETUP_AUTH_FLOW
+	SETUP_CLIENT_SECRET
+	SETUP_THEME
+	SETUP_CHECKING
+	SETUP_DONE
+)
+
+const (
+	SETUP_INPUT_WIDTH = 40
+	SETUP_CHAR_LIMIT  = 64
+
+	SPOTIFY_DASHBOARD_URL = "https://developer.spotify.com/dashboard"
+)
+
+// Client IDs and secrets are both 32 hex characters.
+var clientKeyPattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+var authFlowNames = map[string]string{
+	config.AUTH_FLOW_CODE: "Client ID and secret",
+	config.AUTH_FLOW_PKCE: "Client ID only (PKCE)",
+}
+
+// Asks for the spotify client, how to sign in and the theme on first
+// run, writing "config.yaml" once spotify accepts the client.
+type Setup struct {
+	config *config.Config
+	step   int
+
+	input   textinput.Model
+	spotify config.Credentials
+	theme   string
+
+	// The choices of the auth flow and theme steps, each
+	// theme with its status bar as a preview.
+	themes   []string
+	previews []string
+	cursor   int
+
+	err error
+}
+
+// Sent once spotify has checked the client ID and secret,
+// err is set if spotify couldn't be asked.
+type setupCheckedMsg struct {
+	valid bool
+	err   error
+}
+
+func NewSetup(c *config.Config) *Setup {
+	s := &Setup{
+		config:  c,
+		spotify: config.Credentials{AuthFlow: config.AUTH_FLOW_CODE},
+		theme:   config.DEFAULT_THEME,
+		themes:  c.Themes(),
+	}
+
+	for _, name := range s.themes {
+		preview := ""
+		if t, err := c.LoadTheme(name); err == nil {
+			preview = comp.NewStyles(t).StatusBar.NowPlaying.Render("Now Playing")
+		}
+		s.previews = append(s.previews, preview)
+	}
+
+	s.resetInput("", false)
+
+	return s
+}
+
+// Runs the setup, returning an error if it was quit
+// before "config.yaml" was written.
+func RunSetup(c *config.Config) error {
+	s := NewSetup(c)
+
+	if _, err := tea.NewProgram(s).Run(); err != nil {
+		return errors.Input.Wrap(err, "failed to run setup")
+	}
+
+	if s.step != SETUP_DONE {
+		if s.err != nil {
+			return s.err
+		}
+		return errors.Input.New("setup quit, run spogo again to finish it")
+	}
+
+	return nil
+}
+
+func (s *Setup) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (s *Setup) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case setupCheckedMsg:
+		if msg.err != nil {
+			// Enter on the theme step checks again.
+			s.err = errors.HTTP.New("%v, enter to try again", errorMessage(msg.err))
+			s.step = SETUP_THEME
+			return s, nil
+		}
+
+		if !msg.valid {
+			s.err = errors.Input.New("spotify didn't accept the client ID and secret")
+			s.step = SETUP_CLIENT_ID
+			s.resetInput(s.spotify.ClientID, false)
+			return s, nil
+		}
+		return s, s.write()
+
+	case tea.KeyMsg:
+		switch msg.Type {
+		case tea.KeyCtrlC:
+			return s, tea.Quit
+
+		case tea.KeyEsc:
+			s.back()
+			return s, nil
+
+		case tea.KeyEnter:
+			return s, s.next()
+		}
+
+		switch s.step {
+		case SETUP_AUTH_FLOW, SETUP_THEME:
+			s.move(msg.String())
+			return s, nil
+		}
+	}
+
+	var cmd tea.Cmd
+	s.input, cmd = s.input.Update(msg)
+
+	return s, cmd
+}
+
+// Moves the cursor through the choices of the step.
+func (s *Setup) move(key string) {
+	count := len(config.AUTH_FLOWS)
+	if s.step == SETUP_THEME {
+		count = len(s.themes)
+	}
+
+	switch key {
+	case "up", "k":
+		s.cursor = (s.cursor - 1 + count) % count
+	case "down", "j", "tab":
+		s.cursor = (s.cursor + 1) % count
+	}
+}
+
+// Completes the current step, moving on to the next.
+func (s *Setup) next() tea.Cmd {
+	s.err = nil
+
+	switch s.step {
+	case SETUP_CLIENT_ID:
+		id := strings.TrimSpace(s.input.Value())
+		if !clientKeyPattern.MatchString(id) {
+			s.err = errors.Input.New("a client ID is 32 letters and numbers, copy it from the dashboard")
+			return nil
+		}
+
+		s.spotify.ClientID = id
+		s.step = SETUP_AUTH_FLOW
+		s.cursor = max(0, slices.Index(config.AUTH_FLOWS, s.spotify.AuthFlow))
+
+	case SETUP_AUTH_FLOW:
+		s.spotify.AuthFlow = config.AUTH_FLOWS[s.cursor]
+
+		if s.spotify.PKCE() {
+			s.spotify.ClientSecret = ""
+			s.toTheme()
+		} else {
+			s.step = SETUP_CLIENT_SECRET
+			s.resetInput(s.spotify.ClientSecret, true)
+		}
+
+	case SETUP_CLIENT_SECRET:
+		secret := strings.TrimSpace(s.input.Value())
+		if !clientKeyPattern.MatchString(secret) {
+			s.err = errors.Input.New("a client secret is 32 letters and numbers, copy it from the dashboard")
+			return nil
+		}
+
+		s.spotify.ClientSecret = secret
+		s.toTheme()
+
+	case SETUP_THEME:
+		s.theme = s.themes[s.cursor]
+
+		// Spotify can only check a client that has a secret.
+		if s.spotify.PKCE() {
+			return s.write()
+		}
+
+		s.step = SETUP_CHECKING
+		spotify := s.spotify
+
+		return func() tea.Msg {
+			valid, err := spotify.Valid()
+			return setupCheckedMsg{valid: valid, err: err}
+		}
+	}
+
+	return nil
+}
+
+// Returns to the previous step, keeping what was entered.
+func (s *Setup) back() {
+	s.err = nil
+
+	switch s.step {
+	case SETUP_AUTH_FLOW:
+		s.step = SETUP_CLIENT_ID
+		s.resetInput(s.spotify.ClientID, false)
+
+	case SETUP_CLIENT_SECRET:
+		s.step = SETUP_AUTH_FLOW
+		s.cursor = slices.Index(config.AUTH_FLOWS, s.spotify.AuthFlow)
+
+	case SETUP_THEME:
+		if s.spotify.PKCE() {
+			s.step = SETUP_AUTH_FLOW
+			s.cursor = slices.Index(config.AUTH_FLOWS, s.spotify.AuthFlow)
+		} else {
+			s.step = SETUP_CLIENT_SECRET
+			s.resetInput(s.spotify.ClientSecret, true)
+		}
+	}
+}
+
+func (s *Setup) toTheme() {
+	s.step = SETUP_THEME
+	s.cursor = max(0, slices.Index(s.themes, s.theme))
+}
+
+// Writes "config.yaml" and quits, or quits with the error.
+func (s *Setup) write() tea.Cmd {
+	if s.err = s.config.CreateWith(s.spotify, s.theme); s.err == nil {
+		s.step = SETUP_DONE
+	}
+
+	return tea.Quit
+}
+
+func (s *Setup) resetInput(value string, secret bool) {
+	s.input = textinput.New()
+	s.input.Width = SETUP_INPUT_WIDTH
+	s.input.CharLimit = SETUP_CHAR_LIMIT
+	s.input.SetValue(value)
+	s.input.Focus()
+
+	if secret {
+		s.input.EchoMode = textinput.EchoPassword
+	}
+}
+
+func (s *Setup) View() string {
+	lines := []string{comp.Style.Title.Render("Welcome to spogo"), ""}
+
+	switch s.step {
+	case SETUP_CLIENT_ID:
+		lines = append(lines,
+			"Create an app at "+SPOTIFY_DASHBOARD_URL+" with",
+			auth.REDIRECT_URI+" as a redirect URI, then enter its client ID.",
+			"",
+			s.input.View(),
+		)
+
+	case SETUP_AUTH_FLOW:
+		lines = append(lines, "How should spogo sign in to spotify?", "")
+
+		for i, flow := range config.AUTH_FLOWS {
+			lines = append(lines, s.choice(i, authFlowNames[flow]))
+		}
+
+	case SETUP_CLIENT_SECRET:
+		lines = append(lines, "Enter the app's client secret.", "", s.input.View())
+
+	case SETUP_THEME:
+		lines = append(lines, "Pick a theme, which can be changed later in config.yaml.", "")
+
+		for i, name := range s.themes {
+			lines = append(lines, s.choice(i, fmt.Sprintf("%-12s %s", name, s.previews[i])))
+		}
+
+	case SETUP_CHECKING:
+		lines = append(lines, "Checking the client ID and secret with spotify...")
+
+	case SETUP_DONE:
+		lines = append(lines, "Wrote "+s.config.FilePath())
+	}
+
+	if s.err != nil {
+		lines = append(lines, "", comp.Style.Error.Render(errorMessage(s.err)))
+	}
+
+	lines = append(lines, "", comp.Style.Muted.Render("enter to continue, esc to go back, ctrl+c to quit"))
+
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// The message of the error, without the prefix of its errorx type.
+func errorMessage(err error) string {
+	if e := errorx.Cast(err); e != nil {
+		return e.Message()
+	}
+	return err.Error()
+}
+
+func (s *Setup) choice(i int, text string) string {
+	if i == s.cursor {
+		return comp.Style.Selected.Render("> " + text)
+	}
+	return comp.Style.Muted.Render("  " + text)
+}
@@ -6,40 +6,16 @@ import (
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/dionvu/spogo/config"
 	"github.com/dionvu/spogo/err"
+	"github.com/dionvu/spogo/history"
 	"github.com/dionvu/spogo/player"
+	"github.com/dionvu/spogo/spotify"
 	"github.com/dionvu/spogo/tui/views"
+	comp "github.com/dionvu/spogo/tui/views/components"
 )
 
 const (
-	KEY_ESC                  = "esc"
-	KEY_ENTER                = "enter"
-	KEY_QUIT                 = "q"
-	KEY_QUIT_ALT             = "ctrl+c"
-	KEY_VISUAL_REFRESH       = "ctrl+r"
-	KEY_PLAYER_VIEW          = "f1"
-	KEY_PLAYER_VIEW_ALT      = "ctrl+o"
-	KEY_PLAY_PAUSE           = " "
-	KEY_TOGGLE_SHUFFLING     = "s"
-	KEY_TOGGLE_REPEAT        = "r"
-	KEY_PLAYLIST_VIEW        = "f2"
-	KEY_PLAYLIST_VIEW_ALT    = "ctrl+p"
-	KEY_FZF_PLAYLIST_TRACKS  = "t"
-	KEY_SEARCH_VIEW          = "f3"
-	KEY_SEARCH_VIEW_ALT      = "/"
-	KEY_DEVICE_VIEW          = "UNDEFINED"
-	KEY_HELP_VIEW            = "f4"
-	KEY_HELP_VIEW_ALT        = "ctrl+h"
-	KEY_VOLUME_DOWN_BIG      = "["
-	KEY_VOLUME_DOWN_SMALL    = "{"
-	KEY_VOLUME_UP_BIG        = "]"
-	KEY_VOLUME_UP_SMALL      = "}"
-	KEY_FZF_DEVICES          = "ctrl+d"
-	KEY_FZF_ALBUM_TRACKS     = "ctrl+a"
-	KEY_NEXT_TRACK           = ">"
-	KEY_PREV_TRACK           = "<"
-	KEY_FORWARD              = "."
-	KEY_BACKWARD             = ","
 	VOLUME_INCREMENT_PERCENT = 5
 	EMPTY                    = ""
 
@@ -49,8 +25,6 @@ const (
 
 // Handles updates associate with the current selected view.
 func (p *Program) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
-	p.terminal.UpdateSize()
-
 	if !p.terminal.IsValid() {
 		p.currentView = views.TERMINAL_WARNING_VIEW
 	}
@@ -59,14 +33,10 @@ func (p *Program) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		p.currentView = views.PLAYER_VIEW
 	}
 
-	var cmd tea.Cmd
-	if p.currentView == views.HELP_VIEW {
-		p.help, cmd = p.help.Update(msg)
-		return p, cmd
-	}
-
 	switch msg := msg.(type) {
 	case tickMsg:
+		p.playerView.Sync()
+
 		// If state is unaccessible, likely due to user closing
 		// their playerback device, and attempt reconnect to closed device.
 		if p.PlayerState() == nil {
@@ -89,336 +59,566 @@ func (p *Program) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return tickMsg{}
 		})
 
+	case playerEventMsg:
+		p.playerView.HandleEvent(msg.event)
+		return p, p.nextEvent()
+
+	case configPollMsg:
+		return p, p.watchConfig()
+
+	case configChangedMsg:
+		return p, p.configChanged(msg)
+
+	case views.SearchDebounceMsg:
+		return p, p.search.Debounced(msg)
+
+	case views.SearchResultMsg:
+		p.checkReauth(msg.Err)
+		p.search.Received(msg)
+		return p, nil
+
+	case views.SearchPageMsg:
+		p.checkReauth(msg.Err)
+		p.search.PageReceived(msg)
+		return p, nil
+
+	case views.MarketMsg:
+		p.checkReauth(msg.Err)
+		p.search.MarketLoaded(msg)
+		return p, nil
+
+	case views.StatsMsg:
+		p.checkReauth(msg.Err)
+		p.stats.Received(msg)
+		return p, nil
+
+	case views.RecentMsg:
+		p.checkReauth(msg.Err)
+		p.recent.Received(msg)
+		return p, nil
+
+	case tea.FocusMsg:
+		p.listeners.SetFocused(true)
+		return p, nil
+
+	case tea.BlurMsg:
+		p.listeners.SetFocused(false)
+		return p, nil
+
+	case tea.MouseMsg:
+		if p.currentView == views.HELP_VIEW {
+			var cmd tea.Cmd
+			p.help, cmd = p.help.Update(msg)
+			return p, cmd
+		}
+
+		if p.currentView == views.STATS_VIEW {
+			return p, p.stats.Update(msg, p.terminal)
+		}
+
+		if p.currentView == views.RECENT_VIEW {
+			return p, p.recent.Update(msg, p.terminal)
+		}
+
 	case tea.KeyMsg:
-		// Prevents search query from activating any commands, enless esc or enter.
-		key := msg.String()
+		if p.currentView == views.HELP_VIEW {
+			return p, p.updateHelp(msg)
+		}
+
+		if p.currentView == views.COMMAND_VIEW {
+			return p, p.updatePalette(msg)
+		}
+
+		action, pending := p.resolveKey(msg.String())
+		if pending {
+			return p, nil
+		}
 
-		if p.currentView != views.SEARCH_VIEW_QUERY &&
-			(key == KEY_SEARCH_VIEW || key == KEY_SEARCH_VIEW_ALT) {
-			p.search.Input.Text.Focus()
-			p.currentView = views.SEARCH_VIEW_QUERY
+		// Handled before the list updates below, so the
+		// key isn't typed into the focused query.
+		if p.currentView != views.SEARCH_VIEW_QUERY && action == config.ACTION_SEARCH_VIEW {
+			p.runAction(action)
 			return p, nil
 		}
 
-		if p.currentView == views.SEARCH_VIEW_QUERY && !IsImportantKey(key) {
+		// Likewise so the key doesn't also move the result list.
+		if p.currentView == views.SEARCH_VIEW_RESULTS && (action == config.ACTION_NEXT_TAB || action == config.ACTION_PREV_TAB) {
+			p.runAction(action)
+			return p, nil
+		}
+
+		// Prevents search query from activating any commands, unless important.
+		// Keys that type text, such as "?" for help in the vim preset, are
+		// always typed into the query.
+		if p.currentView == views.SEARCH_VIEW_QUERY && (!config.Action(action).Important || (msg.Type == tea.KeyRunes && !msg.Alt)) {
 			var cmd tea.Cmd
-			p.search.Input, cmd = p.search.Input.Update(msg)
+
+			query := p.search.Input.Query()
+			cmd = p.search.UpdateQuery(msg)
+
+			if p.config.Search.Instant && query != p.search.Input.Query() {
+				cmd = tea.Batch(cmd, p.search.QueryChanged())
+			}
+
 			return p, cmd
 		}
 
-		if p.currentView == views.SEARCH_VIEW_TYPE && !IsImportantKey(key) {
+		if p.currentView == views.SEARCH_VIEW_TYPE && !config.Action(action).Important {
 			var cmd tea.Cmd
 			p.search.TypeList, cmd = p.search.TypeList.Update(msg)
 			return p, cmd
 		}
 
-		if (key == KEY_SEARCH_VIEW || key == KEY_SEARCH_VIEW_ALT) &&
-			p.currentView != views.SEARCH_VIEW_QUERY {
-			p.currentView = views.SEARCH_VIEW_QUERY
-			return p, nil
+		if cmd := p.runAction(action); cmd != nil {
+			return p, cmd
 		}
 
-		switch msg.String() {
-		case KEY_ESC:
-			switch p.currentView {
-			case views.SEARCH_VIEW_QUERY:
-				p.currentView = views.PLAYER_VIEW
-			default:
-			}
+		var cmd tea.Cmd
 
-		case KEY_QUIT, KEY_QUIT_ALT:
-			return p, tea.Quit
+		// Handles updates from the playlist list.
+		if p.currentView == views.PLAYLIST_VIEW {
+			p.playlistView.PlaylistList, cmd = p.playlistView.PlaylistList.Update(msg)
+			return p, cmd
+		}
 
-		case KEY_PLAY_PAUSE:
-			err := p.playerView.PlayPause()
-			if errors.IsReauthenticationErr(err) {
-				p.currentView = views.REAUTH_VIEW
-			}
+		if p.currentView == views.SEARCH_VIEW_QUERY {
+			p.search.Input, cmd = p.search.Input.Update(msg)
+			return p, cmd
+		}
+
+		if p.currentView == views.SEARCH_VIEW_RESULTS {
+			p.search.Results, cmd = p.search.Results.Update(msg)
+			return p, cmd
+		}
 
-		case KEY_FZF_DEVICES:
-			p.currentView = views.DEVICE_FZF_VIEW
+		if p.currentView == views.STATS_VIEW {
+			return p, p.stats.Update(msg, p.terminal)
+		}
 
-		case KEY_PREV_TRACK:
-			if p.currentView == views.PLAYER_VIEW {
-				p.player.SkipPrev(p.session)
-			}
+		if p.currentView == views.RECENT_VIEW {
+			return p, p.recent.Update(msg, p.terminal)
+		}
+	}
 
-			const STATE_DELAY_INTERVAL = time.Second / 100
+	return p, nil
+}
 
-			time.Sleep(STATE_DELAY_INTERVAL)
+// Performs an action, triggered by a key binding or
+// from the command palette.
+func (p *Program) runAction(action string) tea.Cmd {
+	switch action {
+	case config.ACTION_BACK:
+		switch p.currentView {
+		case views.SEARCH_VIEW_QUERY, views.STATS_VIEW, views.RECENT_VIEW:
+			p.currentView = views.PLAYER_VIEW
+		default:
+		}
 
-			p.playerView.UpdateStateSync()
+	case config.ACTION_QUIT:
+		return tea.Quit
 
-		case KEY_NEXT_TRACK:
-			if p.currentView == views.PLAYER_VIEW {
-				p.player.SkipNext(p.session)
-			}
+	case config.ACTION_PLAY_PAUSE:
+		err := p.playerView.PlayPause()
+		if errors.IsReauthenticationErr(err) {
+			p.currentView = views.REAUTH_VIEW
+		}
 
-			time.Sleep(time.Second / 100)
+	case config.ACTION_SELECT_DEVICE:
+		p.currentView = views.DEVICE_FZF_VIEW
 
-			p.playerView.UpdateStateSync()
+	case config.ACTION_PREV_TRACK:
+		if p.currentView == views.PLAYER_VIEW {
+			p.player.SkipPrev(p.session)
+		}
 
-		case KEY_FORWARD:
-			if p.currentView == views.PLAYER_VIEW && p.playerView.State != nil &&
-				p.playerView.State.Track != nil {
-				pos := p.playerView.State.ProgressMs + 10000
-				if pos > p.playerView.State.Track.DurationMs {
-					pos = p.playerView.State.Track.DurationMs
-				} else if pos < 0 {
-					pos = 0
-				}
+		const STATE_DELAY_INTERVAL = time.Second / 100
 
-				p.player.Seek(pos, p.session)
+		time.Sleep(STATE_DELAY_INTERVAL)
+
+		p.playerView.UpdateStateSync()
+
+	case config.ACTION_NEXT_TRACK:
+		if p.currentView == views.PLAYER_VIEW {
+			p.player.SkipNext(p.session)
+		}
+
+		time.Sleep(time.Second / 100)
+
+		p.playerView.UpdateStateSync()
+
+	case config.ACTION_SEEK_FORWARD:
+		if p.currentView == views.PLAYER_VIEW && p.playerView.State != nil &&
+			p.playerView.State.Track != nil {
+			pos := p.playerView.State.ProgressMs + 10000
+			if pos > p.playerView.State.Track.DurationMs {
+				pos = p.playerView.State.Track.DurationMs
+			} else if pos < 0 {
+				pos = 0
 			}
 
-			time.Sleep(time.Second / 100)
+			p.player.Seek(pos, p.session)
+		}
 
-			p.playerView.UpdateStateSync()
+		time.Sleep(time.Second / 100)
 
-		case KEY_BACKWARD:
+		p.playerView.UpdateStateSync()
 
-			if p.currentView == views.PLAYER_VIEW && p.playerView.State != nil &&
-				p.playerView.State.Track != nil {
-				pos := p.playerView.State.ProgressMs - 10000
-				if pos > p.playerView.State.Track.DurationMs {
-					pos = p.playerView.State.Track.DurationMs
-				} else if pos < 0 {
-					pos = 0
-				}
+	case config.ACTION_SEEK_BACKWARD:
 
-				p.player.Seek(pos, p.session)
+		if p.currentView == views.PLAYER_VIEW && p.playerView.State != nil &&
+			p.playerView.State.Track != nil {
+			pos := p.playerView.State.ProgressMs - 10000
+			if pos > p.playerView.State.Track.DurationMs {
+				pos = p.playerView.State.Track.DurationMs
+			} else if pos < 0 {
+				pos = 0
 			}
 
-			time.Sleep(time.Second / 100)
+			p.player.Seek(pos, p.session)
+		}
 
-			p.playerView.UpdateStateSync()
+		time.Sleep(time.Second / 100)
 
-		case KEY_VOLUME_DOWN_BIG:
-			// Spotify doesn't have a volume control for mobile devices.
-			if p.player.Device() != nil && !p.player.Device().IsMobile() {
-				vol := p.PlayerState().Device.VolumePercent
-				newVol := vol - VOLUME_INCREMENT_PERCENT
+		p.playerView.UpdateStateSync()
 
-				if 0 < vol && vol <= 5 {
-					newVol = 0
-				}
+	case config.ACTION_VOLUME_DOWN:
+		// Spotify doesn't have a volume control for mobile devices.
+		if p.player.Device() != nil && !p.player.Device().IsMobile() {
+			vol := p.PlayerState().Device.VolumePercent
+			newVol := vol - VOLUME_INCREMENT_PERCENT
 
-				if !player.IsValidVolume(newVol) {
-					break
-				}
+			if 0 < vol && vol <= 5 {
+				newVol = 0
+			}
 
-				err := p.player.SetVolume(p.session, newVol)
-				if errors.IsReauthenticationErr(err) {
-					p.currentView = views.REAUTH_VIEW
-				}
-				p.playerView.State.Device.VolumePercent = newVol
+			if !player.IsValidVolume(newVol) {
+				break
 			}
 
-		case KEY_VOLUME_UP_BIG:
-			if p.player.Device() != nil && !p.player.Device().IsMobile() {
-				vol := p.PlayerState().Device.VolumePercent
-				newVol := vol + VOLUME_INCREMENT_PERCENT
+			err := p.player.SetVolume(p.session, newVol)
+			if errors.IsReauthenticationErr(err) {
+				p.currentView = views.REAUTH_VIEW
+			}
+			p.playerView.State.Device.VolumePercent = newVol
+		}
 
-				if 95 <= vol && vol < 100 {
-					newVol = 100
-				}
+	case config.ACTION_VOLUME_UP:
+		if p.player.Device() != nil && !p.player.Device().IsMobile() {
+			vol := p.PlayerState().Device.VolumePercent
+			newVol := vol + VOLUME_INCREMENT_PERCENT
 
-				if !player.IsValidVolume(newVol) {
-					break
-				}
+			if 95 <= vol && vol < 100 {
+				newVol = 100
+			}
 
-				err := p.player.SetVolume(p.session, newVol)
-				if errors.IsReauthenticationErr(err) {
-					p.currentView = views.REAUTH_VIEW
-				}
-				p.playerView.State.Device.VolumePercent = newVol
+			if !player.IsValidVolume(newVol) {
+				break
 			}
 
-		case KEY_VOLUME_DOWN_SMALL:
-			if p.player.Device() != nil && !p.player.Device().IsMobile() {
-				vol := p.PlayerState().Device.VolumePercent
-				newVol := vol - 1
+			err := p.player.SetVolume(p.session, newVol)
+			if errors.IsReauthenticationErr(err) {
+				p.currentView = views.REAUTH_VIEW
+			}
+			p.playerView.State.Device.VolumePercent = newVol
+		}
 
-				if 0 < vol && vol <= 5 {
-					newVol = 0
-				}
+	case config.ACTION_VOLUME_DOWN_SMALL:
+		if p.player.Device() != nil && !p.player.Device().IsMobile() {
+			vol := p.PlayerState().Device.VolumePercent
+			newVol := vol - 1
 
-				if !player.IsValidVolume(newVol) {
-					break
-				}
+			if 0 < vol && vol <= 5 {
+				newVol = 0
+			}
 
-				err := p.player.SetVolume(p.session, newVol)
-				if errors.IsReauthenticationErr(err) {
-					p.currentView = views.REAUTH_VIEW
-				}
-				p.playerView.State.Device.VolumePercent = newVol
+			if !player.IsValidVolume(newVol) {
+				break
 			}
 
-		case KEY_VOLUME_UP_SMALL:
-			if p.player.Device() != nil && !p.player.Device().IsMobile() {
-				vol := p.PlayerState().Device.VolumePercent
-				newVol := vol + 1
+			err := p.player.SetVolume(p.session, newVol)
+			if errors.IsReauthenticationErr(err) {
+				p.currentView = views.REAUTH_VIEW
+			}
+			p.playerView.State.Device.VolumePercent = newVol
+		}
 
-				if 95 <= vol && vol < 100 {
-					newVol = 100
-				}
+	case config.ACTION_VOLUME_UP_SMALL:
+		if p.player.Device() != nil && !p.player.Device().IsMobile() {
+			vol := p.PlayerState().Device.VolumePercent
+			newVol := vol + 1
 
-				if !player.IsValidVolume(newVol) {
-					break
-				}
+			if 95 <= vol && vol < 100 {
+				newVol = 100
+			}
 
-				err := p.player.SetVolume(p.session, newVol)
-				if errors.IsReauthenticationErr(err) {
-					p.currentView = views.REAUTH_VIEW
-				}
-				p.playerView.State.Device.VolumePercent = newVol
+			if !player.IsValidVolume(newVol) {
+				break
 			}
 
-		case KEY_PLAYER_VIEW, KEY_PLAYER_VIEW_ALT:
-			p.currentView = views.PLAYER_VIEW
+			err := p.player.SetVolume(p.session, newVol)
+			if errors.IsReauthenticationErr(err) {
+				p.currentView = views.REAUTH_VIEW
+			}
+			p.playerView.State.Device.VolumePercent = newVol
+		}
 
-		case KEY_PLAYLIST_VIEW, KEY_PLAYLIST_VIEW_ALT:
-			p.currentView = views.PLAYLIST_VIEW
+	case config.ACTION_PLAYER_VIEW:
+		p.currentView = views.PLAYER_VIEW
 
-		case KEY_SEARCH_VIEW, KEY_SEARCH_VIEW_ALT:
-			// Requires handling priority, logic is at the top.
+	case config.ACTION_PLAYLIST_VIEW:
+		p.currentView = views.PLAYLIST_VIEW
 
-		// case KEY_HELP_VIEW, KEY_HELP_VIEW_ALT:
-		// 	p.currentView = views.HELP_VIEW
+	case config.ACTION_SEARCH_VIEW:
+		p.search.Input.Text.Focus()
+		p.currentView = views.SEARCH_VIEW_QUERY
 
-		case KEY_ENTER:
-			switch p.currentView {
-			case views.PLAYLIST_VIEW:
-				pl := p.playlistView.GetSelectedPlaylist()
-				p.player.Play(pl.Uri, "", p.session)
+	case config.ACTION_STATS_VIEW:
+		// Opening the view again moves on to the next period.
+		if p.currentView == views.STATS_VIEW {
+			return p.stats.NextPeriod()
+		}
 
-				p.playerView.UpdateStateSync()
+		p.currentView = views.STATS_VIEW
+		return p.stats.Load()
 
-			case views.SEARCH_VIEW_QUERY:
-				if p.search.Input.Text.Value() != EMPTY {
-					p.search.Input = p.search.Input.HideCursor()
-					p.currentView = views.SEARCH_VIEW_TYPE
-				}
+	case config.ACTION_RECENT_VIEW:
+		p.currentView = views.RECENT_VIEW
+		return p.recent.Load()
 
-			case views.SEARCH_VIEW_TYPE:
-				p.search.Results = p.search.Results.Refresh(p.search.Input.Query(), p.search.SelectedType(), p.session)
-				p.currentView = views.SEARCH_VIEW_RESULTS
+	case config.ACTION_HELP_VIEW:
+		// Rebuilt every time, so it lists the current bindings
+		// and fits the current terminal size.
+		p.help = views.NewHelpView(p.config.Keymap().Help())
+		p.previousView = p.currentView
+		p.currentView = views.HELP_VIEW
 
-			case views.SEARCH_VIEW_RESULTS:
+	case config.ACTION_CYCLE_THEME:
+		names := p.config.Themes()
 
-				switch p.search.SelectedType() {
-				case views.TRACK:
-					if p.search.Results.SelectedTrack() == nil {
-						return p, nil
-					}
+		next := names[0]
+		for i, name := range names {
+			if name == p.config.Theme().Name {
+				next = names[(i+1)%len(names)]
+			}
+		}
 
-					err := p.player.Play(p.search.Results.SelectedTrack().Album.Uri, p.search.Results.SelectedTrack().Uri, p.session)
-					if errors.IsReauthenticationErr(err) {
-						p.currentView = views.REAUTH_VIEW
-					}
+		// A broken theme file is skipped over on the next press.
+		if err := p.config.SetTheme(next); err == nil {
+			comp.SetTheme(p.config.Theme())
+		}
 
-					p.playerView.UpdateStateSync()
+	case config.ACTION_COMMAND_PALETTE:
+		p.previousView = p.currentView
+		p.palette = views.NewPalette(p.paletteEntries())
+		p.currentView = views.COMMAND_VIEW
+
+	case config.ACTION_SELECT:
+		switch p.currentView {
+		case views.RECENT_VIEW:
+			played := p.recent.Selected()
+			if played == nil {
+				return nil
+			}
 
-				case views.ALBUM:
-					if p.search.Results.SelectedAlbum() == nil {
-						return p, nil
-					}
+			contextUri, uri := played.Replay()
 
-					err := p.player.Play(p.search.Results.SelectedAlbum().Uri, EMPTY, p.session)
-					if errors.IsReauthenticationErr(err) {
-						p.currentView = views.REAUTH_VIEW
-					}
+			p.checkReauth(p.player.Play(contextUri, uri, p.session))
+			p.playerView.UpdateStateSync()
 
-					p.playerView.UpdateStateSync()
+		case views.PLAYLIST_VIEW:
+			pl := p.playlistView.GetSelectedPlaylist()
+			p.player.Play(pl.Uri, "", p.session)
+			p.search.Record(history.KIND_PLAYLIST, pl.Name, pl.Uri)
 
-				case views.PLAYLIST:
-					if p.search.Results.SelectedPlaylist() == nil {
-						return p, nil
-					}
+			p.playerView.UpdateStateSync()
 
-					err := p.player.Play(p.search.Results.SelectedPlaylist().Uri, EMPTY, p.session)
+		case views.SEARCH_VIEW_QUERY:
+			// An empty query picks the selected suggestion, a past query
+			// is searched again and a recent item is played directly.
+			if e := p.search.SelectedSuggestion(); p.search.Input.Query() == EMPTY && e != nil {
+				if e.Kind != history.KIND_QUERY {
+					err := p.player.Play(e.Uri, EMPTY, p.session)
 					if errors.IsReauthenticationErr(err) {
 						p.currentView = views.REAUTH_VIEW
 					}
 
+					p.search.Record(e.Kind, e.Name, e.Uri)
 					p.playerView.UpdateStateSync()
 
-				default:
-					return p, nil
+					break
 				}
 
+				p.search.Input = p.search.Input.SetQuery(e.Name)
 			}
 
-		case KEY_VISUAL_REFRESH:
-			// Refreshes the terminal fixing any visual glitches. This doesn't yet force any
-			// updates to, for example, listed playlist devices.
-			go func() {
-				view := p.currentView
+			if p.search.Input.Valid() {
+				p.search.Input = p.search.Input.HideCursor()
+				p.currentView = views.SEARCH_VIEW_TYPE
+			}
 
-				p.currentView = views.REFRESH_VIEW
-				time.Sleep(UPDATE_RATE_SEC)
-				p.currentView = view
+		case views.SEARCH_VIEW_TYPE:
+			p.search.Record(history.KIND_QUERY, p.search.Input.Query(), EMPTY)
+			p.currentView = views.SEARCH_VIEW_RESULTS
 
-				cmd := exec.Command("clear")
-				cmd.Stdout = os.Stdout
-				cmd.Run()
-			}()
+			return p.search.Refresh(p.search.Input.Query(), p.search.SelectedType())
 
-		case KEY_FZF_PLAYLIST_TRACKS:
-			if p.currentView == views.PLAYLIST_VIEW {
-				p.currentView = views.PLAYLIST_TRACK_VIEW
-			}
+		case views.SEARCH_VIEW_RESULTS:
 
-		case KEY_FZF_ALBUM_TRACKS:
-			p.currentView = views.ALBUM_TRACK_VIEW
+			switch p.search.Results.CurrentType {
+			case views.TRACK:
+				if p.search.Results.SelectedTrack() == nil {
+					return nil
+				}
 
-		case KEY_TOGGLE_SHUFFLING:
-			// Enables or disables shuffling on current album or playlist.
-			state := p.PlayerState().ShuffleState
+				err := p.player.Play(p.search.Results.SelectedTrack().Album.Uri, p.search.Results.SelectedTrack().Uri, p.session)
+				if errors.IsReauthenticationErr(err) {
+					p.currentView = views.REAUTH_VIEW
+				}
 
-			p.PlayerState().ShuffleState = !state
+				p.playerView.UpdateStateSync()
 
-			p.player.Shuffle(!state, p.session)
+			case views.ALBUM:
+				if p.search.Results.SelectedAlbum() == nil {
+					return nil
+				}
 
-		case KEY_TOGGLE_REPEAT:
-			switch p.PlayerState().RepeatState {
-			case DISABLED:
-				err := p.player.Repeat(true, p.session)
+				err := p.player.Play(p.search.Results.SelectedAlbum().Uri, EMPTY, p.session)
 				if errors.IsReauthenticationErr(err) {
 					p.currentView = views.REAUTH_VIEW
 				}
 
-				p.PlayerState().RepeatState = "context"
-			default:
-				err := p.player.Repeat(false, p.session)
+				p.search.Record(history.KIND_ALBUM, p.search.Results.SelectedAlbum().Name, p.search.Results.SelectedAlbum().Uri)
+
+				p.playerView.UpdateStateSync()
+
+			case views.PLAYLIST:
+				if p.search.Results.SelectedPlaylist() == nil {
+					return nil
+				}
+
+				err := p.player.Play(p.search.Results.SelectedPlaylist().Uri, EMPTY, p.session)
+				if errors.IsReauthenticationErr(err) {
+					p.currentView = views.REAUTH_VIEW
+				}
+
+				p.search.Record(history.KIND_PLAYLIST, p.search.Results.SelectedPlaylist().Name, p.search.Results.SelectedPlaylist().Uri)
+
+				p.playerView.UpdateStateSync()
+
+			case views.ARTIST:
+				if p.search.Results.SelectedArtist() == nil {
+					return nil
+				}
+
+				err := p.player.Play(p.search.Results.SelectedArtist().Uri, EMPTY, p.session)
 				if errors.IsReauthenticationErr(err) {
 					p.currentView = views.REAUTH_VIEW
 				}
 
-				p.PlayerState().RepeatState = DISABLED
+				p.search.Record(history.KIND_ARTIST, p.search.Results.SelectedArtist().Name, p.search.Results.SelectedArtist().Uri)
+
+				p.playerView.UpdateStateSync()
+
+			case views.SHOW:
+				if p.search.Results.SelectedShow() == nil {
+					return nil
+				}
+
+				err := p.player.Play(p.search.Results.SelectedShow().Uri, EMPTY, p.session)
+				if errors.IsReauthenticationErr(err) {
+					p.currentView = views.REAUTH_VIEW
+				}
+
+				p.playerView.UpdateStateSync()
+
+			case views.EPISODE:
+				if p.search.Results.SelectedEpisode() == nil {
+					return nil
+				}
+
+				err := p.player.Play(EMPTY, p.search.Results.SelectedEpisode().Uri, p.session)
+				if errors.IsReauthenticationErr(err) {
+					p.currentView = views.REAUTH_VIEW
+				}
+
+				p.playerView.UpdateStateSync()
+
+			default:
+				return nil
 			}
+
 		}
 
-		var cmd tea.Cmd
+	case config.ACTION_REFRESH:
+		// Refreshes the terminal fixing any visual glitches. This doesn't yet force any
+		// updates to, for example, listed playlist devices.
+		go func() {
+			view := p.currentView
 
-		// Handles updates from the playlist list.
+			p.currentView = views.REFRESH_VIEW
+			time.Sleep(UPDATE_RATE_SEC)
+			p.currentView = view
+
+			cmd := exec.Command("clear")
+			cmd.Stdout = os.Stdout
+			cmd.Run()
+		}()
+
+	case config.ACTION_PLAYLIST_TRACKS:
 		if p.currentView == views.PLAYLIST_VIEW {
-			p.playlistView.PlaylistList, cmd = p.playlistView.PlaylistList.Update(msg)
-			return p, cmd
+			p.currentView = views.PLAYLIST_TRACK_VIEW
 		}
 
-		if p.currentView == views.SEARCH_VIEW_QUERY {
-			p.search.Input, cmd = p.search.Input.Update(msg)
-			return p, cmd
+	case config.ACTION_ALBUM_TRACKS:
+		p.currentView = views.ALBUM_TRACK_VIEW
+
+	case config.ACTION_TRACK_ALBUM:
+		// Errors, such as nothing playing, are only shown in the palette.
+		cmd, _ := p.goTo(spotify.FILTER_ALBUM, views.ALBUM, nil)
+		return cmd
+
+	case config.ACTION_TRACK_ARTIST:
+		cmd, _ := p.goTo(spotify.FILTER_ARTIST, views.ARTIST, nil)
+		return cmd
+
+	case config.ACTION_NEXT_TAB:
+		if p.currentView == views.SEARCH_VIEW_RESULTS {
+			p.search.Results = p.search.Results.CycleType(1)
 		}
 
+	case config.ACTION_PREV_TAB:
 		if p.currentView == views.SEARCH_VIEW_RESULTS {
-			p.search.Results, cmd = p.search.Results.Update(msg)
-			return p, cmd
+			p.search.Results = p.search.Results.CycleType(-1)
+		}
+
+	case config.ACTION_TOGGLE_SHUFFLE:
+		// Enables or disables shuffling on current album or playlist.
+		state := p.PlayerState().ShuffleState
+
+		p.PlayerState().ShuffleState = !state
+
+		p.player.Shuffle(!state, p.session)
+
+	case config.ACTION_TOGGLE_REPEAT:
+		switch p.PlayerState().RepeatState {
+		case DISABLED:
+			err := p.player.Repeat(true, p.session)
+			if errors.IsReauthenticationErr(err) {
+				p.currentView = views.REAUTH_VIEW
+			}
+
+			p.PlayerState().RepeatState = "context"
+		default:
+			err := p.player.Repeat(false, p.session)
+			if errors.IsReauthenticationErr(err) {
+				p.currentView = views.REAUTH_VIEW
+			}
+
+			p.PlayerState().RepeatState = DISABLED
 		}
 	}
 
-	return p, nil
+	return nil
 }
 
 // Returns the player state from the model's player view.
@@ -426,26 +626,50 @@ func (p *Program) PlayerState() *player.State {
 	return p.playerView.State
 }
 
-// Returns is a keys that should always go through,
-// for exmaple keys to change the view, quit keys
-// are excluded since search query uses q.
-func IsImportantKey(key string) bool {
-	keys := []string{
-		KEY_ENTER,
-		KEY_PLAYER_VIEW, KEY_PLAYER_VIEW_ALT,
-		KEY_PLAYLIST_VIEW, KEY_PLAYER_VIEW_ALT,
-		KEY_SEARCH_VIEW, KEY_SEARCH_VIEW_ALT,
-		KEY_DEVICE_VIEW,
-		KEY_HELP_VIEW, KEY_HELP_VIEW_ALT,
-		KEY_FZF_DEVICES,
-		KEY_FZF_ALBUM_TRACKS,
+// Resolves the pressed key to an action using the keymap of the
+// current view. Keys are collected while they form the start of a
+// chord, in which case pending is returned. While the user is typing
+// a search query, chords are ignored so every key reaches the query.
+func (p *Program) resolveKey(key string) (action string, pending bool) {
+	km := p.config.Keymap()
+	view := keymapView(p.currentView)
+
+	if p.currentView == views.SEARCH_VIEW_QUERY {
+		p.pendingKeys = nil
+		action, _ = km.Lookup(view, []string{key})
+		return action, false
 	}
 
-	for _, k := range keys {
-		if key == k {
-			return true
+	keys := append(p.pendingKeys, key)
+
+	action, pending = km.Lookup(view, keys)
+	if pending {
+		p.pendingKeys = keys
+		return "", true
+	}
+
+	p.pendingKeys = nil
+
+	// The chord was broken, so the key is looked up on its own.
+	if action == "" && len(keys) > 1 {
+		action, pending = km.Lookup(view, []string{key})
+		if pending {
+			p.pendingKeys = []string{key}
+			return "", true
 		}
 	}
 
-	return false
+	return action, false
+}
+
+// Maps a view to the keymap view whose bindings apply to it.
+func keymapView(view string) string {
+	switch view {
+	case views.PLAYLIST_VIEW:
+		return config.KEYMAP_VIEW_PLAYLIST
+	case views.SEARCH_VIEW_QUERY, views.SEARCH_VIEW_TYPE, views.SEARCH_VIEW_RESULTS:
+		return config.KEYMAP_VIEW_SEARCH
+	default:
+		return config.KEYMAP_VIEW_PLAYER
+	}
 }
@@ -15,7 +15,13 @@ import (
 
 const MSG_NO_CONTENT = "Content is unavailable :("
 
+// Renders the current view, drawing any album art
+// using graphics protocols once it is laid out.
 func (p *Program) View() string {
+	return comp.PlaceImages(p.withConfigError(p.view()))
+}
+
+func (p *Program) view() string {
 	switch p.currentView {
 	case views.PLAYER_VIEW:
 		if p.playerView.State != nil && p.playerView.State.CurrentPlayingType == views.EPISODE {
@@ -29,7 +35,16 @@ func (p *Program) View() string {
 		return p.playlistView.View(p.playerView, p.terminal)
 
 	case views.HELP_VIEW:
-		return p.help.View()
+		return comp.Content(p.help.View()).CenterVertical(p.terminal).CenterHorizontal(p.terminal).String()
+
+	case views.COMMAND_VIEW:
+		return p.palette.View(p.terminal)
+
+	case views.STATS_VIEW:
+		return p.stats.View(p.terminal)
+
+	case views.RECENT_VIEW:
+		return p.recent.View(p.terminal)
 
 	case views.REAUTH_VIEW:
 		err := p.session.Reauth(p.config)
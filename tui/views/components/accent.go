@@ -0,0 +1,226 @@
+package components
+
+import (
+	"image"
+	_ "image/jpeg"
+	"math"
+	"os"
+	"sync"
+
+	lg "github.com/charmbracelet/lipgloss"
+	"github.com/dionvu/spogo/config"
+	"github.com/dionvu/spogo/err"
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+const (
+	// Pixels sampled along each side of the album art.
+	ACCENT_SAMPLE_SIZE = 64
+
+	// Bits kept of each color channel when grouping similar colors.
+	ACCENT_CHANNEL_BITS = 4
+
+	// Minimum WCAG contrast ratios, for text and for
+	// other elements such as the progress bar.
+	MIN_TEXT_CONTRAST = 4.5
+	MIN_UI_CONTRAST   = 3.0
+)
+
+var (
+	darkBackground  = colorful.Color{R: 0.12, G: 0.12, B: 0.12}
+	lightBackground = colorful.Color{R: 0.96, G: 0.96, B: 0.96}
+	black           = colorful.Color{R: 0, G: 0, B: 0}
+	white           = colorful.Color{R: 1, G: 1, B: 1}
+)
+
+// The terminal's background, which album colors are kept readable against.
+var background = darkBackground
+
+// Album colors already extracted, by mode and image url.
+var accents = struct {
+	sync.Mutex
+	colors map[string]*colorful.Color
+}{colors: map[string]*colorful.Color{}}
+
+// Sets the background album colors are kept readable against, from the
+// config or by asking the terminal. Must be called before the program
+// starts reading input, since the terminal answers on stdin.
+func DetectBackground(cfg *config.Config) {
+	if c, err := colorful.Hex(cfg.AlbumColors.Background); err == nil {
+		background = c
+		return
+	}
+
+	if lg.HasDarkBackground() {
+		background = darkBackground
+	} else {
+		background = lightBackground
+	}
+}
+
+// Returns the color of the album art at path for the given mode,
+// cached by the mode and the image's url. False is returned if the
+// image can't be read or has no usable color.
+func AlbumAccent(url string, path string, mode string) (colorful.Color, bool) {
+	accents.Lock()
+	defer accents.Unlock()
+
+	key := mode + " " + url
+
+	if c, ok := accents.colors[key]; ok {
+		return derefAccent(c)
+	}
+
+	c, err := extractAccent(path, mode)
+	if err != nil {
+		errors.Log(err)
+	}
+
+	// Failures are cached too, so a broken image isn't decoded every render.
+	accents.colors[key] = c
+
+	return derefAccent(c)
+}
+
+func derefAccent(c *colorful.Color) (colorful.Color, bool) {
+	if c == nil {
+		return colorful.Color{}, false
+	}
+	return *c, true
+}
+
+// Groups the sampled pixels of the image into similar colors, returning
+// the average of the most common group, or for the vibrant mode, the
+// group scoring highest on both saturation and count.
+func extractAccent(path string, mode string) (*colorful.Color, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.FileOpen.Wrap(err, "failed to open album art: %v", path)
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, errors.Jpeg.Wrap(err, "failed to decode album art: %v", path)
+	}
+
+	type group struct {
+		count   int
+		r, g, b float64
+	}
+
+	groups := map[uint32]*group{}
+
+	bounds := img.Bounds()
+	stepX := max(1, bounds.Dx()/ACCENT_SAMPLE_SIZE)
+	stepY := max(1, bounds.Dy()/ACCENT_SAMPLE_SIZE)
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += stepY {
+		for x := bounds.Min.X; x < bounds.Max.X; x += stepX {
+			c, ok := colorful.MakeColor(img.At(x, y))
+			if !ok {
+				continue
+			}
+
+			r, g, b := c.RGB255()
+
+			const shift = 8 - ACCENT_CHANNEL_BITS
+			key := uint32(r>>shift)<<16 | uint32(g>>shift)<<8 | uint32(b>>shift)
+
+			if groups[key] == nil {
+				groups[key] = &group{}
+			}
+
+			groups[key].count++
+			groups[key].r += c.R
+			groups[key].g += c.G
+			groups[key].b += c.B
+		}
+	}
+
+	var best *colorful.Color
+	bestScore := 0.0
+
+	for _, grp := range groups {
+		n := float64(grp.count)
+		c := colorful.Color{R: grp.r / n, G: grp.g / n, B: grp.b / n}
+
+		_, s, v := c.Hsv()
+
+		// Near black, white and gray make for poor accents.
+		if v < 0.2 || s < 0.15 {
+			continue
+		}
+
+		score := n
+		if mode != config.ALBUM_COLORS_DOMINANT {
+			score = n * s * s * v
+		}
+
+		if score > bestScore {
+			bestScore = score
+			best = &c
+		}
+	}
+
+	return best, nil
+}
+
+// Replaces the theme's accent colors with c: the status bar, progress
+// bar and labels. Each is adjusted until it meets the WCAG contrast
+// ratio for its use against the terminal background.
+func withAccent(t config.Theme, c colorful.Color) config.Theme {
+	ui := readable(c, background, MIN_UI_CONTRAST)
+	paused := readable(ui.BlendLab(background, 0.35), background, MIN_UI_CONTRAST)
+
+	t.StatusBar.NowPlaying.Background = ui.Hex()
+	t.StatusBar.NowPlaying.Foreground = textOn(ui).Hex()
+	t.StatusBar.Paused.Background = paused.Hex()
+	t.StatusBar.Paused.Foreground = textOn(paused).Hex()
+
+	t.ProgressBar.Completed.Background = ui.Hex()
+	t.Labels.Foreground = readable(c, background, MIN_TEXT_CONTRAST).Hex()
+
+	return t
+}
+
+// Lightens or darkens c, away from the background, until it has at
+// least the given contrast against it.
+func readable(c colorful.Color, bg colorful.Color, min float64) colorful.Color {
+	h, chroma, l := c.Hcl()
+
+	step := 0.04
+	if luminance(bg) > 0.5 {
+		step = -step
+	}
+
+	for i := 0; contrast(c, bg) < min && i < 25; i++ {
+		l = math.Min(1, math.Max(0, l+step))
+		c = colorful.Hcl(h, chroma, l).Clamped()
+	}
+
+	return c
+}
+
+// Black or white, whichever is more readable on bg.
+func textOn(bg colorful.Color) colorful.Color {
+	if contrast(black, bg) >= contrast(white, bg) {
+		return black
+	}
+	return white
+}
+
+// The WCAG contrast ratio of two colors, from 1 to 21.
+func contrast(a colorful.Color, b colorful.Color) float64 {
+	la, lb := luminance(a), luminance(b)
+	if la < lb {
+		la, lb = lb, la
+	}
+	return (la + 0.05) / (lb + 0.05)
+}
+
+// The WCAG relative luminance of a color.
+func luminance(c colorful.Color) float64 {
+	r, g, b := c.LinearRgb()
+	return 0.2126*r + 0.7152*g + 0.0722*b
+}
@@ -0,0 +1,263 @@
+package components
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dionvu/spogo/config"
+	"github.com/dionvu/spogo/err"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	IMAGE_DOWNLOAD_TIMEOUT = 10 * time.Second
+
+	// How long a failed download is remembered before it is tried again.
+	IMAGE_RETRY_INTERVAL = 30 * time.Second
+
+	// Images downloaded at once when prefetching.
+	MAX_PREFETCHES = 4
+
+	// Names of images still being downloaded start with this.
+	IMAGE_DOWNLOAD_PREFIX = "download-"
+)
+
+// Downloaded images, stored under the hash of their url so the same
+// image is only downloaded once, wherever it is shown. Images are
+// removed in least recently used order once past the size limit.
+type ImageCache struct {
+	dir   string
+	limit int64
+
+	client   *http.Client
+	inflight singleflight.Group
+
+	mu     sync.Mutex
+	failed map[string]time.Time
+
+	// Urls downloading in the background.
+	fetching map[string]bool
+}
+
+// The cache images are downloaded to, opened with the config.
+var images *ImageCache
+
+// Opens the image cache in the config's images folder. Must be
+// called before any image is updated.
+func OpenImageCache(cfg *config.Config) error {
+	if err := os.MkdirAll(cfg.ImagesPath(), os.ModePerm); err != nil {
+		err = errors.FileCreate.Wrap(err, "creating image cache %v", cfg.ImagesPath())
+		errors.Log(err)
+		return err
+	}
+
+	images = NewImageCache(cfg.ImagesPath(), cfg.ImageCacheSize())
+
+	return nil
+}
+
+func NewImageCache(dir string, limit int64) *ImageCache {
+	return &ImageCache{
+		dir:      dir,
+		limit:    limit,
+		client:   &http.Client{Timeout: IMAGE_DOWNLOAD_TIMEOUT},
+		failed:   map[string]time.Time{},
+		fetching: map[string]bool{},
+	}
+}
+
+// The path the image at url is stored at.
+func (c *ImageCache) Path(url string) string {
+	hash := sha256.Sum256([]byte(url))
+	return filepath.Join(c.dir, hex.EncodeToString(hash[:])+FILE_EXTENSION)
+}
+
+// Returns the path of the image at url if it is cached, marking
+// it as recently used, without downloading it.
+func (c *ImageCache) Cached(url string) (string, bool) {
+	path := c.Path(url)
+
+	now := time.Now()
+	if err := os.Chtimes(path, now, now); err != nil {
+		return "", false
+	}
+
+	return path, true
+}
+
+// Returns the path of the image at url, downloading it if it isn't
+// cached. Concurrent fetches of the same url share one download.
+func (c *ImageCache) Fetch(url string) (string, error) {
+	if path, ok := c.Cached(url); ok {
+		return path, nil
+	}
+
+	path := c.Path(url)
+
+	c.mu.Lock()
+	failed := c.recentlyFailed(url)
+	c.mu.Unlock()
+
+	if failed {
+		return "", errors.PlayerViewImageCache.New("recently failed to download image: %s", url)
+	}
+
+	_, err, _ := c.inflight.Do(url, func() (interface{}, error) {
+		err := c.download(url, path)
+
+		c.mu.Lock()
+		if err != nil {
+			c.failed[url] = time.Now()
+		} else {
+			delete(c.failed, url)
+		}
+		c.mu.Unlock()
+
+		if err == nil {
+			c.evict(path)
+		}
+
+		return nil, err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+// Downloads the image at url in the background, logging any error,
+// unless it is already downloading or recently failed to.
+func (c *ImageCache) FetchInBackground(url string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.fetching[url] || c.recentlyFailed(url) {
+		return
+	}
+	c.fetching[url] = true
+
+	go func() {
+		if _, err := c.Fetch(url); err != nil {
+			errors.Log(err)
+		}
+
+		c.mu.Lock()
+		delete(c.fetching, url)
+		c.mu.Unlock()
+	}()
+}
+
+// Must be called holding mu.
+func (c *ImageCache) recentlyFailed(url string) bool {
+	failedAt, failed := c.failed[url]
+	return failed && time.Since(failedAt) < IMAGE_RETRY_INTERVAL
+}
+
+// Downloads to a temporary file first, so a partial
+// image is never read from the cache.
+func (c *ImageCache) download(url string, path string) error {
+	res, err := c.client.Get(url)
+	if err != nil {
+		return errors.HTTP.Wrap(err, "failed to download image: %s", url)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return errors.HTTP.New("failed to download image: %s, status: %v", url, res.StatusCode)
+	}
+
+	file, err := os.CreateTemp(c.dir, IMAGE_DOWNLOAD_PREFIX+"*")
+	if err != nil {
+		return errors.FileCreate.Wrap(err, "creating image file in %v", c.dir)
+	}
+	defer os.Remove(file.Name())
+
+	if _, err = io.Copy(file, res.Body); err != nil {
+		file.Close()
+		return errors.FileWrite.Wrap(err, "writing image: %s", url)
+	}
+
+	if err = file.Close(); err != nil {
+		return errors.FileWrite.Wrap(err, "writing image: %s", url)
+	}
+
+	if err = os.Rename(file.Name(), path); err != nil {
+		return errors.FileWrite.Wrap(err, "moving image to %v", path)
+	}
+
+	return nil
+}
+
+// Removes the least recently used images until the cache is
+// within its size limit, keeping the image just downloaded.
+func (c *ImageCache) evict(keep string) {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return
+	}
+
+	files := []os.FileInfo{}
+	total := int64(0)
+
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil || info.IsDir() || strings.HasPrefix(e.Name(), IMAGE_DOWNLOAD_PREFIX) {
+			continue
+		}
+
+		files = append(files, info)
+		total += info.Size()
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].ModTime().Before(files[j].ModTime())
+	})
+
+	for _, f := range files {
+		if total <= c.limit {
+			return
+		}
+
+		path := filepath.Join(c.dir, f.Name())
+		if path == keep {
+			continue
+		}
+
+		if os.Remove(path) == nil {
+			total -= f.Size()
+		}
+	}
+}
+
+// Downloads the images in the background, so they're
+// cached by the time they're shown.
+func PrefetchImages(urls []string) {
+	if images == nil {
+		return
+	}
+
+	go func() {
+		sem := make(chan struct{}, MAX_PREFETCHES)
+
+		for _, url := range urls {
+			sem <- struct{}{}
+
+			go func() {
+				defer func() { <-sem }()
+
+				if _, err := images.Fetch(url); err != nil {
+					errors.Log(err)
+				}
+			}()
+		}
+	}()
+}
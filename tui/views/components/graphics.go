@@ -0,0 +1,268 @@
+package components
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"strings"
+
+	"github.com/dionvu/spogo/err"
+	"golang.org/x/image/draw"
+)
+
+const (
+	// Kitty reads transmitted images in chunks of at most this size.
+	KITTY_CHUNK_SIZE = 4096
+
+	// Cells showing a kitty image, identified by their foreground
+	// color, with diacritics giving the row and column within it.
+	KITTY_PLACEHOLDER = "\U0010EEEE"
+
+	ITERM_JPEG_QUALITY = 90
+)
+
+// The first of the combining characters kitty uses to number
+// the rows and columns of placeholders, in order.
+var kittyDiacritics = []rune{
+	0x0305, 0x030D, 0x030E, 0x0310, 0x0312, 0x033D, 0x033E, 0x033F,
+	0x0346, 0x034A, 0x034B, 0x034C, 0x0350, 0x0351, 0x0352, 0x0357,
+	0x035B, 0x0363, 0x0364, 0x0365, 0x0366, 0x0367, 0x0368, 0x0369,
+	0x036A, 0x036B, 0x036C, 0x036D, 0x036E, 0x036F, 0x0483, 0x0484,
+	0x0485, 0x0486, 0x0487, 0x0592, 0x0593, 0x0594, 0x0595, 0x0597,
+}
+
+// Transmits the image to kitty, which then draws it wherever the
+// placeholder cells are written. Being text, the placeholders are
+// laid out, moved and cleared like any other text.
+func encodeKitty(img image.Image, id int, cols, rows, cellW, cellH int) (graphic, error) {
+	if cols > len(kittyDiacritics) || rows > len(kittyDiacritics) {
+		return graphic{}, errors.PlayerViewImageRender.New("kitty images can't be larger than %d cells", len(kittyDiacritics))
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, fit(img, cols*cellW, rows*cellH)); err != nil {
+		return graphic{}, err
+	}
+
+	data := base64.StdEncoding.EncodeToString(buf.Bytes())
+
+	var escape strings.Builder
+	for i := 0; i < len(data); i += KITTY_CHUNK_SIZE {
+		chunk := data[i:min(len(data), i+KITTY_CHUNK_SIZE)]
+
+		more := 0
+		if i+KITTY_CHUNK_SIZE < len(data) {
+			more = 1
+		}
+
+		if i == 0 {
+			fmt.Fprintf(&escape, "\x1b_Ga=T,U=1,i=%d,f=100,c=%d,r=%d,q=2,m=%d;%s\x1b\\", id, cols, rows, more, chunk)
+		} else {
+			fmt.Fprintf(&escape, "\x1b_Gm=%d;%s\x1b\\", more, chunk)
+		}
+	}
+
+	g := graphic{id: id}
+
+	cell := func(r, c int) string {
+		return KITTY_PLACEHOLDER + string(kittyDiacritics[r]) + string(kittyDiacritics[c])
+	}
+
+	var art strings.Builder
+	for r := 0; r < rows; r++ {
+		fmt.Fprintf(&art, "\x1b[38;2;%d;%d;%dm", id>>16&0xFF, id>>8&0xFF, id&0xFF)
+
+		for c := 0; c < cols; c++ {
+			if r == 0 && c == 0 {
+				art.WriteString(g.marker())
+			} else {
+				art.WriteString(cell(r, c))
+			}
+		}
+
+		art.WriteString("\x1b[39m")
+
+		if r < rows-1 {
+			art.WriteString("\n")
+		}
+	}
+
+	g.art = art.String()
+	g.escape = escape.String() + cell(0, 0)
+
+	return g, nil
+}
+
+// Sends the image as a file, which iTerm scales to the cells.
+func encodeITerm(img image.Image, id int, cols, rows, cellW, cellH int) (graphic, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, fit(img, cols*cellW, rows*cellH), &jpeg.Options{Quality: ITERM_JPEG_QUALITY}); err != nil {
+		return graphic{}, err
+	}
+
+	file := fmt.Sprintf("\x1b]1337;File=inline=1;size=%d;width=%d;height=%d;preserveAspectRatio=1:%s\a",
+		buf.Len(), cols, rows, base64.StdEncoding.EncodeToString(buf.Bytes()))
+
+	return drawnOver(file, id, cols, rows), nil
+}
+
+// Encodes the image as sixels, each a column of six pixels, using
+// a fixed palette the image is dithered to.
+func encodeSixel(img image.Image, id int, cols, rows, cellW, cellH int) (graphic, error) {
+	scaled := fit(img, cols*cellW, rows*cellH)
+	bounds := scaled.Bounds()
+
+	paletted := image.NewPaletted(bounds, palette.Plan9)
+	draw.FloydSteinberg.Draw(paletted, bounds, scaled, image.Point{})
+
+	w, h := bounds.Dx(), bounds.Dy()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "\x1bP0;1q\"1;1;%d;%d", w, h)
+
+	for i, c := range paletted.Palette {
+		r, g, bl, _ := c.RGBA()
+		fmt.Fprintf(&b, "#%d;2;%d;%d;%d", i, r*100/0xFFFF, g*100/0xFFFF, bl*100/0xFFFF)
+	}
+
+	for y := 0; y < h; y += 6 {
+		// The sixels of each color in this band of six rows.
+		bands := map[uint8][]byte{}
+		colors := []uint8{}
+
+		for dy := 0; dy < 6 && y+dy < h; dy++ {
+			for x := 0; x < w; x++ {
+				c := paletted.ColorIndexAt(x, y+dy)
+				if bands[c] == nil {
+					bands[c] = make([]byte, w)
+					colors = append(colors, c)
+				}
+				bands[c][x] |= 1 << dy
+			}
+		}
+
+		for i, c := range colors {
+			if i > 0 {
+				// Returns to the start of the band for the next color.
+				b.WriteByte('$')
+			}
+
+			fmt.Fprintf(&b, "#%d", c)
+			writeSixels(&b, bands[c])
+		}
+
+		b.WriteByte('-')
+	}
+
+	b.WriteString("\x1b\\")
+
+	return drawnOver(b.String(), id, cols, rows), nil
+}
+
+// Writes the sixels, repeating runs of the same sixel.
+func writeSixels(b *strings.Builder, sixels []byte) {
+	for x := 0; x < len(sixels); {
+		run := 1
+		for x+run < len(sixels) && sixels[x+run] == sixels[x] {
+			run++
+		}
+
+		if run > 3 {
+			fmt.Fprintf(b, "!%d%c", run, sixels[x]+'?')
+		} else {
+			b.WriteString(strings.Repeat(string(rune(sixels[x]+'?')), run))
+		}
+
+		x += run
+	}
+}
+
+// Fills the cells with spaces, drawing the image from the last cell
+// so it is drawn after every line it covers is written. The cursor
+// is moved to the first cell and restored after drawing.
+func drawnOver(seq string, id int, cols, rows int) graphic {
+	g := graphic{id: id, redraw: true}
+
+	lines := make([]string, rows)
+	for i := range lines {
+		lines[i] = strings.Repeat(" ", cols)
+	}
+	lines[rows-1] = strings.Repeat(" ", cols-1) + g.marker()
+
+	g.art = strings.Join(lines, "\n")
+
+	up := ""
+	if rows > 1 {
+		up = fmt.Sprintf("\x1b[%dA", rows-1)
+	}
+
+	g.escape = fmt.Sprintf(" \x1b7%s\x1b[%dD%s\x1b8", up, cols, seq)
+
+	return g
+}
+
+// Scales the image to fit within w by h pixels, keeping its aspect ratio.
+func fit(img image.Image, w int, h int) image.Image {
+	bounds := img.Bounds()
+	scale := math.Min(float64(w)/float64(bounds.Dx()), float64(h)/float64(bounds.Dy()))
+
+	dst := image.NewRGBA(image.Rect(0, 0,
+		max(1, int(float64(bounds.Dx())*scale)),
+		max(1, int(float64(bounds.Dy())*scale)),
+	))
+
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Src, nil)
+
+	return dst
+}
+
+// Draws two pixels in every cell with the upper half block, colored
+// by the top pixel, on a background colored by the bottom pixel.
+func encodeHalfblock(grayscale bool) encoder {
+	return func(img image.Image, id int, cols, rows, cellW, cellH int) (graphic, error) {
+		scaled := fit(img, cols, rows*2)
+		bounds := scaled.Bounds()
+
+		rgb := func(x, y int) (uint32, uint32, uint32) {
+			c := scaled.At(x, y)
+			if grayscale {
+				c = color.GrayModel.Convert(c)
+			}
+
+			r, g, b, _ := c.RGBA()
+			return r >> 8, g >> 8, b >> 8
+		}
+
+		var art strings.Builder
+		for row := 0; row < rows; row++ {
+			top, bottom := 2*row, 2*row+1
+
+			x := 0
+			for ; x < bounds.Dx() && top < bounds.Dy(); x++ {
+				r, g, b := rgb(x, top)
+				fmt.Fprintf(&art, "\x1b[38;2;%d;%d;%d", r, g, b)
+
+				if bottom < bounds.Dy() {
+					r, g, b := rgb(x, bottom)
+					fmt.Fprintf(&art, ";48;2;%d;%d;%dm▀", r, g, b)
+				} else {
+					art.WriteString(";49m▀")
+				}
+			}
+
+			art.WriteString("\x1b[0m" + strings.Repeat(" ", cols-x))
+
+			if row < rows-1 {
+				art.WriteString("\n")
+			}
+		}
+
+		return graphic{id: id, art: art.String()}, nil
+	}
+}
@@ -1,8 +1,9 @@
 package components
 
 import (
-	"io"
-	"net/http"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
 	"os"
 
 	"github.com/TheZoraiz/ascii-image-converter/aic_package"
@@ -25,8 +26,11 @@ type Image struct {
 	// The url of the image.
 	Url string
 
-	// The cached image's path.
+	// The cached image's path, empty until it is downloaded.
 	FilePath string
+
+	// The cached image decoded, for the graphics renderers.
+	decoded image.Image
 }
 
 // A string of ascii.
@@ -49,7 +53,7 @@ func (i *Image) AsciiNormal(cfg *config.Config) Ascii {
 		return Ascii(InvisibleBarV(ASCII_MEDIUM_HEIGHT/2 - 1).PadLinesLeft(ASCII_MEDIUM_WIDTH))
 	}
 
-	return i.Ascii(AsciiFlagsNormal(cfg))
+	return i.Render(cfg, ASCII_MEDIUM_HEIGHT, ASCII_MEDIUM_WIDTH)
 }
 
 // Shorthand for rendering image as ascii with size
@@ -59,7 +63,15 @@ func (i *Image) AsciiSmall(cfg *config.Config) Ascii {
 		return Ascii(InvisibleBarV(ASCII_SMALL_HEIGHT/2 - 1).PadLinesLeft(ASCII_SMALL_WIDTH))
 	}
 
-	return i.Ascii(AsciiFlagsSmall(cfg))
+	return i.Render(cfg, ASCII_SMALL_HEIGHT, ASCII_SMALL_WIDTH)
+}
+
+// Renders the image across cols by rows cells with the configured
+// renderer, kept in memory by url, size and renderer. The size
+// constants are named after the ascii flags' dimensions, which are
+// the columns followed by the rows.
+func (i *Image) Render(cfg *config.Config, cols int, rows int) Ascii {
+	return NewRenderer(cfg).Render(i, cfg, cols, rows)
 }
 
 // Renders the ascii as a string.
@@ -76,53 +88,63 @@ func (a Image) Ascii(flags aic_package.Flags) Ascii {
 func (img *Image) Update(url string) {
 	if AsciiNewUrl := url; AsciiNewUrl != img.Url {
 		img.Url = AsciiNewUrl
+		img.FilePath = ""
+		img.decoded = nil
 
-		err := img.Cache()
-		if err != nil {
-			errors.Log(errors.PlayerViewImageCache.Wrap(err, "failed to cache new image with url: %s", url))
-		}
+		img.Cached()
 	}
 }
 
-// Caches the image.
-func (img *Image) Cache() error {
-	res, err := http.Get(img.Url)
-	if err != nil {
-		return err
+// Reports whether the image is in the image cache, setting its path if
+// it is. Otherwise it is downloaded in the background, as downloading
+// would hold up the view, and is cached by a later frame.
+func (img *Image) Cached() bool {
+	if images == nil {
+		return false
 	}
 
-	file, err := os.Create(img.FilePath)
-	if err != nil {
-		return err
+	path, ok := images.Cached(img.Url)
+	if !ok {
+		images.FetchInBackground(img.Url)
+		return false
 	}
 
-	_, err = io.Copy(file, res.Body)
-	if err != nil {
-		return err
-	}
+	img.FilePath = path
 
-	return nil
+	return true
 }
 
-func AsciiFlagsNormal(cfg *config.Config) aic_package.Flags {
-	flags := aic_package.DefaultFlags()
-	flags.Dimensions = []int{ASCII_MEDIUM_HEIGHT, ASCII_MEDIUM_WIDTH}
-	flags.Threshold = cfg.Ascii.Threshold
+// Reads the cached image, once for every new url.
+func (img *Image) Decode() (image.Image, error) {
+	if img.decoded != nil {
+		return img.decoded, nil
+	}
 
-	if cfg.Ascii.Grayscale {
-		flags.Grayscale = true
-	} else {
-		flags.Colored = true
+	file, err := os.Open(img.FilePath)
+	if err != nil {
+		return nil, errors.FileOpen.Wrap(err, "failed to open image: %v", img.FilePath)
 	}
+	defer file.Close()
 
-	flags.Braille = true
+	img.decoded, _, err = image.Decode(file)
+	if err != nil {
+		return nil, errors.Jpeg.Wrap(err, "failed to decode image: %v", img.FilePath)
+	}
 
-	return flags
+	return img.decoded, nil
+}
+
+func AsciiFlagsNormal(cfg *config.Config) aic_package.Flags {
+	return asciiFlags(cfg, ASCII_MEDIUM_HEIGHT, ASCII_MEDIUM_WIDTH)
 }
 
 func AsciiFlagsSmall(cfg *config.Config) aic_package.Flags {
+	return asciiFlags(cfg, ASCII_SMALL_HEIGHT, ASCII_SMALL_WIDTH)
+}
+
+func asciiFlags(cfg *config.Config, cols int, rows int) aic_package.Flags {
 	flags := aic_package.DefaultFlags()
-	flags.Dimensions = []int{ASCII_SMALL_HEIGHT, ASCII_SMALL_WIDTH}
+	flags.Dimensions = []int{cols, rows}
 
 	flags.Threshold = cfg.Ascii.Threshold
 
@@ -37,10 +37,10 @@ func (d UniqueItemDelegate) Render(w io.Writer, m list.Model, index int, listIte
 
 	str := fmt.Sprintf("%s", i.Name)
 
-	fn := PlaylistViewStyle.ListItem.Render
+	fn := Style.Muted.PaddingLeft(4).Render
 	if index == m.Index() {
 		fn = func(s ...string) string {
-			return PlaylistViewStyle.ItemSelected.Render("> " + strings.Join(s, " "))
+			return Style.Selected.PaddingLeft(2).Render("> " + strings.Join(s, " "))
 		}
 	}
 
@@ -66,10 +66,10 @@ func (d ItemDelegate) Render(w io.Writer, m list.Model, index int, listItem list
 
 	str := fmt.Sprintf("%s", i)
 
-	fn := PlaylistViewStyle.ListItem.Render
+	fn := Style.Muted.PaddingLeft(4).Render
 	if index == m.Index() {
 		fn = func(s ...string) string {
-			return PlaylistViewStyle.ItemSelected.Render("> " + strings.Join(s, " "))
+			return Style.Selected.PaddingLeft(2).Render("> " + strings.Join(s, " "))
 		}
 	}
 
@@ -80,78 +80,6 @@ func (i ListItem) FilterValue() string {
 	return ""
 }
 
-var CommonStyle = struct {
-	MainControls struct {
-		Selected lg.Style
-		Normal   lg.Style
-	}
-}{
-	MainControls: struct {
-		Selected lg.Style
-		Normal   lg.Style
-	}{
-		Normal:   lg.NewStyle().Faint(true),
-		Selected: lg.NewStyle(),
-	},
-}
-
-var PlayerViewStyle = struct {
-	StatusBar struct {
-		NowPlaying lg.Style
-		Paused     lg.Style
-		NoPlayer   lg.Style
-	}
-}{
-	StatusBar: struct {
-		NowPlaying lg.Style
-		Paused     lg.Style
-		NoPlayer   lg.Style
-	}{
-		NowPlaying: lg.NewStyle().
-			Bold(true).
-			Foreground(lg.Color("#282828")).
-			Background(lg.Color("#98971a")).
-			PaddingLeft(1).
-			PaddingRight(1),
-
-		Paused: lg.NewStyle().
-			Bold(true).
-			Foreground(lg.Color("#282828")).
-			Background(lg.Color("#d79921")).
-			PaddingLeft(1).
-			PaddingRight(1),
-
-		NoPlayer: lg.NewStyle().
-			Bold(true).
-			Foreground(lg.Color("#282828")).
-			Background(lg.Color("#cc241d")).
-			PaddingLeft(1).
-			PaddingRight(1),
-	},
-}
-
-var PlaylistViewStyle = struct {
-	Title        lg.Style
-	ItemSelected lg.Style
-	ListItem     lg.Style
-}{
-	Title:        lg.NewStyle().Bold(true).Background(lg.Color("#458588")).Foreground(lg.Color("#282828")).PaddingLeft(1).PaddingRight(1),
-	ItemSelected: lg.NewStyle().PaddingLeft(2),
-
-	ListItem: lg.NewStyle().PaddingLeft(4).Faint(true),
-}
-
-var DeviceViewStyle = struct {
-	Title        lg.Style
-	ItemSelected lg.Style
-	ListItem     lg.Style
-}{
-	Title:        lg.NewStyle().Bold(true).Background(lg.Color("#a89984")).Foreground(lg.Color("#282828")).PaddingLeft(1).PaddingRight(1),
-	ItemSelected: lg.NewStyle().PaddingLeft(2),
-
-	ListItem: lg.NewStyle().PaddingLeft(4).Faint(true),
-}
-
 func NewDefaultList(items []list.Item, title string) list.Model {
 	l := list.New(items, ItemDelegate{}, DEFAULT_WIDTH, LIST_HEIGHT_NORMAL)
 	l.Styles.Title = lg.NewStyle().MarginLeft(0)
@@ -0,0 +1,260 @@
+package components
+
+import (
+	"fmt"
+	"image"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/dionvu/spogo/config"
+	"github.com/dionvu/spogo/err"
+)
+
+const (
+	// Art kept rendered, the least recently used is dropped past this.
+	MAX_RENDERED_ART = 32
+
+	// Private use runes marking where a graphic's escape
+	// sequences are written, one for each graphic id.
+	MARKER_START = 0xE000
+	MARKER_COUNT = 0x1900
+)
+
+// Draws an image across a number of terminal cells.
+type Renderer interface {
+	Render(img *Image, cfg *config.Config, cols int, rows int) Ascii
+}
+
+// Returns the configured renderer, detecting one from the terminal
+// for auto. Unknown renderers fall back to ascii.
+func NewRenderer(cfg *config.Config) Renderer {
+	name := cfg.Ascii.Renderer
+	if name == "" || name == config.RENDERER_AUTO {
+		name = detectedRenderer()
+	}
+
+	switch name {
+	case config.RENDERER_KITTY:
+		return graphicsRenderer{name: name, encode: encodeKitty}
+	case config.RENDERER_ITERM:
+		return graphicsRenderer{name: name, encode: encodeITerm}
+	case config.RENDERER_SIXEL:
+		return graphicsRenderer{name: name, encode: encodeSixel}
+	case config.RENDERER_HALFBLOCK:
+		return graphicsRenderer{name: name, encode: encodeHalfblock(cfg.Ascii.Grayscale), grayscale: cfg.Ascii.Grayscale}
+	default:
+		return asciiRenderer{}
+	}
+}
+
+// The environment doesn't change while running, so it is only read once.
+var detectedRenderer = sync.OnceValue(DetectRenderer)
+
+// Guesses the graphics protocol supported by the terminal from its
+// environment variables. Tmux doesn't pass graphics through, so
+// they're never used inside it.
+func DetectRenderer() string {
+	term := os.Getenv("TERM")
+	program := os.Getenv("TERM_PROGRAM")
+	colors := os.Getenv("COLORTERM")
+
+	switch {
+	case os.Getenv("TMUX") != "" || strings.HasPrefix(term, "screen") || strings.HasPrefix(term, "tmux"):
+		break
+
+	case os.Getenv("KITTY_WINDOW_ID") != "" || term == "xterm-kitty" ||
+		term == "xterm-ghostty" || program == "ghostty":
+		return config.RENDERER_KITTY
+
+	case program == "iTerm.app" || os.Getenv("LC_TERMINAL") == "iTerm2" ||
+		program == "WezTerm" || program == "mintty":
+		return config.RENDERER_ITERM
+
+	case strings.HasPrefix(term, "foot") || strings.HasPrefix(term, "mlterm") ||
+		strings.HasPrefix(term, "contour") || strings.Contains(term, "sixel"):
+		return config.RENDERER_SIXEL
+	}
+
+	if colors == "truecolor" || colors == "24bit" {
+		return config.RENDERER_HALFBLOCK
+	}
+
+	return config.RENDERER_ASCII
+}
+
+// Draws braille characters with ascii-image-converter.
+type asciiRenderer struct{}
+
+func (asciiRenderer) Render(img *Image, cfg *config.Config, cols int, rows int) Ascii {
+	key := artKey{img.Url, config.RENDERER_ASCII, cfg.Ascii.Threshold, cfg.Ascii.Grayscale, cols, rows, 0, 0}
+
+	return render(img, key, func(id int) (graphic, error) {
+		return graphic{id: id, art: img.Ascii(asciiFlags(cfg, cols, rows)).String()}, nil
+	})
+}
+
+// An image encoded for a graphics protocol, or as text.
+type graphic struct {
+	id int
+
+	// Text filling the image's cells, containing the marker
+	// where the escape sequences are written, if any.
+	art string
+
+	// Replaces the marker once the view is rendered.
+	escape string
+
+	// Sixel and iTerm images are erased by text written over
+	// them, so they are drawn again every frame.
+	redraw bool
+}
+
+func (g graphic) marker() string {
+	return string(rune(MARKER_START + g.id%MARKER_COUNT))
+}
+
+// Encodes an image to fill cols by rows cells, where each cell is
+// cellW by cellH pixels.
+type encoder func(img image.Image, id int, cols, rows, cellW, cellH int) (graphic, error)
+
+// Draws the image from its pixels, with a terminal graphics
+// protocol or half blocks. Images that fail to encode are
+// drawn as ascii instead.
+type graphicsRenderer struct {
+	name      string
+	encode    encoder
+	grayscale bool
+}
+
+func (r graphicsRenderer) Render(img *Image, cfg *config.Config, cols int, rows int) Ascii {
+	cellW, cellH := CellSize()
+	key := artKey{img.Url, r.name, 0, r.grayscale, cols, rows, cellW, cellH}
+
+	return render(img, key, func(id int) (graphic, error) {
+		decoded, err := img.Decode()
+		if err == nil {
+			var g graphic
+			if g, err = r.encode(decoded, id, cols, rows, cellW, cellH); err == nil {
+				return g, nil
+			}
+		}
+
+		errors.Log(errors.PlayerViewImageRender.Wrap(err, "failed to render image with %s: %s", r.name, img.Url))
+
+		return graphic{id: id, art: img.Ascii(asciiFlags(cfg, cols, rows)).String()}, nil
+	})
+}
+
+// Identifies rendered art by the image, its size and
+// the renderer along with its options.
+type artKey struct {
+	url          string
+	renderer     string
+	threshold    int
+	grayscale    bool
+	cols, rows   int
+	cellW, cellH int
+}
+
+// Art rendered, as converting and encoding images is too slow
+// to do every frame. The least recently used is dropped first.
+var rendered = struct {
+	sync.Mutex
+
+	byKey map[artKey]graphic
+	order []artKey
+	next  int
+
+	// Escape sequences of graphics rendered since the last frame.
+	placed map[string]graphic
+
+	// Kitty images evicted, still to be deleted from the terminal.
+	evicted []int
+
+	frame int
+}{byKey: map[artKey]graphic{}, placed: map[string]graphic{}}
+
+// Returns the art kept for the key, rendering it if there is none.
+// Blank cells are drawn for images still being downloaded.
+func render(img *Image, key artKey, draw func(id int) (graphic, error)) Ascii {
+	rendered.Lock()
+	defer rendered.Unlock()
+
+	g, ok := rendered.byKey[key]
+	if ok {
+		rendered.order = slices.DeleteFunc(rendered.order, func(k artKey) bool { return k == key })
+		rendered.order = append(rendered.order, key)
+	} else {
+		// Downloads the image again if it was removed from the cache.
+		if !img.Cached() {
+			return placeholder(key.cols, key.rows)
+		}
+
+		rendered.next++
+
+		var err error
+		if g, err = draw(rendered.next); err != nil {
+			return ""
+		}
+
+		rendered.byKey[key] = g
+		rendered.order = append(rendered.order, key)
+
+		if len(rendered.order) > MAX_RENDERED_ART {
+			oldest := rendered.order[0]
+			if oldest.renderer == config.RENDERER_KITTY {
+				rendered.evicted = append(rendered.evicted, rendered.byKey[oldest].id)
+			}
+
+			delete(rendered.byKey, oldest)
+			rendered.order = rendered.order[1:]
+		}
+	}
+
+	if g.escape != "" {
+		rendered.placed[g.marker()] = g
+	}
+
+	return Ascii(g.art)
+}
+
+// Blank cells taking the place of art not rendered yet.
+func placeholder(cols int, rows int) Ascii {
+	line := strings.Repeat(" ", cols)
+	return Ascii(strings.TrimSuffix(strings.Repeat(line+"\n", rows), "\n"))
+}
+
+// Writes the escape sequences of the graphics rendered in the view at
+// their markers. Must be called on the finished view, as the escape
+// sequences would be counted as text by the tables laying it out.
+func PlaceImages(view string) string {
+	rendered.Lock()
+	defer rendered.Unlock()
+
+	rendered.frame++
+
+	for marker, g := range rendered.placed {
+		escape := g.escape
+
+		// The renderer only writes lines that changed since the last
+		// frame, alternating between two resets changes the line.
+		if g.redraw && rendered.frame%2 == 0 {
+			escape += "\x1b[0m"
+		} else if g.redraw {
+			escape += "\x1b[m"
+		}
+
+		view = strings.Replace(view, marker, escape, 1)
+	}
+
+	clear(rendered.placed)
+
+	for _, id := range rendered.evicted {
+		view = fmt.Sprintf("\x1b_Ga=d,d=I,i=%d,q=2\x1b\\", id) + view
+	}
+	rendered.evicted = nil
+
+	return view
+}
@@ -0,0 +1,70 @@
+package components
+
+import (
+	"os"
+	"testing"
+
+	"github.com/dionvu/spogo/config"
+	"github.com/dionvu/spogo/err"
+)
+
+const FIXTURE_COVER = "testdata/cover.jpeg"
+
+// Puts the fixture cover in a new image cache, under a url
+// that is never downloaded.
+func fixtureCover(b *testing.B) (*Image, *config.Config) {
+	b.Helper()
+
+	errors.Init(b.TempDir())
+	images = NewImageCache(b.TempDir(), 1<<20)
+
+	img := &Image{Url: "https://example.com/cover.jpeg"}
+
+	cover, err := os.ReadFile(FIXTURE_COVER)
+	if err != nil {
+		b.Fatal(err)
+	}
+	if err := os.WriteFile(images.Path(img.Url), cover, 0600); err != nil {
+		b.Fatal(err)
+	}
+
+	cfg := config.Defaults()
+	cfg.Ascii.Renderer = config.RENDERER_ASCII
+
+	return img, cfg
+}
+
+// The cost of each frame drawing the album art, kept rendered.
+func BenchmarkRenderMemoized(b *testing.B) {
+	img, cfg := fixtureCover(b)
+
+	if img.AsciiNormal(cfg) == "" {
+		b.Fatal("the fixture cover wasn't rendered")
+	}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		img.AsciiNormal(cfg)
+	}
+}
+
+// The cost of each frame converting the album art again,
+// as it was before art was kept rendered.
+func BenchmarkRenderUncached(b *testing.B) {
+	img, cfg := fixtureCover(b)
+
+	if !img.Cached() {
+		b.Fatal("the fixture cover isn't cached")
+	}
+
+	flags := AsciiFlagsNormal(cfg)
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if img.Ascii(flags) == "" {
+			b.Fatal("the fixture cover wasn't converted")
+		}
+	}
+}
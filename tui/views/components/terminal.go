@@ -5,9 +5,10 @@ import (
 	"os"
 	"os/exec"
 	"os/signal"
+	"sync"
 	"syscall"
 
-	"github.com/fatih/color"
+	"golang.org/x/sys/unix"
 	"golang.org/x/term"
 )
 
@@ -18,6 +19,11 @@ const (
 	MAX_TERMINAL_HEIGHT_SMALL      = 30
 	MAX_TERMINAL_WIDTH_SMALL       = 76
 	MIN_TERMINAL_HEIGHT_NORMAL     = 40
+
+	// Assumed size of a cell in pixels, when the
+	// terminal doesn't report its size in pixels.
+	DEFAULT_CELL_WIDTH  = 10
+	DEFAULT_CELL_HEIGHT = 20
 )
 
 type Terminal struct {
@@ -25,7 +31,14 @@ type Terminal struct {
 	Width  int
 }
 
-// Asyncronously updates the terminal dimensions.
+// The size of a cell in pixels, read again when the terminal is resized.
+var cellSize = struct {
+	sync.Mutex
+	width, height int
+}{}
+
+// Asyncronously updates the terminal dimensions, along with the
+// cell size. Must only be called once.
 func (terminal *Terminal) UpdateSize() {
 	// Channel to receive terminal size change signals (SIGWINCH)
 	sigCh := make(chan os.Signal, 1)
@@ -43,6 +56,10 @@ func (terminal *Terminal) UpdateSize() {
 			}
 
 			terminal.Width, terminal.Height = w, h
+
+			cellSize.Lock()
+			cellSize.width, cellSize.height = GetCellSize()
+			cellSize.Unlock()
 		}
 	}()
 }
@@ -57,6 +74,28 @@ func GetTerminalSize() (int, int) {
 	return width, height
 }
 
+// Gets the size of a cell of the user's terminal in pixels.
+func GetCellSize() (int, int) {
+	ws, err := unix.IoctlGetWinsize(int(os.Stdout.Fd()), unix.TIOCGWINSZ)
+	if err != nil || ws.Xpixel == 0 || ws.Ypixel == 0 || ws.Col == 0 || ws.Row == 0 {
+		return DEFAULT_CELL_WIDTH, DEFAULT_CELL_HEIGHT
+	}
+
+	return int(ws.Xpixel / ws.Col), int(ws.Ypixel / ws.Row)
+}
+
+// The size of a cell in pixels, as of the last resize.
+func CellSize() (int, int) {
+	cellSize.Lock()
+	defer cellSize.Unlock()
+
+	if cellSize.width == 0 {
+		cellSize.width, cellSize.height = GetCellSize()
+	}
+
+	return cellSize.width, cellSize.height
+}
+
 // If the terminal is within the minimum dimensions.
 func (t Terminal) IsValid() bool {
 	return t.Height >= MIN_TERMINAL_HEIGHT && t.Width >= MIN_TERMINAL_WIDTH
@@ -86,7 +125,7 @@ func (t Terminal) IsSizeNormal() bool {
 // Returns the error message associated with the terminal being
 // below the required dimensions.
 func (terminal *Terminal) WarningString() string {
-	return color.RedString(
+	return Style.Error.Render(
 		fmt.Sprint(
 			"Terminal of size ",
 			terminal.Height, "x", terminal.Width,
@@ -0,0 +1,101 @@
+package components
+
+import (
+	lg "github.com/charmbracelet/lipgloss"
+	"github.com/dionvu/spogo/config"
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+// The styles of the active theme, every view renders with these.
+var Style = NewStyles(config.DefaultTheme())
+
+var (
+	// The active theme, before album colors are applied.
+	theme = config.DefaultTheme()
+
+	// Taken from the playing track's album art, if enabled.
+	accent *colorful.Color
+)
+
+// A theme converted to lipgloss styles.
+type Styles struct {
+	StatusBar struct {
+		NowPlaying lg.Style
+		Paused     lg.Style
+		NoPlayer   lg.Style
+	}
+
+	ProgressBar struct {
+		Completed lg.Style
+		Remaining lg.Style
+	}
+
+	Labels   lg.Style
+	Text     lg.Style
+	Title    lg.Style
+	Selected lg.Style
+	Muted    lg.Style
+	Error    lg.Style
+
+	Box string
+}
+
+func NewStyles(t config.Theme) Styles {
+	s := Styles{
+		Labels:   newStyle(t.Labels),
+		Text:     newStyle(t.Text),
+		Title:    newStyle(t.Title),
+		Selected: newStyle(t.Selected),
+		Muted:    newStyle(t.Muted),
+		Error:    newStyle(t.Error),
+		Box:      t.Box,
+	}
+
+	s.StatusBar.NowPlaying = newStyle(t.StatusBar.NowPlaying).PaddingLeft(1).PaddingRight(1)
+	s.StatusBar.Paused = newStyle(t.StatusBar.Paused).PaddingLeft(1).PaddingRight(1)
+	s.StatusBar.NoPlayer = newStyle(t.StatusBar.NoPlayer).PaddingLeft(1).PaddingRight(1)
+
+	s.ProgressBar.Completed = newStyle(t.ProgressBar.Completed)
+	s.ProgressBar.Remaining = newStyle(t.ProgressBar.Remaining)
+
+	return s
+}
+
+// Switches every view to the theme, taking effect on the next render.
+func SetTheme(t config.Theme) {
+	theme = t
+	applyTheme()
+}
+
+// Replaces the theme's accent colors with a color taken from
+// album art, or restores them if c is nil.
+func SetAccent(c *colorful.Color) {
+	accent = c
+	applyTheme()
+}
+
+func applyTheme() {
+	if accent != nil {
+		Style = NewStyles(withAccent(theme, *accent))
+	} else {
+		Style = NewStyles(theme)
+	}
+}
+
+func newStyle(ts config.ThemeStyle) lg.Style {
+	s := lg.NewStyle().
+		Bold(ts.Bold).
+		Faint(ts.Faint).
+		Underline(ts.Underline).
+		Reverse(ts.Reverse)
+
+	if ts.Foreground != "" {
+		s = s.Foreground(lg.Color(ts.Foreground))
+	}
+
+	if ts.Background != "" {
+		s = s.Background(lg.Color(ts.Background))
+	}
+
+	return s
+}
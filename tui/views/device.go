@@ -26,15 +26,15 @@ func (dv *Device) UpdateNumberDevices() {
 	}
 }
 
-func (dv *Device) View(term comp.Terminal, device *player.Device, config *config.Config) string {
+func (dv *Device) View(term comp.Terminal, device *player.Device, cfg *config.Config) string {
 	var currDeviceInfo string
 
 	if device == nil {
 		currDeviceInfo = comp.Content("Avaliable Devices: " + fmt.Sprint(dv.NumDevices) +
-			"\n\nCtrl+D to select a device\n\nCurrent Device: " + "none").String()
+			"\n\n" + dv.Cfg.Keymap().First(config.KEYMAP_VIEW_PLAYER, config.ACTION_SELECT_DEVICE) + " to select a device\n\nCurrent Device: " + "none").String()
 	} else {
 		currDeviceInfo = comp.Content("Avaliable Devices: " + fmt.Sprint(dv.NumDevices) +
-			"\n\nCtrl+D to select a device\n\nCurrent Device: " + device.Name + " " + "(" +
+			"\n\n" + dv.Cfg.Keymap().First(config.KEYMAP_VIEW_PLAYER, config.ACTION_SELECT_DEVICE) + " to select a device\n\nCurrent Device: " + device.Name + " " + "(" +
 			device.Type + ")").String()
 	}
 
@@ -53,12 +53,14 @@ type ViewStatus struct {
 // Renders the ViewStatus as a content string based on the
 // it's current view.
 func (vs ViewStatus) Content(cfg *config.Config) comp.Content {
+	help := cfg.Keymap().First(config.KEYMAP_VIEW_PLAYER, config.ACTION_HELP_VIEW)
+
 	style := struct {
 		Selected lg.Style
 		Normal   lg.Style
 	}{
-		Normal:   lg.NewStyle().Faint(true),
-		Selected: lg.NewStyle(),
+		Normal:   comp.Style.Muted,
+		Selected: comp.Style.Selected,
 	}
 
 	switch vs.CurrentView {
@@ -67,7 +69,7 @@ func (vs ViewStatus) Content(cfg *config.Config) comp.Content {
 			// "[ Spogo 󰝚 ] ",
 			style.Selected.Render("[ "),
 			style.Selected.Render("Player"),
-			style.Normal.Render(" - Playlists - Search - F4 Help ]"),
+			style.Normal.Render(" - Playlists - Search - Recent - Stats - " + help + " Help ]"),
 		}, "")
 
 	case PLAYLIST_VIEW:
@@ -75,14 +77,14 @@ func (vs ViewStatus) Content(cfg *config.Config) comp.Content {
 			// "[ Spogo 󰝚 ] ",
 			style.Normal.Render("[ Player - "),
 			style.Selected.Render("Playlists"),
-			style.Normal.Render(" - Search - F4 Help ]"),
+			style.Normal.Render(" - Search - Recent - Stats - " + help + " Help ]"),
 		}, "")
 
 	case HELP_VIEW:
 		return comp.Join([]string{
 			// "[ Spogo 󰝚 ] ",
-			style.Normal.Render("[ Player - Playlists - Search "),
-			style.Selected.Render("- F4 Help ]"),
+			style.Normal.Render("[ Player - Playlists - Search - Recent - Stats "),
+			style.Selected.Render("- " + help + " Help ]"),
 		}, "")
 
 	case SEARCH_VIEW_QUERY, SEARCH_VIEW_TYPE, SEARCH_VIEW_RESULTS:
@@ -90,7 +92,23 @@ func (vs ViewStatus) Content(cfg *config.Config) comp.Content {
 			// "[ Spogo 󰝚 ] ",
 			style.Normal.Render("[ Player - Playlists - "),
 			style.Selected.Render("Search"),
-			style.Normal.Render(" - F4 Help ]"),
+			style.Normal.Render(" - Recent - Stats - " + help + " Help ]"),
+		}, "")
+
+	case RECENT_VIEW:
+		return comp.Join([]string{
+			// "[ Spogo 󰝚 ] ",
+			style.Normal.Render("[ Player - Playlists - Search - "),
+			style.Selected.Render("Recent"),
+			style.Normal.Render(" - Stats - " + help + " Help ]"),
+		}, "")
+
+	case STATS_VIEW:
+		return comp.Join([]string{
+			// "[ Spogo 󰝚 ] ",
+			style.Normal.Render("[ Player - Playlists - Search - Recent - "),
+			style.Selected.Render("Stats"),
+			style.Normal.Render(" - " + help + " Help ]"),
 		}, "")
 
 	default:
@@ -117,14 +135,15 @@ type Help struct {
 	viewport viewport.Model
 }
 
-func NewHelpView() Help {
-	content := ""
-
+// Creates the help view, content is usually
+// generated from the active keymap.
+func NewHelpView(content string) Help {
 	x, y := comp.GetTerminalSize()
 
 	vp := viewport.New(int(float64(x)*0.5), int(float64(y)*0.5))
+	vp.SetContent(content)
 
-	return Help{content: string(content), viewport: vp}
+	return Help{content: content, viewport: vp}
 }
 
 func (m Help) View() string {
@@ -132,13 +151,13 @@ func (m Help) View() string {
 }
 
 func (m Help) headerView() string {
-	title := titleStyle.Render("Pager")
+	title := titleStyle.Render("Help")
 	line := strings.Repeat("─", max(0, m.viewport.Width-lg.Width(title)))
 	return lg.JoinHorizontal(lg.Center, title, line)
 }
 
 func (m Help) footerView() string {
-	info := infoStyle.Render(fmt.Sprintf("%3.f%%", m.viewport.ScrollPercent()*100))
+	info := infoStyle.Render(fmt.Sprintf("esc to close  %3.f%%", m.viewport.ScrollPercent()*100))
 	line := strings.Repeat("─", max(0, m.viewport.Width-lg.Width(info)))
 	return lg.JoinHorizontal(lg.Center, line, info)
 }
@@ -153,24 +172,8 @@ func (m Help) Update(msg tea.Msg) (Help, tea.Cmd) {
 	// m.viewport.Height = int(float64(x) * 0.5)
 	// m.viewport.Width = int(float64(y) * 0.5)
 
-	switch msg := msg.(type) {
-	case tea.KeyMsg:
-		if k := msg.String(); k == "ctrl+c" || k == "q" || k == "esc" {
-			return m, tea.Quit
-		}
-
-		// case tea.WindowSizeMsg:
-		// 	headerHeight := lg.Height(m.headerView())
-		// 	footerHeight := lg.Height(m.footerView())
-		// 	verticalMarginHeight := headerHeight + footerHeight
-		//
-		// 	m.viewport = viewport.New(msg.Width, msg.Height-verticalMarginHeight)
-		// 	m.viewport.YPosition = headerHeight
-		// 	m.viewport.SetContent(m.content)
-		//
-		// 	m.viewport.YPosition = headerHeight + 1
-		// }
-	}
+	// Closing the help view is handled by the program,
+	// since it depends on the keymap.
 
 	// Handle keyboard and mouse events in the viewport
 	m.viewport, cmd = m.viewport.Update(msg)
@@ -0,0 +1,217 @@
+package views
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	lg "github.com/charmbracelet/lipgloss"
+	comp "github.com/dionvu/spogo/tui/views/components"
+	"github.com/joomcode/errorx"
+	"github.com/sahilm/fuzzy"
+)
+
+const (
+	MAX_PALETTE_MATCHES = 10
+	PALETTE_WIDTH       = 64
+)
+
+// A command listed in the command palette.
+type PaletteEntry struct {
+	// Words typed to run the command, such as "volume".
+	Name string
+
+	// Usage of the command's arguments, such as "<0-100>",
+	// empty if the command takes none.
+	Args string
+
+	Description string
+
+	// The keys bound to the command, if it is also an action.
+	Keys string
+}
+
+// A ":" style prompt that fuzzy matches the typed text against every
+// command. Text following the full name of a command taking
+// arguments, such as "volume 40", is passed as its arguments.
+type Palette struct {
+	Input   textinput.Model
+	entries []PaletteEntry
+	matches []PaletteEntry
+	cursor  int
+	err     error
+}
+
+type paletteSource []PaletteEntry
+
+func (ps paletteSource) String(i int) string { return ps[i].Name }
+func (ps paletteSource) Len() int            { return len(ps) }
+
+func NewPalette(entries []PaletteEntry) Palette {
+	ti := textinput.New()
+	ti.Prompt = ": "
+	ti.Placeholder = "command"
+	ti.CharLimit = TEXT_INPUT_CHAR_LIMIT
+	ti.Width = PALETTE_WIDTH - 4
+
+	pl := Palette{Input: ti, entries: entries}
+
+	return pl.Open()
+}
+
+// Clears the prompt, ready to be shown again.
+func (pl Palette) Open() Palette {
+	pl.Input.SetValue("")
+	pl.Input.Focus()
+	pl.err = nil
+
+	return pl.match()
+}
+
+// Moves through the matches with the arrow keys, tab completes the
+// selected command and anything else is typed into the prompt.
+func (pl Palette) Update(msg tea.Msg) (Palette, tea.Cmd) {
+	var cmd tea.Cmd
+
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.String() {
+		case "up", "ctrl+p", "shift+tab":
+			pl.cursor = max(0, pl.cursor-1)
+			return pl, nil
+
+		case "down", "ctrl+n":
+			pl.cursor = min(len(pl.matches)-1, pl.cursor+1)
+			return pl, nil
+
+		case "tab":
+			return pl.Complete(), nil
+		}
+	}
+
+	query := pl.Input.Value()
+	pl.Input, cmd = pl.Input.Update(msg)
+
+	if query != pl.Input.Value() {
+		pl.err = nil
+		pl = pl.match()
+	}
+
+	return pl, cmd
+}
+
+// Replaces the prompt with the name of the selected command,
+// followed by a space if it takes arguments.
+func (pl Palette) Complete() Palette {
+	e, _, ok := pl.Selected()
+	if !ok {
+		return pl
+	}
+
+	if e.Args != "" {
+		pl.Input.SetValue(e.Name + " ")
+	} else {
+		pl.Input.SetValue(e.Name)
+	}
+	pl.Input.CursorEnd()
+
+	return pl.match()
+}
+
+// The selected command and the arguments typed after its name.
+func (pl Palette) Selected() (entry PaletteEntry, args []string, ok bool) {
+	if len(pl.matches) == 0 {
+		return PaletteEntry{}, nil, false
+	}
+
+	entry = pl.matches[min(pl.cursor, len(pl.matches)-1)]
+
+	// Arguments keep the case they were typed in, such as device names.
+	input := strings.TrimLeft(pl.Input.Value(), " ")
+	if entry.Args != "" && strings.HasPrefix(strings.ToLower(input), entry.Name+" ") {
+		args = strings.Fields(input[len(entry.Name)+1:])
+	}
+
+	return entry, args, true
+}
+
+// Shows an error below the prompt, such as an invalid argument.
+func (pl Palette) SetError(err error) Palette {
+	pl.err = err
+	return pl
+}
+
+// Lists the commands matching the prompt, best match first. Once the
+// full name of a command taking arguments is typed, it is the only
+// match so its arguments aren't fuzzy matched against other commands.
+func (pl Palette) match() Palette {
+	pl.cursor = 0
+
+	input := strings.ToLower(strings.TrimLeft(pl.Input.Value(), " "))
+	if input == "" {
+		pl.matches = pl.entries
+		return pl
+	}
+
+	var exact *PaletteEntry
+	for i, e := range pl.entries {
+		if e.Args != "" && strings.HasPrefix(input, e.Name+" ") && (exact == nil || len(e.Name) > len(exact.Name)) {
+			exact = &pl.entries[i]
+		}
+	}
+
+	if exact != nil {
+		pl.matches = []PaletteEntry{*exact}
+		return pl
+	}
+
+	pl.matches = []PaletteEntry{}
+	for _, m := range fuzzy.FindFrom(input, paletteSource(pl.entries)) {
+		pl.matches = append(pl.matches, pl.entries[m.Index])
+	}
+
+	return pl
+}
+
+func (pl Palette) View(term comp.Terminal) string {
+	style := struct {
+		Selected lg.Style
+		Normal   lg.Style
+	}{
+		Normal:   comp.Style.Muted,
+		Selected: comp.Style.Selected,
+	}
+
+	lines := []string{comp.Style.Title.Render("Command"), "", pl.Input.View(), ""}
+
+	if pl.err != nil {
+		lines = append(lines, comp.Style.Error.Render(comp.Content(errorx.Cast(pl.err).Message()).AdjustFit(PALETTE_WIDTH).String()), "")
+	}
+
+	// Keeps the selected match in view.
+	start := max(0, pl.cursor-MAX_PALETTE_MATCHES+1)
+
+	for i := start; i < len(pl.matches) && i < start+MAX_PALETTE_MATCHES; i++ {
+		e := pl.matches[i]
+
+		name := strings.TrimSpace(e.Name + " " + e.Args)
+		line := fmt.Sprintf("%-24s %s", name, e.Description)
+		if e.Keys != "" {
+			line += " (" + e.Keys + ")"
+		}
+		line = comp.Content(line).AdjustFit(PALETTE_WIDTH - 2).String()
+
+		if i == pl.cursor {
+			lines = append(lines, style.Selected.Render("> "+line))
+		} else {
+			lines = append(lines, style.Normal.Render("  "+line))
+		}
+	}
+
+	if len(pl.matches) == 0 {
+		lines = append(lines, style.Normal.Render("  no matching command"))
+	}
+
+	return comp.Content(lg.NewStyle().Width(PALETTE_WIDTH).Render(strings.Join(lines, "\n"))).
+		CenterVertical(term).CenterHorizontal(term).String()
+}
@@ -2,10 +2,7 @@ package views
 
 import (
 	"fmt"
-	"log"
 	"math"
-	"os"
-	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -13,7 +10,6 @@ import (
 	"github.com/Delta456/box-cli-maker/v2"
 	lg "github.com/charmbracelet/lipgloss"
 	"github.com/dionvu/spogo/config"
-	"github.com/dionvu/spogo/err"
 	"github.com/dionvu/spogo/player"
 	"github.com/dionvu/spogo/spotify"
 	"github.com/dionvu/spogo/spotify/auth"
@@ -36,17 +32,35 @@ const (
 	DEVICE_VIEW           = "device_view"
 	REAUTH_VIEW           = "reauthentication_view"
 	DEVICE_FZF_VIEW       = "device_fzf_view"
+	COMMAND_VIEW          = "command_view"
+	STATS_VIEW            = "stats_view"
+	RECENT_VIEW           = "recent_view"
 
 	UPDATE_RATE_SEC          = time.Second
 	POLLING_RATE_STATE_SEC   = time.Second * 5
 	PLAYER_MAX_CHAR          = 60
 	VOLUME_INCREMENT_PERCENT = 5
-	PLAYER_IMAGE_FILE        = "player" + comp.FILE_EXTENSION
 	ENABLED                  = "on"
 	DISABLED                 = "off"
+
+	PLACEHOLDER_IMAGE_URL = "https://i.pinimg.com/736x/ad/7a/16/ad7a164adabc065fae659a5b9dce9f69.jpg"
 )
 
-var Box = box.New(box.Config{Px: 3, Py: 1, Type: "Hidden", Color: "HiGreen", TitlePos: "Bottom"})
+// The box drawn around the player, in the theme's box color. Colors
+// are either hex codes or names box-cli-maker knows, such as "HiGreen".
+func Box() box.Box {
+	var color interface{}
+
+	if c := comp.Style.Box; strings.HasPrefix(c, "#") {
+		if hex, err := strconv.ParseUint(c[1:], 16, 32); err == nil {
+			color = uint(hex)
+		}
+	} else if c != "" {
+		color = c
+	}
+
+	return box.New(box.Config{Px: 3, Py: 1, Type: "Hidden", Color: color, TitlePos: "Bottom"})
+}
 
 // The view struct that displays player state
 // details, the current track's album art.
@@ -61,18 +75,23 @@ type Player struct {
 	// Album art image of the track currently playing.
 	image *comp.Image
 
+	// Shown in place of album art when no device is selected.
+	placeholder *comp.Image
+
 	State   *player.State
 	session *auth.Session
 	config  *config.Config
 	player  *player.Player
+	watcher *player.Watcher
 
 	// Tracks time independent of state progress
 	// to improve performance, periodically will
 	// be checked for error.
 	progressMs int
 
-	// Kept to track if progressMs is in sync with the song.
-	trackID string
+	// Changes with each state polled by the watcher,
+	// so State is only replaced when there is a new one.
+	sequence int
 }
 
 func (pv *Player) UpdateStatusBar(s *player.State) {
@@ -80,73 +99,24 @@ func (pv *Player) UpdateStatusBar(s *player.State) {
 }
 
 func NewPlayerView(
-	auth *auth.Session, player *player.Player, cfg *config.Config,
+	auth *auth.Session, player *player.Player, watcher *player.Watcher, cfg *config.Config,
 ) Player {
 	pv := Player{
 		session: auth,
 		player:  player,
+		watcher: watcher,
 		config:  cfg,
 
 		playerDetails: &PlayerDetails{},
 		statusBar:     &statusBar{},
-		image:         &comp.Image{FilePath: filepath.Join(cfg.CachePath(), IMAGES_FOLDER_NAME, PLAYER_IMAGE_FILE)},
+		image:         &comp.Image{},
+		placeholder:   &comp.Image{},
 	}
 
-	os.MkdirAll(filepath.Join(cfg.CachePath(), IMAGES_FOLDER_NAME), os.ModePerm)
-
 	pv.UpdateStateSync()
 
 	if pv.State != nil {
 		pv.progressMs = pv.State.ProgressMs
-		pv.trackID = pv.State.Track.ID
-	}
-
-	pv.statusBar.Style = struct {
-		NowPlaying lg.Style
-		Paused     lg.Style
-		NoPlayer   lg.Style
-	}{
-		NowPlaying: lg.NewStyle().
-			Bold(cfg.Player.StatusBar.NowPlaying.Bold).
-			Foreground(lg.Color(cfg.Player.StatusBar.NowPlaying.Foreground)).
-			Background(lg.Color(cfg.Player.StatusBar.NowPlaying.Background)).
-			PaddingLeft(1).
-			PaddingRight(1),
-
-		Paused: lg.NewStyle().
-			Bold(cfg.Player.StatusBar.Paused.Bold).
-			Foreground(lg.Color(cfg.Player.StatusBar.Paused.Foreground)).
-			Background(lg.Color(cfg.Player.StatusBar.Paused.Background)).
-			PaddingLeft(1).
-			PaddingRight(1),
-
-		NoPlayer: lg.NewStyle().
-			Bold(cfg.Player.StatusBar.NoPlayer.Bold).
-			Foreground(lg.Color(cfg.Player.StatusBar.NoPlayer.Foreground)).
-			Background(lg.Color(cfg.Player.StatusBar.NoPlayer.Background)).
-			PaddingLeft(1).
-			PaddingRight(1),
-	}
-
-	pv.playerDetails.Style = struct {
-		ProgressBar struct {
-			Completed lg.Style
-		}
-		Labels lg.Style
-		Text   lg.Style
-	}{
-		ProgressBar: struct {
-			Completed lg.Style
-		}{
-			Completed: lg.NewStyle().
-				Background(lg.Color(cfg.Player.ProgressBar.Completed.Color)),
-		},
-
-		Labels: lg.NewStyle().
-			Foreground(lg.Color(cfg.Player.Labels.Color)),
-
-		Text: lg.NewStyle().
-			Foreground(lg.Color(cfg.Player.Text.Color)),
 	}
 
 	pv.statusBar.Update(pv.State)
@@ -154,8 +124,11 @@ func NewPlayerView(
 	return pv
 }
 
-// Ensures that player time progress is within 2 * polling rate.
+// Advances progress between states, taking the latest state
+// from the watcher.
 func (pv *Player) EnsureProgressSynced() {
+	pv.Sync()
+
 	if pv.State == nil {
 		return
 	}
@@ -167,20 +140,37 @@ func (pv *Player) EnsureProgressSynced() {
 	if pv.State.IsPlaying && pv.progressMs < pv.State.Track.DurationMs {
 		pv.progressMs += int(UPDATE_RATE_SEC.Milliseconds())
 	}
+}
+
+// Replaces the state with the watcher's latest, if it has polled a new
+// one. Progress is only synced if it has drifted too far (5 * update
+// rate), as the events of the watcher sync it on seeks and new tracks.
+func (pv *Player) Sync() {
+	state, sequence := pv.watcher.Latest()
+	if sequence == pv.sequence {
+		return
+	}
 
-	// Syncs progress time if it differs too much (5 * Polling rate).
-	if math.Abs(float64(pv.State.ProgressMs-pv.progressMs)) >
-		float64(5*UPDATE_RATE_SEC.Milliseconds()) ||
-		pv.State.Track.ID != pv.trackID {
+	pv.State, pv.sequence = state, sequence
 
+	if pv.State != nil && (!pv.State.IsPlaying ||
+		math.Abs(float64(pv.State.ProgressMs-pv.progressMs)) > float64(5*UPDATE_RATE_SEC.Milliseconds())) {
 		pv.progressMs = pv.State.ProgressMs
-		pv.trackID = pv.State.Track.ID
 	}
+}
 
-	// Updates the progress percisly when player is paused.
-	if !pv.State.IsPlaying {
-		pv.progressMs = pv.State.ProgressMs
-		pv.trackID = pv.State.Track.ID
+// Updates the player on an event from the watcher. Progress is synced
+// precisely when the track changes, is seeked, paused or played.
+func (pv *Player) HandleEvent(e player.Event) {
+	switch e.(type) {
+	case player.TrackChanged, player.Seeked, player.PlaybackStarted, player.PlaybackPaused:
+		pv.Sync()
+
+		if state := e.Changed().State; state != nil {
+			pv.progressMs = state.ProgressMs
+		}
+
+		pv.statusBar.Update(pv.State)
 	}
 }
 
@@ -224,7 +214,7 @@ func (pv *Player) View(term comp.Terminal) string {
 			switch pv.State {
 			case nil:
 				c := comp.Join([]comp.Content{
-					"'Ctrl+D' to select a playback device",
+					comp.Content("'" + pv.deviceKey() + "' to select a playback device"),
 					pv.statusBar.Content().Prepend(NL, 1),
 					comp.InvisibleBarV(12),
 				})
@@ -232,24 +222,23 @@ func (pv *Player) View(term comp.Terminal) string {
 				mainContainer := func() comp.Content {
 					t := comp.NewDefaultTable()
 
-					img := comp.Image{FilePath: filepath.Join(pv.config.CachePath(), IMAGES_FOLDER_NAME, "temp"+comp.FILE_EXTENSION)}
-					img.Update("https://i.pinimg.com/736x/ad/7a/16/ad7a164adabc065fae659a5b9dce9f69.jpg")
+					pv.placeholder.Update(PLACEHOLDER_IMAGE_URL)
 					t.AppendRow(table.Row{
-						img.AsciiNormal(pv.config),
+						pv.placeholder.AsciiNormal(pv.config),
 						c.PadLinesLeft(3),
 					})
 
 					return comp.Content(t.Render())
 				}()
 
-				return comp.Content(Box.String(
+				return comp.Content(Box().String(
 					ViewStatus{CurrentView: PLAYER_VIEW}.Content(pv.config).String(),
 					comp.InvisibleBar(GLOBAL_VIEW_WIDTH).Append('\n', 1).String()+mainContainer.Append(NL, 1).String(),
 				))
 
 			default:
 				if len(pv.State.Track.Album.Images) > 0 {
-					pv.image.Update(pv.State.Track.Album.Images[0].Url)
+					pv.updateImage(pv.State.Track.Album.Images[0].Url)
 				}
 
 				c := comp.Join([]comp.Content{
@@ -268,7 +257,7 @@ func (pv *Player) View(term comp.Terminal) string {
 					return comp.Content(t.Render())
 				}()
 
-				// return comp.Content(Box.String(
+				// return comp.Content(Box().String(
 				// ViewStatus{CurrentView: PLAYER_VIEW}.Content(pv.config).String(),
 				// 	comp.InvisibleBar(GLOBAL_VIEW_WIDTH).Append('\n', 1).String()+mainContainer.Append(NL, 1).String(),
 				// ))
@@ -284,9 +273,9 @@ func (pv *Player) View(term comp.Terminal) string {
 		content := func() comp.Content {
 			switch pv.State {
 			case nil:
-				return "Ctrl+D to select a device\n\n" + pv.statusBar.Content()
+				return comp.Content(pv.deviceKey()+" to select a device\n\n") + pv.statusBar.Content()
 			default:
-				pv.image.Update(pv.State.Track.Album.Images[0].Url)
+				pv.updateImage(pv.State.Track.Album.Images[0].Url)
 
 				return comp.Join([]comp.Content{
 					pv.image.AsciiSmall(pv.config).Content(),
@@ -303,9 +292,9 @@ func (pv *Player) View(term comp.Terminal) string {
 	content := func() comp.Content {
 		switch pv.State {
 		case nil:
-			return "Ctrl+D to select a device\n\n" + pv.statusBar.Content()
+			return comp.Content(pv.deviceKey()+" to select a device\n\n") + pv.statusBar.Content()
 		default:
-			pv.image.Update(pv.State.Track.Album.Images[0].Url)
+			pv.updateImage(pv.State.Track.Album.Images[0].Url)
 
 			return comp.Join([]comp.Content{
 				pv.image.AsciiNormal(pv.config).Content(),
@@ -318,29 +307,43 @@ func (pv *Player) View(term comp.Terminal) string {
 	return content.CenterVertical(term).PadLinesLeft(3).String()
 }
 
-// Update state synchronously for percision.
-func (pv *Player) UpdateStateSync() {
-	pv.State, _ = pv.player.State(pv.session)
+// Caches the album art of a new track. With album colors enabled,
+// the theme's accent colors are taken from it once it is downloaded.
+func (pv *Player) updateImage(url string) {
+	switch {
+	case url != pv.image.Url:
+		pv.image.Update(url)
+	case pv.image.FilePath != "" || !pv.image.Cached():
+		return
+	}
+
+	pv.ApplyAlbumColors()
 }
 
-// Updates state continuously and asyncchronously, runs reauthentication if requried.
-func (pv *Player) UpdateStateLoop(session *auth.Session, config *config.Config) {
-	go func() {
-		var err error
+// Takes the theme's accent colors from the album art, if album
+// colors are enabled, or restores the theme's own colors.
+func (pv *Player) ApplyAlbumColors() {
+	if !pv.config.AlbumColors.Enabled || pv.image.FilePath == "" {
+		comp.SetAccent(nil)
+		return
+	}
 
-		pv.State, err = pv.player.State(pv.session)
-		if err != nil {
-			err = session.Reauth(config)
-			if err != nil {
-				log.Fatal("ERR: Failed to reauthenticate: ", err)
-				errors.Log(err)
-			}
-		}
+	if c, ok := comp.AlbumAccent(pv.image.Url, pv.image.FilePath, pv.config.AlbumColors.Mode); ok {
+		comp.SetAccent(&c)
+	} else {
+		comp.SetAccent(nil)
+	}
+}
 
-		time.Sleep(POLLING_RATE_STATE_SEC)
+// The key bound to selecting a playback device.
+func (pv *Player) deviceKey() string {
+	return pv.config.Keymap().First(config.KEYMAP_VIEW_PLAYER, config.ACTION_SELECT_DEVICE)
+}
 
-		pv.UpdateStateLoop(session, config)
-	}()
+// Update state synchronously for percision.
+func (pv *Player) UpdateStateSync() {
+	pv.watcher.Poll()
+	pv.Sync()
 }
 
 type PlayerDetails struct {
@@ -354,13 +357,6 @@ type PlayerDetails struct {
 	Album         string
 	ShuffleState  bool
 	RepeatState   string
-	Style         struct {
-		ProgressBar struct {
-			Completed lg.Style
-		}
-		Labels lg.Style
-		Text   lg.Style
-	}
 }
 
 // Renders the player details as a content string.
@@ -390,11 +386,18 @@ func (pd *PlayerDetails) Content(track *spotify.Track, progressMs int, state *pl
 
 	options := fmt.Sprintf("Sfl [%v]  Rep [%v]  Vol [%s%%]", shuffle, repeat, pd.VolumePercent)
 
+	// Values are cut to fit before they are styled,
+	// so the escape codes aren't counted.
+	line := func(label string, value string) comp.Content {
+		return comp.Content(comp.Style.Labels.Render(label) +
+			comp.Style.Text.Render(comp.Content(value).AdjustFit(maxChar-len(label)).String()))
+	}
+
 	return comp.Join([]comp.Content{
-		comp.Content(pd.Style.Labels.Render("Track:   ") + pd.Track).AdjustFit(maxChar),
-		comp.Content(pd.Style.Labels.Render("Artist:  ") + pd.Artists).AdjustFit(maxChar),
-		comp.Content(pd.Style.Labels.Render("Album:   ") + pd.Album).AdjustFit(maxChar),
-		comp.Content(pd.Style.Labels.Render("Option:  ") + options).AdjustFit(maxChar),
+		line("Track:   ", pd.Track),
+		line("Artist:  ", pd.Artists),
+		line("Album:   ", pd.Album),
+		line("Option:  ", options),
 		// AdjustFit works weird on this so it requires more room
 		comp.Content(pd.progressBar(18, float64(state.ProgressMs)/float64(state.Track.DurationMs)*100) + " " + timerProg + " - " + timerDur).AdjustFit(maxChar + 10),
 	}, "\n\n")
@@ -428,12 +431,9 @@ func (pd PlayerDetails) progressBar(width int, percentage float64) string {
 	// Calculate completed segments and ensure it's set to width if percentage is 100
 	completedSegments := int(math.Floor(((percentage / 100) * float64(width))))
 
-	// Style for completed and remaining segments
-	remainingStyle := lg.NewStyle().Foreground(lg.Color("8")).Foreground(lg.Color("0"))
-
 	// Create completed and remaining parts of the bar
-	completedPart := pd.Style.ProgressBar.Completed.Render(strings.Repeat(" ", completedSegments))
-	remainingPart := remainingStyle.Render(strings.Repeat("-", width-completedSegments))
+	completedPart := comp.Style.ProgressBar.Completed.Render(strings.Repeat(" ", completedSegments))
+	remainingPart := comp.Style.ProgressBar.Remaining.Render(strings.Repeat("-", width-completedSegments))
 
 	// Combine parts and enclose in brackets
 	return fmt.Sprintf("[%s%s]", completedPart, remainingPart)
@@ -442,41 +442,44 @@ func (pd PlayerDetails) progressBar(width int, percentage float64) string {
 // The title status bar indicating whether the player is
 // playing, paused or an invalid device is selected.
 type statusBar struct {
-	Style struct {
-		NowPlaying lg.Style
-		Paused     lg.Style
-		NoPlayer   lg.Style
-	}
-	Status       string
-	CurrentStyle lg.Style
+	Status string
 }
 
+const (
+	PAUSED      = "Paused"
+	NO_PLAYER   = "Player Inactive"
+	NOW_PLAYING = "Now Playing"
+)
+
 // Renders the status bar as a string.
 func (sb *statusBar) Render() string {
-	return sb.CurrentStyle.Render(sb.Status)
+	return sb.style().Render(sb.Status)
 }
 
 // Renders the status bar as a content string.
 func (sb *statusBar) Content() comp.Content {
-	return comp.Content(sb.CurrentStyle.Render(sb.Status))
+	return comp.Content(sb.Render())
+}
+
+// The theme's style for the current status.
+func (sb *statusBar) style() lg.Style {
+	switch sb.Status {
+	case NOW_PLAYING:
+		return comp.Style.StatusBar.NowPlaying
+	case PAUSED:
+		return comp.Style.StatusBar.Paused
+	default:
+		return comp.Style.StatusBar.NoPlayer
+	}
 }
 
 // Updates the status bar given the player's state.
 func (sb *statusBar) Update(state *player.State) {
-	const (
-		PAUSED      = "Paused"
-		NO_PLAYER   = "Player Inactive"
-		NOW_PLAYING = "Now Playing"
-	)
-
 	if state != nil && state.IsPlaying {
-		sb.CurrentStyle = sb.Style.NowPlaying
 		sb.Status = NOW_PLAYING
 	} else if state != nil && !state.IsPlaying {
-		sb.CurrentStyle = sb.Style.Paused
 		sb.Status = PAUSED
 	} else {
-		sb.CurrentStyle = sb.Style.NoPlayer
 		sb.Status = NO_PLAYER
 	}
 }
@@ -2,8 +2,6 @@ package views
 
 import (
 	"fmt"
-	"os"
-	"path/filepath"
 
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
@@ -11,13 +9,11 @@ import (
 	"github.com/dionvu/spogo/spotify"
 	"github.com/dionvu/spogo/spotify/auth"
 	comp "github.com/dionvu/spogo/tui/views/components"
-	"github.com/fatih/color"
 	"github.com/jedib0t/go-pretty/v6/table"
 )
 
 const (
 	DEFAULT_PLAYLIST_IMAGE_URL = "https://i.pinimg.com/control/564x/84/29/d1/8429d1c27414bdf99dc5adf9b25a96b3.jpg"
-	IMAGES_FOLDER_NAME         = "assets"
 	MAX_PLAYLIST_WIDTH         = 35
 	MAX_PLAYLIST_ITEM_WIDTH    = 30
 	TOP_MARGIN_PLAYLIST        = 7
@@ -77,16 +73,16 @@ func NewPlaylistView(s *auth.Session, initialTerm comp.Terminal, cfg *config.Con
 		return pv
 	}
 
-	os.MkdirAll(filepath.Join(cfg.CachePath(), IMAGES_FOLDER_NAME), os.ModePerm)
+	// Covers are downloaded in the background, and shown once selected.
+	pv.Images = make([]comp.Image, len(*pv.UserPlaylists))
+	urls := []string{}
 
 	for i, playlist := range *pv.UserPlaylists {
-		pv.Images = append(pv.Images, comp.Image{FilePath: filepath.Join(cfg.CachePath(), IMAGES_FOLDER_NAME, playlist.ID+comp.FILE_EXTENSION)})
-
+		pv.Images[i].Url = DEFAULT_PLAYLIST_IMAGE_URL
 		if len(playlist.Images) != 0 {
-			pv.Images[i].Update(playlist.Images[0].Url)
-		} else {
-			pv.Images[i].Update(DEFAULT_PLAYLIST_IMAGE_URL)
+			pv.Images[i].Url = playlist.Images[0].Url
 		}
+		urls = append(urls, pv.Images[i].Url)
 
 		playlistListItem := comp.ListItem(comp.Content(playlist.Name).AdjustFit(MAX_PLAYLIST_ITEM_WIDTH))
 		playlistListItems = append(playlistListItems, playlistListItem)
@@ -95,6 +91,8 @@ func NewPlaylistView(s *auth.Session, initialTerm comp.Terminal, cfg *config.Con
 		pv.playlistsMap[playlistListItem] = &playlist
 	}
 
+	comp.PrefetchImages(urls)
+
 	pv.PlaylistList = PlaylistList{list: comp.NewDefaultList(playlistListItems, "Playlists")}
 
 	return pv
@@ -176,8 +174,8 @@ func (pi *PlaylistInfo) Update(playlist *spotify.Playlist) {
 func (pi PlaylistInfo) Content(term comp.Terminal) comp.Content {
 	return comp.Join(
 		[]string{
-			color.HiGreenString("Name:    ") + pi.Name.String(),
-			color.HiGreenString("Tracks:  ") + fmt.Sprint(pi.TotalTracks),
+			comp.Style.Labels.Render("Name:    ") + pi.Name.String(),
+			comp.Style.Labels.Render("Tracks:  ") + fmt.Sprint(pi.TotalTracks),
 		}, "\n\n")
 }
 
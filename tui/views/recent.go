@@ -0,0 +1,342 @@
+package views
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	lg "github.com/charmbracelet/lipgloss"
+	"github.com/dionvu/spogo/config"
+	"github.com/dionvu/spogo/err"
+	"github.com/dionvu/spogo/history"
+	"github.com/dionvu/spogo/spotify"
+	"github.com/dionvu/spogo/spotify/auth"
+	comp "github.com/dionvu/spogo/tui/views/components"
+	"github.com/mattn/go-runewidth"
+)
+
+const (
+	// Rows taken by the header, details, footer and view status around the list.
+	RECENT_VIEW_CHROME_HEIGHT = 12
+	MAX_RECENT_VIEW_WIDTH     = 100
+
+	// Tracks requested for each page.
+	RECENT_PAGE_LIMIT = 20
+
+	// Wide enough for "23h ago".
+	RECENT_AGO_WIDTH = 8
+
+	MSG_RECENT_UNAVAILABLE = "Recently played tracks are unavailable, see the error log"
+	MSG_RECENT_EMPTY       = "Nothing played yet"
+)
+
+// Lists the tracks played recently, newest first, loading older pages
+// as the selection reaches the end and newer tracks when reopened.
+type Recent struct {
+	session *auth.Session
+	config  *config.Config
+
+	items []history.RecentPlay
+	more  bool
+
+	// Whether the items include the play history, so they are
+	// loaded again once "recently_played.local_history" changes.
+	local bool
+
+	loading bool
+	err     error
+
+	// Counts the times the list was loaded from the start,
+	// so pages of a list since replaced are dropped.
+	seq int
+
+	// Playlist names by uri, to name the context of tracks.
+	playlists map[string]string
+
+	list list.Model
+}
+
+// Carries tracks loaded for the recent view, either a page of older
+// tracks, or those played since the newest listed.
+type RecentMsg struct {
+	Seq   int
+	Items []history.RecentPlay
+	Newer bool
+	More  bool
+	Err   error
+}
+
+func NewRecent(session *auth.Session, cfg *config.Config, playlists *[]spotify.Playlist) Recent {
+	l := comp.NewCustomUniqueItemList([]list.Item{}, "", 0, 0)
+	l.SetShowTitle(false)
+	l.SetShowPagination(false)
+
+	r := Recent{
+		session:   session,
+		config:    cfg,
+		playlists: map[string]string{},
+		list:      l,
+	}
+
+	if playlists != nil {
+		for _, pl := range *playlists {
+			r.playlists[pl.Uri] = pl.Name
+		}
+	}
+
+	return r
+}
+
+// Loads the tracks played since the newest listed, or the latest
+// page if none are, in the background.
+func (r *Recent) Load() tea.Cmd {
+	if len(r.items) == 0 || r.local != r.config.RecentlyPlayed.LocalHistory {
+		r.seq++
+		r.items = nil
+		r.more = false
+		r.local = r.config.RecentlyPlayed.LocalHistory
+		r.list.Select(0)
+
+		return r.load(time.Time{}, false)
+	}
+
+	if r.loading {
+		return nil
+	}
+
+	return r.load(r.items[0].PlayedAt, true)
+}
+
+// Loads the page of tracks played before the oldest listed,
+// unless one is loading or there are none left.
+func (r *Recent) LoadOlder() tea.Cmd {
+	if r.loading || !r.more || len(r.items) == 0 {
+		return nil
+	}
+
+	return r.load(r.items[len(r.items)-1].PlayedAt, false)
+}
+
+func (r *Recent) load(cursor time.Time, newer bool) tea.Cmd {
+	r.loading = true
+
+	seq := r.seq
+	plays := history.NewRecentPlays(r.config, r.session)
+
+	return func() tea.Msg {
+		if newer {
+			items, err := plays.After(cursor)
+			return RecentMsg{Seq: seq, Items: items, Newer: true, Err: err}
+		}
+
+		items, more, err := plays.Before(cursor, RECENT_PAGE_LIMIT)
+		return RecentMsg{Seq: seq, Items: items, More: more, Err: err}
+	}
+}
+
+// Adds the tracks loaded, unless the list has been loaded from the
+// start since. Errors are logged where they occur, keeping the list.
+func (r *Recent) Received(msg RecentMsg) {
+	if msg.Seq != r.seq {
+		return
+	}
+
+	r.loading = false
+	r.err = msg.Err
+
+	if msg.Err != nil {
+		return
+	}
+
+	if !msg.Newer {
+		r.items = history.Merge(r.items, msg.Items)
+		r.more = msg.More
+		return
+	}
+
+	// Keeps the same track selected as tracks are added above it.
+	index := r.list.Index()
+	n := len(r.items)
+
+	r.items = history.Merge(msg.Items, r.items)
+	r.list.Select(index + len(r.items) - n)
+}
+
+// The track selected, nil if none are listed.
+func (r Recent) Selected() *history.RecentPlay {
+	i := r.list.Index()
+	if i < 0 || i >= len(r.items) {
+		return nil
+	}
+
+	return &r.items[i]
+}
+
+// Moves the selection, loading older tracks before
+// it moves past the last one.
+func (r *Recent) Update(msg tea.Msg, term comp.Terminal) tea.Cmd {
+	r.resize(term)
+
+	var cmd tea.Cmd
+
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.String() {
+		case "down", "j":
+			if n := len(r.items); n > 0 && r.list.Index() == n-1 {
+				cmd = r.LoadOlder()
+			}
+		}
+	}
+
+	var listCmd tea.Cmd
+	r.list, listCmd = r.list.Update(msg)
+
+	return tea.Batch(cmd, listCmd)
+}
+
+func (r Recent) View(term comp.Terminal) string {
+	r.resize(term)
+
+	body := r.list.View()
+
+	switch {
+	case len(r.items) > 0:
+	case r.loading:
+		body = "Loading..."
+	case r.err != nil:
+		body = r.errorText()
+	default:
+		body = MSG_RECENT_EMPTY
+	}
+
+	body = lg.NewStyle().Width(r.list.Width()).Height(r.list.Height()).Render(body)
+
+	return comp.Join([]string{
+		fmt.Sprintf("%s\n%s\n\n%s\n\n%s", r.headerView(), body, r.detailsView(), r.footerView()),
+		ViewStatus{CurrentView: RECENT_VIEW}.Content(r.config).String(),
+	}, "\n").CenterVertical(term).CenterHorizontal(term).String()
+}
+
+// Fits the list to the terminal, laying out its rows to its width.
+func (r *Recent) resize(term comp.Terminal) {
+	width := min(max(term.Width-4, 0), MAX_RECENT_VIEW_WIDTH)
+	r.list.SetSize(width, max(term.Height-RECENT_VIEW_CHROME_HEIGHT, 1))
+
+	// Rows are made again each time, as the time since
+	// each track was played moves on.
+	now := time.Now()
+	items := make([]list.Item, len(r.items))
+
+	for i, p := range r.items {
+		// Rows are padded by the list's delegate.
+		name := runewidth.Truncate(p.Name+" - "+strings.Join(p.Artists, ", "), max(width-RECENT_AGO_WIDTH-6, 0), "…")
+
+		items[i] = comp.UniqueItem{
+			Name: fmt.Sprintf("%-*s %s", RECENT_AGO_WIDTH, ago(p.PlayedAt, now), name),
+			Id:   fmt.Sprint(p.PlayedAt.UnixNano(), p.Uri),
+		}
+	}
+
+	r.list.SetItems(items)
+}
+
+func (r Recent) headerView() string {
+	name := "Recently Played"
+	if r.loading && len(r.items) > 0 {
+		name += " (loading)"
+	}
+
+	title := titleStyle.Render(name)
+	line := strings.Repeat("─", max(0, r.list.Width()-lg.Width(title)))
+	return lg.JoinHorizontal(lg.Center, title, line)
+}
+
+// When the selected track was played, what from, and its album.
+func (r Recent) detailsView() string {
+	style := lg.NewStyle().Width(r.list.Width())
+
+	p := r.Selected()
+	if p == nil {
+		return style.Render("\n\n")
+	}
+
+	played := p.PlayedAt.Local().Format("Mon 2 Jan 15:04")
+	if p.Local {
+		played += " (play history)"
+	}
+
+	fit := func(s string) string {
+		return runewidth.Truncate(s, max(r.list.Width()-14, 0), "…")
+	}
+
+	return style.Render(comp.Join([]string{
+		comp.Style.Labels.Render("Played:   ") + fit(played),
+		comp.Style.Labels.Render("Context:  ") + fit(r.contextName(*p)),
+		comp.Style.Labels.Render("Album:    ") + fit(p.Album),
+	}, "\n").PadLinesLeft(4).String())
+}
+
+func (r Recent) footerView() string {
+	km := r.config.Keymap()
+
+	hints := fmt.Sprintf("%s to play  %s album  %s artist",
+		km.First(config.KEYMAP_VIEW_PLAYER, config.ACTION_SELECT),
+		km.First(config.KEYMAP_VIEW_PLAYER, config.ACTION_TRACK_ALBUM),
+		km.First(config.KEYMAP_VIEW_PLAYER, config.ACTION_TRACK_ARTIST))
+
+	info := infoStyle.Render(hints)
+	line := strings.Repeat("─", max(0, r.list.Width()-lg.Width(info)))
+	return lg.JoinHorizontal(lg.Center, line, info)
+}
+
+// Names what the track was played from, as far as it is known.
+func (r Recent) contextName(p history.RecentPlay) string {
+	switch p.ContextType {
+	case "":
+		return "None"
+	case "album":
+		if p.AlbumUri == "" || p.AlbumUri == p.ContextUri {
+			return "Album, " + p.Album
+		}
+		return "Album"
+	case "playlist":
+		if name, ok := r.playlists[p.ContextUri]; ok {
+			return "Playlist, " + name
+		}
+		return "Playlist"
+	case "artist":
+		return "Artist"
+	case "collection":
+		return "Liked Songs"
+	default:
+		return strings.ToUpper(p.ContextType[:1]) + p.ContextType[1:]
+	}
+}
+
+func (r Recent) errorText() string {
+	if errors.IsMissingScopeErr(r.err) {
+		return fmt.Sprintf("Recently played tracks need spogo to be signed in again,\nremove %v\nand %v then run spogo",
+			filepath.Join(r.config.CachePath(), config.ACCESSTOKENFILE), filepath.Join(r.config.CachePath(), config.REQUESTTOKENFILE))
+	}
+
+	return MSG_RECENT_UNAVAILABLE
+}
+
+// How long ago t was, such as "5m ago".
+func ago(t time.Time, now time.Time) string {
+	d := now.Sub(t)
+
+	switch {
+	case d < time.Minute:
+		return "now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < time.Hour*24:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+	}
+}
@@ -1,8 +1,11 @@
 package views
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/cursor"
 	"github.com/charmbracelet/bubbles/list"
@@ -11,11 +14,12 @@ import (
 	lg "github.com/charmbracelet/lipgloss"
 	"github.com/dionvu/spogo/config"
 	"github.com/dionvu/spogo/err"
+	"github.com/dionvu/spogo/history"
 	"github.com/dionvu/spogo/spotify"
 	"github.com/dionvu/spogo/spotify/auth"
 	comp "github.com/dionvu/spogo/tui/views/components"
-	"github.com/fatih/color"
 	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/joomcode/errorx"
 )
 
 const (
@@ -24,6 +28,11 @@ const (
 	SEARCH_RESULT_LIMIT   = 42
 	MAX_RESULT_ITEM_WIDTH = MAX_RESULT_WIDTH - 5
 
+	// Spotify allows at most 50 results per page,
+	// and an offset of at most 1000.
+	SEARCH_RESULT_PAGE_LIMIT = 20
+	SEARCH_MAX_OFFSET        = 1000
+
 	RESULT_DETAILS_OFFSET  = 21
 	MAX_RESULT_DETAILS_LEN = 76
 	TEXT_INPUT_CHAR_LIMIT  = 156
@@ -32,11 +41,32 @@ const (
 	ALBUM    = "album"
 	EPISODE  = "episode"
 	PLAYLIST = "playlist"
+	ARTIST   = "artist"
+	SHOW     = "show"
 
 	NL = '\n'
+
+	DEFAULT_SEARCH_DEBOUNCE = 300 * time.Millisecond
+
+	MAX_SUGGESTIONS      = 8
+	MAX_SUGGESTION_WIDTH = comp.DEFAULT_WIDTH - 4
 )
 
-var SEARCH_TYPES = []string{TRACK, ALBUM, PLAYLIST}
+var SEARCH_TYPES = []string{TRACK, ALBUM, PLAYLIST, ARTIST, SHOW, EPISODE}
+
+// The titles of each search type's result tab.
+var SEARCH_TYPE_TITLES = map[string]string{
+	TRACK:    "Tracks",
+	ALBUM:    "Albums",
+	PLAYLIST: "Playlists",
+	ARTIST:   "Artists",
+	SHOW:     "Shows",
+	EPISODE:  "Episodes",
+}
+
+// Search results are shared between every search view, so returning
+// to a recent query doesn't hit the api.
+var searchCache = spotify.NewSearchCache(spotify.SEARCH_CACHE_SIZE, spotify.SEARCH_CACHE_TTL)
 
 type Search struct {
 	Input    SearchQuery
@@ -46,6 +76,21 @@ type Search struct {
 
 	session *auth.Session
 
+	// The user's country, fetched once in the background on
+	// start, and left empty if that failed.
+	market string
+
+	// Instant search state. Every query change increments seq,
+	// debounce ticks and responses carrying an older seq are
+	// stale and ignored.
+	seq    int
+	cancel context.CancelFunc
+
+	// Past queries and recently opened items, suggested
+	// while the query is empty.
+	History    *history.Search
+	suggestion int
+
 	typeMap map[list.Item]string
 }
 
@@ -59,6 +104,11 @@ func NewSearch(session *auth.Session, cfg *config.Config) Search {
 		searchTypeListItemMap[item] = searchType
 	}
 
+	h, err := history.LoadSearch(cfg)
+	if err != nil {
+		errors.Log(err)
+	}
+
 	return Search{
 		session:  session,
 		Config:   cfg,
@@ -66,25 +116,301 @@ func NewSearch(session *auth.Session, cfg *config.Config) Search {
 		TypeList: NewSearchTypeList(searchTypeListItems),
 		typeMap:  searchTypeListItemMap,
 		Results:  Results{},
+		History:  h,
 	}
 }
 
+// Handles key presses while the query box is focused. While the query
+// is empty, up and down move through the suggestions, and delete
+// removes the selected suggestion from the history.
+func (s *Search) UpdateQuery(msg tea.Msg) tea.Cmd {
+	if key, ok := msg.(tea.KeyMsg); ok && s.Input.Query() == "" {
+		suggestions := s.Suggestions()
+
+		switch key.String() {
+		case "up":
+			s.suggestion = max(s.suggestion-1, 0)
+			return nil
+
+		case "down":
+			s.suggestion = min(s.suggestion+1, max(len(suggestions)-1, 0))
+			return nil
+
+		case "delete", "ctrl+x":
+			if e := s.SelectedSuggestion(); e != nil {
+				s.History.Remove(*e)
+				s.suggestion = min(s.suggestion, max(len(suggestions)-2, 0))
+			}
+			return nil
+		}
+	}
+
+	var cmd tea.Cmd
+	s.Input, cmd = s.Input.Update(msg)
+
+	return cmd
+}
+
+// The most recent queries and opened items.
+func (s Search) Suggestions() []history.Entry {
+	if s.History == nil {
+		return []history.Entry{}
+	}
+
+	entries := s.History.All()
+	return entries[:min(len(entries), MAX_SUGGESTIONS)]
+}
+
+// The highlighted suggestion, or nil if there are none.
+func (s Search) SelectedSuggestion() *history.Entry {
+	suggestions := s.Suggestions()
+	if len(suggestions) == 0 {
+		return nil
+	}
+
+	return &suggestions[min(s.suggestion, len(suggestions)-1)]
+}
+
+// Records a query or an opened item in the history.
+func (s Search) Record(kind string, name string, uri string) {
+	if s.History == nil {
+		return
+	}
+
+	var err error
+	if kind == history.KIND_QUERY {
+		err = s.History.AddQuery(name)
+	} else {
+		err = s.History.AddItem(kind, name, uri)
+	}
+
+	if err != nil {
+		errors.Log(err)
+	}
+}
+
+// Renders the suggestions, marking each
+// recently opened item with its kind.
+func (s Search) suggestionsView() string {
+	style := struct {
+		Selected lg.Style
+		Normal   lg.Style
+	}{
+		Normal:   comp.Style.Muted,
+		Selected: comp.Style.Selected,
+	}
+
+	lines := []string{"Recent:", ""}
+
+	for i, e := range s.Suggestions() {
+		name := e.Name
+		if e.Kind != history.KIND_QUERY {
+			name = e.Kind[:2] + ": " + name
+		}
+
+		name = comp.Content(name).AdjustFit(MAX_SUGGESTION_WIDTH).String()
+
+		if i == min(s.suggestion, MAX_SUGGESTIONS-1) {
+			lines = append(lines, style.Selected.Render("> "+name))
+		} else {
+			lines = append(lines, style.Normal.Render("  "+name))
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
 func (r Results) SelectedTrack() *spotify.Track {
-	return r.trackMap[r.listTracks.SelectedItem()]
+	t, _ := r.selected(TRACK).(*spotify.Track)
+	return t
 }
 
 func (r Results) SelectedAlbum() *spotify.Album {
-	return r.albumMap[r.listAlbums.SelectedItem()]
+	a, _ := r.selected(ALBUM).(*spotify.Album)
+	return a
 }
 
 func (r Results) SelectedPlaylist() *spotify.Playlist {
-	return r.playlistMap[r.listPlaylists.SelectedItem()]
+	p, _ := r.selected(PLAYLIST).(*spotify.Playlist)
+	return p
+}
+
+func (r Results) SelectedArtist() *spotify.Artist {
+	a, _ := r.selected(ARTIST).(*spotify.Artist)
+	return a
+}
+
+func (r Results) SelectedShow() *spotify.Show {
+	sh, _ := r.selected(SHOW).(*spotify.Show)
+	return sh
+}
+
+func (r Results) SelectedEpisode() *spotify.Episode {
+	e, _ := r.selected(EPISODE).(*spotify.Episode)
+	return e
+}
+
+// The spotify item hovered in the list of the given search type.
+func (r Results) selected(searchType string) interface{} {
+	l, ok := r.lists[searchType]
+	if !ok {
+		return nil
+	}
+	return r.items[l.SelectedItem()]
 }
 
 func (s Search) SelectedType() string {
 	return s.typeMap[s.TypeList.Selected()]
 }
 
+// Sent once the user has stopped typing for the debounce interval.
+type SearchDebounceMsg struct {
+	Seq int
+}
+
+// Carries the response of an instant search.
+type SearchResultMsg struct {
+	Seq    int
+	Query  string
+	Market string
+	Result *spotify.SearchResult
+	Err    error
+}
+
+// Called after the query text has changed while instant search is
+// enabled. Any in-flight search is cancelled, and a search is
+// scheduled after the debounce interval.
+func (s *Search) QueryChanged() tea.Cmd {
+	s.seq++
+
+	if s.cancel != nil {
+		s.cancel()
+		s.cancel = nil
+	}
+
+	if !s.Input.Valid() {
+		return nil
+	}
+
+	debounce := time.Duration(s.Config.Search.DebounceMs) * time.Millisecond
+	if debounce <= 0 {
+		debounce = DEFAULT_SEARCH_DEBOUNCE
+	}
+
+	seq := s.seq
+
+	return tea.Tick(debounce, func(time.Time) tea.Msg {
+		return SearchDebounceMsg{Seq: seq}
+	})
+}
+
+// Starts the search scheduled by QueryChanged, unless
+// the query has changed since.
+func (s *Search) Debounced(msg SearchDebounceMsg) tea.Cmd {
+	if msg.Seq != s.seq {
+		return nil
+	}
+
+	q, _ := spotify.ParseQuery(s.Input.Query())
+	query, market := q.String(), s.Market()
+	key := spotify.SearchCacheKey(query, SEARCH_TYPES, market)
+
+	if result, ok := searchCache.Get(key); ok {
+		return func() tea.Msg {
+			return SearchResultMsg{Seq: msg.Seq, Query: query, Market: market, Result: result}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	session := s.session
+
+	return func() tea.Msg {
+		result, err := spotify.SearchPageContext(ctx, query, SEARCH_TYPES, SEARCH_RESULT_LIMIT, 0, market, session)
+		if err == nil {
+			searchCache.Put(key, result)
+		}
+
+		return SearchResultMsg{Seq: msg.Seq, Query: query, Market: market, Result: result, Err: err}
+	}
+}
+
+// Streams the response of a search into the results,
+// ignoring responses to queries that have since changed.
+func (s *Search) Received(msg SearchResultMsg) {
+	if msg.Seq != s.seq {
+		return
+	}
+
+	s.cancel = nil
+	s.Results.loading = false
+
+	if msg.Err != nil {
+		errors.Log(msg.Err)
+		return
+	}
+
+	s.Results = s.Results.FromResult(msg.Query, msg.Market, s.session, msg.Result)
+	s.Results.seq = msg.Seq
+}
+
+// Carries a further page of results fetched by Results.LoadMore.
+type SearchPageMsg struct {
+	Seq    int
+	Type   string
+	Result *spotify.SearchResult
+	Err    error
+}
+
+// Appends a further page to the results, unless they have
+// since been replaced by another search.
+func (s *Search) PageReceived(msg SearchPageMsg) {
+	if msg.Seq != s.Results.seq {
+		return
+	}
+
+	s.Results.loading = false
+
+	if msg.Err != nil {
+		errors.Log(msg.Err)
+		return
+	}
+
+	s.Results.appendPage(msg.Result, []string{msg.Type})
+}
+
+// Carries the user's country fetched by LoadMarket.
+type MarketMsg struct {
+	Country string
+	Err     error
+}
+
+// Fetches the user's country in the background.
+func (s Search) LoadMarket() tea.Cmd {
+	session := s.session
+
+	return func() tea.Msg {
+		user, err := spotify.New(session)
+		if err != nil {
+			return MarketMsg{Err: err}
+		}
+
+		return MarketMsg{Country: user.Country}
+	}
+}
+
+// Keeps the country fetched. A failure is kept too, as an empty
+// country, so searches aren't held up asking again.
+func (s *Search) MarketLoaded(msg MarketMsg) {
+	s.market = msg.Country
+}
+
+// Returns the user's country to restrict results to content
+// playable by the user, or an empty string if it is unknown.
+func (s *Search) Market() string {
+	return s.market
+}
+
 // Renders the search view, this includes, the text area,
 // the type selection, and the list of results.
 func (s Search) View(term comp.Terminal, currentView string) string {
@@ -93,9 +419,16 @@ func (s Search) View(term comp.Terminal, currentView string) string {
 
 	s.TypeList = s.TypeList.UpdateSelected(currentView)
 
+	// Suggestions take the place of the type list
+	// until the user starts typing.
+	below := s.TypeList.View()
+	if currentView == SEARCH_VIEW_QUERY && s.Input.Query() == "" && len(s.Suggestions()) > 0 {
+		below = s.suggestionsView()
+	}
+
 	queryAndTypeContainer.AppendRows([]table.Row{
 		{s.Input.Content().PadLinesLeft(2)},
-		{s.TypeList.View()},
+		{below},
 	})
 
 	mainContainerTable.AppendRow(table.Row{
@@ -115,8 +448,8 @@ func (s Search) View(term comp.Terminal, currentView string) string {
 			mins, secs := MsToMinutesAndSeconds(s.Results.SelectedTrack().DurationMs)
 			return comp.Join(
 				[]string{
-					color.HiGreenString("Artist:    ") + s.Results.SelectedTrack().Artists[0].Name,
-					color.HiGreenString("Duration:  ") + mins + "m:" + secs + "s",
+					comp.Style.Labels.Render("Artist:    ") + s.Results.SelectedTrack().Artists[0].Name,
+					comp.Style.Labels.Render("Duration:  ") + mins + "m:" + secs + "s",
 				}, "\n\n")
 
 		case ALBUM:
@@ -126,8 +459,8 @@ func (s Search) View(term comp.Terminal, currentView string) string {
 
 			return comp.Join(
 				[]string{
-					color.HiGreenString("Artist:  ") + s.Results.SelectedAlbum().ArtistsString(),
-					color.HiGreenString("Tracks:  ") + fmt.Sprint(s.Results.SelectedAlbum().TotalTracks),
+					comp.Style.Labels.Render("Artist:  ") + s.Results.SelectedAlbum().ArtistsString(),
+					comp.Style.Labels.Render("Tracks:  ") + fmt.Sprint(s.Results.SelectedAlbum().TotalTracks),
 				}, "\n\n")
 
 		case PLAYLIST:
@@ -137,8 +470,42 @@ func (s Search) View(term comp.Terminal, currentView string) string {
 
 			return comp.Join(
 				[]string{
-					color.HiGreenString("Owner:   ") + s.Results.SelectedPlaylist().Owner.DisplayName,
-					color.HiGreenString("Tracks:  ") + fmt.Sprint(s.Results.SelectedPlaylist().Tracks.Total),
+					comp.Style.Labels.Render("Owner:   ") + s.Results.SelectedPlaylist().Owner.DisplayName,
+					comp.Style.Labels.Render("Tracks:  ") + fmt.Sprint(s.Results.SelectedPlaylist().Tracks.Total),
+				}, "\n\n")
+
+		case ARTIST:
+			if s.Results.SelectedArtist() == nil {
+				return comp.Content("").Append(NL, 2)
+			}
+
+			return comp.Join(
+				[]string{
+					comp.Style.Labels.Render("Genres:     ") + strings.Join(s.Results.SelectedArtist().Genres, ", "),
+					comp.Style.Labels.Render("Followers:  ") + fmt.Sprint(s.Results.SelectedArtist().Followers.Total),
+				}, "\n\n")
+
+		case SHOW:
+			if s.Results.SelectedShow() == nil {
+				return comp.Content("").Append(NL, 2)
+			}
+
+			return comp.Join(
+				[]string{
+					comp.Style.Labels.Render("Publisher:  ") + s.Results.SelectedShow().Publisher,
+					comp.Style.Labels.Render("Episodes:   ") + fmt.Sprint(s.Results.SelectedShow().TotalEpisodes),
+				}, "\n\n")
+
+		case EPISODE:
+			if s.Results.SelectedEpisode() == nil {
+				return comp.Content("").Append(NL, 2)
+			}
+
+			mins, secs := MsToMinutesAndSeconds(s.Results.SelectedEpisode().DurationMs)
+			return comp.Join(
+				[]string{
+					comp.Style.Labels.Render("Released:  ") + s.Results.SelectedEpisode().ReleaseDate,
+					comp.Style.Labels.Render("Duration:  ") + mins + "m:" + secs + "s",
 				}, "\n\n")
 
 		default:
@@ -164,84 +531,203 @@ func (s Search) View(term comp.Terminal, currentView string) string {
 	return content.CenterHorizontalLeft(term, 10).CenterVertical(term, 1).String()
 }
 
+// The results of a single search. Every search type is fetched at
+// once, so switching between the result tabs is done locally.
 type Results struct {
 	CurrentType string
 	Items       spotify.SearchResult
 
-	listTracks list.Model
-	trackMap   map[list.Item]*spotify.Track
+	// The result list of each search type, and the spotify
+	// item behind every list item.
+	lists  map[string]list.Model
+	items  map[list.Item]interface{}
+	totals map[string]int
 
-	listAlbums list.Model
-	albumMap   map[list.Item]*spotify.Album
+	// Kept to request further pages when the user
+	// scrolls past the end of the list.
+	query   string
+	market  string
+	session *auth.Session
 
-	listPlaylists list.Model
-	playlistMap   map[list.Item]*spotify.Playlist
+	// The search the results belong to, and whether
+	// a response is still on its way.
+	seq     int
+	loading bool
 }
 
-// Called whenever the user has finished inputing a search query and selected the search type
-// of the results to be displayed. This updates the state of result to match the desired
-// specified content.
-func (r Results) Refresh(query string, selectedType string, s *auth.Session) Results {
-	r.CurrentType = selectedType
+// Called whenever the user has finished inputing a search query. Results
+// of every search type are taken from the search cache, or fetched in
+// the background, and the type to display is switched to selectedType.
+// The query may contain field filters, see spotify.ParseQuery.
+func (s *Search) Refresh(query string, selectedType string) tea.Cmd {
+	q, _ := spotify.ParseQuery(query)
+	query, market := q.String(), s.Market()
+
+	r := s.Results
+	if r.lists != nil && query == r.query && market == r.market {
+		s.Results = r.SetType(selectedType)
+		return nil
+	}
 
-	searchResults, err := spotify.Search(query, SEARCH_TYPES, SEARCH_RESULT_LIMIT, s)
-	if err != nil {
-		errors.Log(err)
+	// Replaces any instant search still in flight.
+	s.seq++
+
+	if s.cancel != nil {
+		s.cancel()
+		s.cancel = nil
 	}
 
-	switch r.CurrentType {
-	case TRACK:
-		listItems := make([]list.Item, len(searchResults.Tracks))
-		r.trackMap = map[list.Item]*spotify.Track{}
+	s.Results = NewResults(query, market, s.session).SetType(selectedType)
+	s.Results.seq = s.seq
 
-		for i, track := range searchResults.Tracks {
-			listItems[i] = comp.UniqueItem{
-				Name: comp.Content(track.Name).AdjustFit(MAX_RESULT_ITEM_WIDTH).String(),
-				Id:   track.ID,
-			}
-			r.trackMap[listItems[i]] = track
+	key := spotify.SearchCacheKey(query, SEARCH_TYPES, market)
+
+	if result, ok := searchCache.Get(key); ok {
+		s.Results.appendPage(result, SEARCH_TYPES)
+		return nil
+	}
+
+	s.Results.loading = true
+	seq, session := s.seq, s.session
+
+	return func() tea.Msg {
+		result, err := spotify.SearchPage(query, SEARCH_TYPES, SEARCH_RESULT_LIMIT, 0, market, session)
+		if err == nil {
+			searchCache.Put(key, result)
 		}
 
-		r.listTracks = comp.NewDefaultUniqueItemList(listItems, "Tracks")
+		return SearchResultMsg{Seq: seq, Query: query, Market: market, Result: result, Err: err}
+	}
+}
 
-	case ALBUM:
-		listItems := make([]list.Item, len(searchResults.Albums))
-		r.albumMap = map[list.Item]*spotify.Album{}
+// Replaces the results with an already fetched search result,
+// keeping the displayed type, or showing tracks if none was shown.
+func (r Results) FromResult(query string, market string, s *auth.Session, searchResults *spotify.SearchResult) Results {
+	searchType := r.CurrentType
+	if searchType == "" {
+		searchType = TRACK
+	}
 
-		for i, album := range searchResults.Albums {
-			listItems[i] = comp.UniqueItem{
-				Name: comp.Content(album.Name).AdjustFit(MAX_RESULT_ITEM_WIDTH).String(),
-				Id:   album.ID,
-			}
-			r.albumMap[listItems[i]] = album
+	r = NewResults(query, market, s)
+	r.appendPage(searchResults, SEARCH_TYPES)
+
+	return r.SetType(searchType)
+}
+
+// Creates empty results for the query, with a list for every search type.
+func NewResults(query string, market string, s *auth.Session) Results {
+	r := Results{
+		query:   query,
+		market:  market,
+		session: s,
+		lists:   map[string]list.Model{},
+		items:   map[list.Item]interface{}{},
+		totals:  map[string]int{},
+	}
+
+	// The tabs already name the displayed type.
+	for _, t := range SEARCH_TYPES {
+		l := comp.NewDefaultUniqueItemList([]list.Item{}, SEARCH_TYPE_TITLES[t])
+		l.SetShowTitle(false)
+		r.lists[t] = l
+	}
+
+	return r
+}
+
+// Switches the displayed result tab without searching again.
+func (r Results) SetType(searchType string) Results {
+	r.CurrentType = searchType
+	return r
+}
+
+// Switches to the next, or previous if step is negative, result tab.
+func (r Results) CycleType(step int) Results {
+	for i, t := range SEARCH_TYPES {
+		if t == r.CurrentType {
+			return r.SetType(SEARCH_TYPES[(i+step+len(SEARCH_TYPES))%len(SEARCH_TYPES)])
 		}
+	}
+
+	return r.SetType(SEARCH_TYPES[0])
+}
+
+// Fetches the next page of the current search type in the background,
+// doing nothing if every result is already listed or a page is on its way.
+func (r *Results) LoadMore() tea.Cmd {
+	offset := len(r.lists[r.CurrentType].Items())
+
+	if r.session == nil || r.loading || offset >= r.totals[r.CurrentType] || offset >= SEARCH_MAX_OFFSET {
+		return nil
+	}
 
-		r.listAlbums = comp.NewDefaultUniqueItemList(listItems, "Albums")
+	r.loading = true
+	seq, searchType, query, market, session := r.seq, r.CurrentType, r.query, r.market, r.session
+
+	return func() tea.Msg {
+		result, err := spotify.SearchPage(query, []string{searchType}, SEARCH_RESULT_PAGE_LIMIT, offset, market, session)
+		return SearchPageMsg{Seq: seq, Type: searchType, Result: result, Err: err}
+	}
+}
 
-	case PLAYLIST:
-		listItems := make([]list.Item, len(searchResults.Playlists))
-		r.playlistMap = map[list.Item]*spotify.Playlist{}
+// Appends the results of the given search types to their lists.
+func (r *Results) appendPage(searchResults *spotify.SearchResult, searchTypes []string) {
+	for _, t := range searchTypes {
+		r.totals[t] = searchResults.Totals[t]
 
-		for i, playlist := range searchResults.Playlists {
-			listItems[i] = comp.UniqueItem{
-				Name: comp.Content(playlist.Name).AdjustFit(MAX_RESULT_ITEM_WIDTH).String(),
-				Id:   playlist.ID,
+		l := r.lists[t]
+
+		add := func(name string, id string, item interface{}) {
+			listItem := comp.UniqueItem{
+				Name: comp.Content(name).AdjustFit(MAX_RESULT_ITEM_WIDTH).String(),
+				Id:   id,
 			}
-			r.playlistMap[listItems[i]] = playlist
+			r.items[listItem] = item
+			l.InsertItem(len(l.Items()), listItem)
 		}
 
-		r.listPlaylists = comp.NewDefaultUniqueItemList(listItems, "Playlist")
-	}
+		switch t {
+		case TRACK:
+			for _, track := range searchResults.Tracks {
+				add(track.Name, track.ID, track)
+			}
+		case ALBUM:
+			for _, album := range searchResults.Albums {
+				add(album.Name, album.ID, album)
+			}
+		case PLAYLIST:
+			for _, playlist := range searchResults.Playlists {
+				add(playlist.Name, playlist.ID, playlist)
+			}
+		case ARTIST:
+			for _, artist := range searchResults.Artists {
+				add(artist.Name, artist.ID, artist)
+			}
+		case SHOW:
+			for _, show := range searchResults.Shows {
+				add(show.Name, show.ID, show)
+			}
+		case EPISODE:
+			for _, episode := range searchResults.Episodes {
+				add(episode.Name, episode.ID, episode)
+			}
+		}
 
-	return r
+		r.lists[t] = l
+	}
 }
 
 // Updates the state of the result when a key is pressed,
 // handling every search type's result list. Ensure that
 // result.CurrentType has been set before (by calling
-// result.Refresh() ) before Update is called.
+// Search.Refresh() ) before Update is called.
 func (r Results) Update(msg tea.Msg) (Results, tea.Cmd) {
-	var cmd tea.Cmd
+	var cmd, loadCmd tea.Cmd
+
+	l, ok := r.lists[r.CurrentType]
+	if !ok {
+		return r, nil
+	}
 
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
@@ -251,39 +737,64 @@ func (r Results) Update(msg tea.Msg) (Results, tea.Cmd) {
 
 		case "esc":
 			return r, nil
+
+		case "down", "j":
+			// Loads the next page before the cursor moves
+			// past the last result.
+			if n := len(l.Items()); n > 0 && l.Index() == n-1 {
+				loadCmd = r.LoadMore()
+			}
 		}
 	}
 
-	switch r.CurrentType {
-	case TRACK:
-		r.listTracks, cmd = r.listTracks.Update(msg)
-	case ALBUM:
-		r.listAlbums, cmd = r.listAlbums.Update(msg)
-	case PLAYLIST:
-		r.listPlaylists, cmd = r.listPlaylists.Update(msg)
-	}
+	l, cmd = l.Update(msg)
+	r.lists[r.CurrentType] = l
 
-	return r, cmd
+	return r, tea.Batch(loadCmd, cmd)
 }
 
 // Renders the result view based on the
 // current selected display type. Ensure
-// result.Refresh() was once before we display
+// Search.Refresh() was once before we display
 // the result content.
 func (r Results) view() string {
-	switch r.CurrentType {
-	case TRACK:
-		return r.listTracks.View()
-	case ALBUM:
-		return r.listAlbums.View()
-	case PLAYLIST:
-		return r.listPlaylists.View()
+	l, ok := r.lists[r.CurrentType]
+	if !ok {
+		// User's first time in the search view,
+		// hasn't selected a type, so just hide
+		// results.
+		return ""
+	}
+
+	if r.loading && len(l.Items()) == 0 {
+		return r.tabs() + "\n\n" + comp.Style.Muted.Render("Searching...")
+	}
+
+	return r.tabs() + "\n\n" + l.View()
+}
+
+// Renders the name of every search type, with
+// the displayed type highlighted.
+func (r Results) tabs() string {
+	style := struct {
+		Selected lg.Style
+		Normal   lg.Style
+	}{
+		Normal:   comp.Style.Muted,
+		Selected: comp.Style.Selected.Underline(true),
 	}
 
-	// User's first time in the search view,
-	// hasn't selected a type, so just hide
-	// results.
-	return ""
+	tabs := []string{}
+
+	for _, t := range SEARCH_TYPES {
+		if t == r.CurrentType {
+			tabs = append(tabs, style.Selected.Render(SEARCH_TYPE_TITLES[t]))
+		} else {
+			tabs = append(tabs, style.Normal.Render(SEARCH_TYPE_TITLES[t]))
+		}
+	}
+
+	return strings.Join(tabs, " ")
 }
 
 func (r Results) Content() comp.Content {
@@ -311,7 +822,7 @@ func (stl SearchTypeList) Selected() list.Item {
 func NewSearchTypeList(items []list.Item) SearchTypeList {
 	lm := SearchTypeList{
 		list: comp.NewCustomList(items, "Select type:",
-			comp.DEFAULT_WIDTH-5, comp.LIST_HEIGHT_SMALL-1),
+			comp.DEFAULT_WIDTH-5, comp.LIST_HEIGHT_NORMAL-1),
 	}
 
 	return lm
@@ -391,6 +902,11 @@ func (sq SearchQuery) Update(msg tea.Msg) (SearchQuery, tea.Cmd) {
 
 	sq.Text, cmd = sq.Text.Update(msg)
 
+	sq.err = nil
+	if sq.Text.Value() != "" {
+		_, sq.err = spotify.ParseQuery(sq.Text.Value())
+	}
+
 	return sq, cmd
 }
 
@@ -400,9 +916,20 @@ func (sq SearchQuery) View() string {
 		sq.Text.View(),
 	) + "\n"
 
+	// Feedback about an invalid field filter, such as "year:19".
+	if sq.err != nil {
+		s += comp.Style.Error.Render(comp.Content(errorx.Cast(sq.err).Message()).AdjustFit(TEXT_INPUT_CHAR_LIMIT).String()) + "\n"
+	}
+
 	return s
 }
 
+// True if the query can be searched, field filters such
+// as "year:" must hold valid values.
+func (sq SearchQuery) Valid() bool {
+	return sq.Text.Value() != "" && sq.err == nil
+}
+
 func (sq SearchQuery) Content() comp.Content {
 	return comp.Content(sq.View())
 }
@@ -411,6 +938,13 @@ func (sq SearchQuery) Query() string {
 	return sq.Text.Value()
 }
 
+// Replaces the query text, validating it as if it was typed.
+func (sq SearchQuery) SetQuery(query string) SearchQuery {
+	sq.Text.SetValue(query)
+	_, sq.err = spotify.ParseQuery(query)
+	return sq
+}
+
 func (sq SearchQuery) HideCursor() SearchQuery {
 	sq.Text.Blur()
 	return sq
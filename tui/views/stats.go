@@ -0,0 +1,146 @@
+package views
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	lg "github.com/charmbracelet/lipgloss"
+	"github.com/dionvu/spogo/config"
+	"github.com/dionvu/spogo/spotify/auth"
+	"github.com/dionvu/spogo/stats"
+	comp "github.com/dionvu/spogo/tui/views/components"
+)
+
+const (
+	// Rows taken by the header, footer and view status around the report.
+	STATS_VIEW_CHROME_HEIGHT = 8
+
+	MSG_STATS_UNAVAILABLE = "Stats are unavailable, see the error log"
+)
+
+// Shows the listening stats report of a period, which
+// is built again each time the view is opened.
+type Stats struct {
+	session *auth.Session
+	config  *config.Config
+
+	// The index of the period shown in stats.PERIODS.
+	period int
+
+	report  *stats.Report
+	loading bool
+
+	// Counts reports requested, so only the latest is shown.
+	seq int
+
+	viewport viewport.Model
+}
+
+// Carries a report built for the stats view.
+type StatsMsg struct {
+	Seq    int
+	Report stats.Report
+	Err    error
+}
+
+func NewStats(session *auth.Session, cfg *config.Config) Stats {
+	return Stats{
+		session:  session,
+		config:   cfg,
+		viewport: viewport.New(0, 0),
+	}
+}
+
+// Builds the report of the period shown, in the background.
+func (s *Stats) Load() tea.Cmd {
+	s.seq++
+	s.loading = true
+
+	seq := s.seq
+	since := stats.PERIODS[s.period].Since(time.Now())
+	// A copy, as the config is replaced when it is reloaded.
+	session, cfg := s.session, *s.config
+
+	return func() tea.Msg {
+		r, err := stats.New(&cfg, session, since, time.Time{})
+		return StatsMsg{Seq: seq, Report: r, Err: err}
+	}
+}
+
+// Switches to the next period, building its report.
+func (s *Stats) NextPeriod() tea.Cmd {
+	s.period = (s.period + 1) % len(stats.PERIODS)
+	s.viewport.GotoTop()
+
+	return s.Load()
+}
+
+// Shows the report, unless another has been requested since.
+// Errors are logged where they occur, leaving the last report.
+func (s *Stats) Received(msg StatsMsg) {
+	if msg.Seq != s.seq {
+		return
+	}
+
+	s.loading = false
+
+	if msg.Err == nil {
+		s.report = &msg.Report
+	}
+}
+
+// Scrolls the report.
+func (s *Stats) Update(msg tea.Msg, term comp.Terminal) tea.Cmd {
+	s.resize(term)
+
+	var cmd tea.Cmd
+	s.viewport, cmd = s.viewport.Update(msg)
+
+	return cmd
+}
+
+func (s Stats) View(term comp.Terminal) string {
+	s.resize(term)
+
+	return comp.Join([]string{
+		fmt.Sprintf("%s\n%s\n%s", s.headerView(), s.viewport.View(), s.footerView()),
+		ViewStatus{CurrentView: STATS_VIEW}.Content(s.config).String(),
+	}, "\n").CenterVertical(term).CenterHorizontal(term).String()
+}
+
+// Fits the viewport to the terminal, rendering the report to its width.
+func (s *Stats) resize(term comp.Terminal) {
+	s.viewport.Width = min(max(term.Width-4, 0), stats.MAX_TEXT_WIDTH)
+	s.viewport.Height = max(term.Height-STATS_VIEW_CHROME_HEIGHT, 1)
+
+	switch {
+	case s.report != nil:
+		s.viewport.SetContent(s.report.Text(s.viewport.Width))
+	case s.loading:
+		s.viewport.SetContent("Loading...")
+	default:
+		s.viewport.SetContent(MSG_STATS_UNAVAILABLE)
+	}
+}
+
+func (s Stats) headerView() string {
+	name := stats.PERIODS[s.period].Name
+	if s.loading && s.report != nil {
+		name += " (loading)"
+	}
+
+	title := titleStyle.Render("Stats - " + name)
+	line := strings.Repeat("─", max(0, s.viewport.Width-lg.Width(title)))
+	return lg.JoinHorizontal(lg.Center, title, line)
+}
+
+func (s Stats) footerView() string {
+	next := s.config.Keymap().First(config.KEYMAP_VIEW_PLAYER, config.ACTION_STATS_VIEW)
+
+	info := infoStyle.Render(fmt.Sprintf("%s for the next period  %3.f%%", next, s.viewport.ScrollPercent()*100))
+	line := strings.Repeat("─", max(0, s.viewport.Width-lg.Width(info)))
+	return lg.JoinHorizontal(lg.Center, line, info)
+}